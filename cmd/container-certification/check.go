@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opdev/knex/types"
+	"github.com/spf13/cobra"
+
+	"github.com/opdev/container-certification/internal/checks"
+	"github.com/opdev/container-certification/internal/cli"
+)
+
+func checkCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check <name>",
+		Short: "Run a single check against an image",
+		Long: fmt.Sprintf("Run a single check of Red Hat's Container Certification Policy against an image, with its default params.\n\n"+
+			"Available checks: %s", strings.Join(checks.CheckNames(), ", ")),
+	}
+
+	for _, name := range checks.CheckNames() {
+		cmd.AddCommand(singleCheckCmd(name))
+	}
+
+	return cmd
+}
+
+func singleCheckCmd(name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   name + " <image>",
+		Short: fmt.Sprintf("Run the %s check against an image", name),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ch, err := checks.NewCheck(name, checkConfig(cmd))
+			if err != nil {
+				return err
+			}
+			return cli.RunChecks(cmd, args[0], []types.Check{ch}, false)
+		},
+	}
+
+	f := cmd.Flags()
+	cli.BindBaseFlags(f)
+	bindCheckConfigFlags(f)
+
+	return cmd
+}
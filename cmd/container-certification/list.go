@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/opdev/knex/types"
+	"github.com/spf13/cobra"
+
+	"github.com/opdev/container-certification/internal/checks"
+	"github.com/opdev/container-certification/internal/flags"
+)
+
+const keyListPolicy = "policy"
+
+func listCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the available checks and what they verify",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listed, err := listedChecks(cmd)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tLEVEL\tDESCRIPTION")
+			for _, ch := range listed {
+				meta := ch.Metadata()
+				fmt.Fprintf(w, "%s\t%s\t%s\n", ch.Name(), meta.Level, meta.Description)
+			}
+			return w.Flush()
+		},
+	}
+
+	f := cmd.Flags()
+	f.String(keyListPolicy, "", "Only list the checks in this policy, at the levels the policy sets.")
+	flags.BindFlagPolicyFiles(f)
+
+	return cmd
+}
+
+// listedChecks returns every built-in check or, with --policy, the checks in that
+// policy at the levels it sets, including its external checks.
+func listedChecks(cmd *cobra.Command) ([]types.Check, error) {
+	polName, _ := cmd.Flags().GetString(keyListPolicy)
+	if polName == "" {
+		names := checks.CheckNames()
+		listed := make([]types.Check, 0, len(names))
+		for _, name := range names {
+			ch, err := checks.NewCheck(name, checks.ContainerCheckConfig{})
+			if err != nil {
+				return nil, err
+			}
+			listed = append(listed, ch)
+		}
+		return listed, nil
+	}
+
+	policyFiles, _ := cmd.Flags().GetStringSlice(flags.KeyPolicyFiles)
+	policies, err := checks.LoadPolicies(policyFiles...)
+	if err != nil {
+		return nil, err
+	}
+	pol, ok := policies[polName]
+	if !ok {
+		return nil, fmt.Errorf("unknown policy %q, must be one of %s", polName, strings.Join(checks.PolicyNames(policies), ", "))
+	}
+	return pol.Build(checks.ContainerCheckConfig{})
+}
+
+func explainCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "explain <check>",
+		Short: "Describe a check, and how to fix an image that fails it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ch, err := checks.NewCheck(args[0], checks.ContainerCheckConfig{})
+			if err != nil {
+				return fmt.Errorf("%w, must be one of %s", err, strings.Join(checks.CheckNames(), ", "))
+			}
+
+			meta := ch.Metadata()
+			help := ch.Help()
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "%s (level: %s)\n\n", ch.Name(), meta.Level)
+			fmt.Fprintf(out, "%s\n\n", meta.Description)
+			fmt.Fprintf(out, "If it fails:  %s\n", help.Suggestion)
+			fmt.Fprintf(out, "If it errors: %s\n\n", help.Message)
+			fmt.Fprintf(out, "Knowledge base: %s\n", meta.KnowledgeBaseURL)
+			fmt.Fprintf(out, "Check details:  %s\n", meta.CheckURL)
+			if params := checks.CheckParams(args[0]); len(params) > 0 {
+				fmt.Fprintf(out, "Policy params:  %s\n", strings.Join(params, ", "))
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/opdev/container-certification/internal/checks"
+	"github.com/opdev/container-certification/internal/config"
+	"github.com/opdev/container-certification/internal/flags"
+)
+
+func main() {
+	cmd := rootCmd()
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func rootCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:          "container-certification",
+		Long:         `Run the checks of Red Hat's Container Certification Policy individually or as a whole policy. This is a debugging tool, and not used for certification. Certification is performed with the OpenShift Preflight utility.`,
+		SilenceUsage: true,
+	}
+
+	cmd.AddCommand(checkCmd(), policyCmd(), listCmd(), explainCmd())
+
+	return &cmd
+}
+
+// bindCheckConfigFlags binds the flags read by checkConfig.
+func bindCheckConfigFlags(f *pflag.FlagSet) {
+	flags.BindFlagPyxisAPIToken(f)
+	flags.BindFlagPyxisEnv(f)
+	flags.BindFlagPyxisHost(f)
+	flags.BindFlagsPyxisRetry(f)
+	flags.BindFlagCertificationProjectID(f)
+	flags.BindFlagAdvisoryFiles(f)
+}
+
+// checkConfig returns the configuration used to build checks, read from the flags
+// of cmd.
+func checkConfig(cmd *cobra.Command) checks.ContainerCheckConfig {
+	f := cmd.Flags()
+	dockerCfg, _ := f.GetString(flags.KeyDockerConfig)
+	pyxisToken, _ := f.GetString(flags.KeyPyxisAPIToken)
+	pyxisEnv, _ := f.GetString(flags.KeyPyxisEnv)
+	pyxisHost, _ := f.GetString(flags.KeyPyxisHost)
+	projectID, _ := f.GetString(flags.KeyCertProjectID)
+	policyFiles, _ := f.GetStringSlice(flags.KeyPolicyFiles)
+	insecure, _ := f.GetBool(flags.KeyInsecure)
+	registryCAFile, _ := f.GetString(flags.KeyRegistryCAFile)
+	advisoryFiles, _ := f.GetStringSlice(flags.KeyAdvisoryFiles)
+	cfg := viper.New()
+	_ = cfg.BindPFlags(f)
+
+	return checks.ContainerCheckConfig{
+		DockerConfig:           dockerCfg,
+		PyxisAPIToken:          pyxisToken,
+		CertificationProjectID: projectID,
+		PyxisHost:              config.PyxisHostLookup(pyxisEnv, pyxisHost),
+		PolicyFiles:            policyFiles,
+		Insecure:               insecure,
+		RegistryCAFile:         registryCAFile,
+		AdvisoryFiles:          advisoryFiles,
+		PyxisRetry:             flags.PyxisRetryPolicy(cfg),
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/opdev/container-certification/internal/checks"
+	"github.com/opdev/container-certification/internal/cli"
+	"github.com/opdev/container-certification/internal/flags"
+	"github.com/opdev/container-certification/internal/policy"
+)
+
+func policyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Run or list certification policies",
+	}
+
+	cmd.AddCommand(policyRunCmd(), policyListCmd())
+
+	return cmd
+}
+
+func policyRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run <policy> <image>",
+		Short: "Run every check in a policy against an image",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pol := args[0]
+			renderedChecks, err := checks.InitializeContainerChecks(cmd.Context(), pol, checkConfig(cmd))
+			if err != nil {
+				return err
+			}
+			return cli.RunChecks(cmd, args[1], renderedChecks, pol == policy.PolicyScratch)
+		},
+	}
+
+	f := cmd.Flags()
+	cli.BindBaseFlags(f)
+	bindCheckConfigFlags(f)
+	flags.BindFlagPolicyFiles(f)
+
+	return cmd
+}
+
+func policyListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the built-in policies and those in policy files",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			policyFiles, _ := cmd.Flags().GetStringSlice(flags.KeyPolicyFiles)
+			policies, err := checks.LoadPolicies(policyFiles...)
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tCHECKS\tSOURCE\tDESCRIPTION")
+			for _, name := range checks.PolicyNames(policies) {
+				p := policies[name]
+				fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", p.Name, len(p.Checks), p.Source, p.Description)
+			}
+			return w.Flush()
+		},
+	}
+
+	flags.BindFlagPolicyFiles(cmd.Flags())
+
+	return cmd
+}
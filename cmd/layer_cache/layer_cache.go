@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/opdev/container-certification/internal/layercache"
+)
+
+func main() {
+	cmd := layerCacheCmd()
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func layerCacheCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:  "layer-cache",
+		Long: `Inspect and prune the persistent layer cache used when checks are run with --layer-cache-dir.`,
+	}
+
+	cmd.AddCommand(inspectCmd(), pruneCmd())
+
+	return &cmd
+}
+
+func inspectCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "inspect <cache-dir>",
+		Short: "List the layers in the cache, least recently used first",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := layercache.New(args[0], 0)
+			if err != nil {
+				return err
+			}
+
+			entries, err := c.Entries()
+			if err != nil {
+				return err
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "DIGEST\tSIZE\tLAST USED")
+			var total int64
+			for _, e := range entries {
+				total += e.Size
+				fmt.Fprintf(w, "%s\t%s\t%s\n", e.Digest, formatSize(e.Size), e.LastUsed.Format(time.RFC3339))
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "\n%d layers, %s total\n", len(entries), formatSize(total))
+			return nil
+		},
+	}
+}
+
+func pruneCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "prune <cache-dir>",
+		Short: "Evict least recently used layers until the cache is within the given size",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			all, _ := cmd.Flags().GetBool("all")
+			maxMB, _ := cmd.Flags().GetInt64("max-mb")
+			if !all && !cmd.Flags().Changed("max-mb") {
+				return errors.New("one of --max-mb or --all is required")
+			}
+			if all {
+				maxMB = 0
+			}
+
+			c, err := layercache.New(args[0], 0)
+			if err != nil {
+				return err
+			}
+
+			evicted, err := c.Prune(maxMB * 1024 * 1024)
+			if err != nil {
+				return err
+			}
+
+			var freed int64
+			for _, e := range evicted {
+				freed += e.Size
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "evicted %d layers, freed %s\n", len(evicted), formatSize(freed))
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.Int64("max-mb", 0, "Evict least recently used layers until the cache is no larger than this many megabytes")
+	f.Bool("all", false, "Evict every layer in the cache")
+
+	return &cmd
+}
+
+// formatSize formats a size in bytes for display.
+func formatSize(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/opdev/container-certification/internal/pyxismock"
+)
+
+func main() {
+	cmd := pyxisMockCmd()
+	if err := cmd.Execute(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func pyxisMockCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use: "pyxis-mock",
+		Long: `Run an in-memory stand-in for the Pyxis API, to submit results and run the checks that query Pyxis
+with no network. Point the certification at it with --pyxis-host http://<address>.`,
+	}
+
+	cmd.AddCommand(serveCmd())
+
+	return &cmd
+}
+
+func serveCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "serve",
+		Short: "Serve the Pyxis API until interrupted",
+		Long: `Serve the Pyxis API until interrupted. Resources are kept in memory, and seeded from the JSON
+fixtures files given with --fixtures, e.g.
+
+  {
+    "api_keys": ["my-api-key"],
+    "projects": [{"_id": "my-project", "certification_status": "Started", "container": {"type": "container"}}],
+    "images": [{"_id": "ubi", "certified": true, "uncompressed_top_layer_id": "sha256:...",
+                "repositories": [{"registry": "registry.access.redhat.com"}]}]
+  }`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listen, _ := cmd.Flags().GetString("listen")
+			fixturesFiles, _ := cmd.Flags().GetStringSlice("fixtures")
+
+			mock := pyxismock.New(pyxismock.Fixtures{})
+			for _, path := range fixturesFiles {
+				fixtures, err := pyxismock.LoadFixtures(path)
+				if err != nil {
+					return err
+				}
+				mock.Seed(fixtures)
+			}
+
+			listener, err := net.Listen("tcp", listen)
+			if err != nil {
+				return fmt.Errorf("could not listen on %s: %w", listen, err)
+			}
+			server := &http.Server{Handler: mock, ReadHeaderTimeout: 10 * time.Second}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				_ = server.Shutdown(shutdownCtx)
+			}()
+
+			fmt.Fprintf(cmd.OutOrStdout(), "serving the Pyxis API on %s, use --pyxis-host http://%s\n", listener.Addr(), listener.Addr())
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.String("listen", "localhost:8080", "Address to serve the Pyxis API on")
+	f.StringSlice("fixtures", nil, "JSON files of the projects, images and API keys to start with")
+
+	return &cmd
+}
@@ -0,0 +1,167 @@
+// Package advisory reads the fixes Red Hat publishes for vulnerabilities, from OVAL
+// definitions and CSAF advisories or VEX files, so that the packages of an image can
+// be checked for fixable vulnerabilities without network access.
+package advisory
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/opdev/container-certification/internal/rpm"
+)
+
+// Fix is a package version that fixes a vulnerability.
+type Fix struct {
+	// Name is the name of the binary package that is fixed.
+	Name string
+	// Arches are the architectures the fix applies to. An empty list applies
+	// to all architectures.
+	Arches []string
+	// Fixed is the first version of the package that is not vulnerable.
+	Fixed rpm.EVR
+	CVE   string
+	// Severity is the Red Hat severity rating of the vulnerability: Low,
+	// Moderate, Important or Critical.
+	Severity string
+	// Advisory is the erratum that shipped the fix, e.g. RHSA-2023:1234, if known.
+	Advisory string
+}
+
+// appliesTo reports whether f fixes the package with arch and installed evr.
+func (f Fix) appliesTo(arch string, installed rpm.EVR) bool {
+	if len(f.Arches) > 0 && !contains(f.Arches, arch) && arch != "noarch" {
+		return false
+	}
+	// Fixes for other releases of RHEL, and for module streams, are not
+	// comparable with the installed package.
+	if distMajor(f.Fixed.Release) != distMajor(installed.Release) || isModule(f.Fixed.Release) != isModule(installed.Release) {
+		return false
+	}
+	return rpm.CompareEVR(installed, f.Fixed) < 0
+}
+
+// Database holds the fixes read from advisory files, keyed by package name.
+type Database struct {
+	fixes map[string][]Fix
+}
+
+// Load reads the advisory files at paths. Each file may be OVAL XML, or CSAF or
+// VEX JSON, and may be compressed with bzip2, as Red Hat publishes them.
+func Load(paths ...string) (*Database, error) {
+	db := &Database{fixes: map[string][]Fix{}}
+	for _, path := range paths {
+		fixes, err := loadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not load advisories from %s: %w", path, err)
+		}
+		db.add(fixes...)
+	}
+	return db, nil
+}
+
+func (db *Database) add(fixes ...Fix) {
+	for _, fix := range fixes {
+		db.fixes[fix.Name] = append(db.fixes[fix.Name], fix)
+	}
+}
+
+// Len returns the number of fixes in db.
+func (db *Database) Len() int {
+	var n int
+	for _, fixes := range db.fixes {
+		n += len(fixes)
+	}
+	return n
+}
+
+// Fixes returns the fixes for vulnerabilities of the installed package. If a
+// vulnerability is fixed more than once, the latest fix is returned.
+func (db *Database) Fixes(name, arch string, installed rpm.EVR) []Fix {
+	var found []Fix
+	byCVE := map[string]int{}
+	for _, fix := range db.fixes[name] {
+		if !fix.appliesTo(arch, installed) {
+			continue
+		}
+		if i, ok := byCVE[fix.CVE]; ok {
+			if rpm.CompareEVR(fix.Fixed, found[i].Fixed) > 0 {
+				found[i] = fix
+			}
+			continue
+		}
+		byCVE[fix.CVE] = len(found)
+		found = append(found, fix)
+	}
+	return found
+}
+
+func loadFile(path string) ([]Fix, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	if magic, _ := r.Peek(3); bytes.Equal(magic, []byte("BZh")) {
+		r = bufio.NewReader(bzip2.NewReader(r))
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch trimmed := bytes.TrimSpace(data); {
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		return parseOVAL(data)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return parseCSAF(data)
+	default:
+		return nil, fmt.Errorf("unrecognized format, expected OVAL XML or CSAF JSON")
+	}
+}
+
+var (
+	distPattern   = regexp.MustCompile(`\.el(\d+)`)
+	modulePattern = regexp.MustCompile(`\.module[+_]`)
+)
+
+// distMajor returns the RHEL major version a release was built for, e.g. "9" for
+// 6.el9_1, or an empty string if it cannot be told.
+func distMajor(release string) string {
+	m := distPattern.FindStringSubmatch(release)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// isModule reports whether a release was built for a module stream.
+func isModule(release string) bool {
+	return modulePattern.MatchString(release)
+}
+
+// normalizeSeverity returns severity capitalized as Red Hat rates it, e.g. Important.
+func normalizeSeverity(severity string) string {
+	severity = strings.TrimSpace(severity)
+	if severity == "" {
+		return ""
+	}
+	return strings.ToUpper(severity[:1]) + strings.ToLower(severity[1:])
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package advisory
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/opdev/container-certification/internal/rpm"
+)
+
+const csafAdvisory = `{
+  "document": {
+    "csaf_version": "2.0",
+    "tracking": {"id": "RHSA-2023:0340"},
+    "aggregate_severity": {"text": "Moderate"}
+  },
+  "product_tree": {
+    "branches": [{
+      "branches": [{
+        "branches": [
+          {"product": {"product_id": "bash-0:5.1.8-6.el9_1.x86_64", "product_identification_helper": {"purl": "pkg:rpm/redhat/bash@5.1.8-6.el9_1?arch=x86_64"}}},
+          {"product": {"product_id": "bash-0:5.1.8-6.el9_1.src", "product_identification_helper": {"purl": "pkg:rpm/redhat/bash@5.1.8-6.el9_1?arch=src"}}}
+        ]
+      }]
+    }],
+    "relationships": [
+      {"full_product_name": {"product_id": "BaseOS-9.1.0.Z.MAIN:bash-0:5.1.8-6.el9_1.x86_64"}, "product_reference": "bash-0:5.1.8-6.el9_1.x86_64"},
+      {"full_product_name": {"product_id": "BaseOS-9.1.0.Z.MAIN:bash-0:5.1.8-6.el9_1.src"}, "product_reference": "bash-0:5.1.8-6.el9_1.src"},
+      {"full_product_name": {"product_id": "BaseOS-9.1.0.Z.MAIN:bash-doc-0:5.1.8-6.el9_1.noarch"}, "product_reference": "bash-doc-0:5.1.8-6.el9_1.noarch"}
+    ]
+  },
+  "vulnerabilities": [{
+    "cve": "CVE-2022-3715",
+    "product_status": {"fixed": [
+      "BaseOS-9.1.0.Z.MAIN:bash-0:5.1.8-6.el9_1.x86_64",
+      "BaseOS-9.1.0.Z.MAIN:bash-0:5.1.8-6.el9_1.src",
+      "BaseOS-9.1.0.Z.MAIN:bash-doc-0:5.1.8-6.el9_1.noarch"
+    ]},
+    "threats": [{"category": "impact", "details": "Important"}]
+  }]
+}`
+
+const ovalDefinitionsFile = `<?xml version="1.0" encoding="utf-8"?>
+<oval_definitions xmlns="http://oval.mitre.org/XMLSchema/oval-definitions-5" xmlns:red-def="http://oval.mitre.org/XMLSchema/oval-definitions-5#linux">
+  <definitions>
+    <definition class="patch" id="oval:com.redhat.rhsa:def:20230001" version="1">
+      <metadata>
+        <title>RHSA-2023:0001: openssl security update (Important)</title>
+        <reference ref_id="RHSA-2023:0001" source="RHSA"/>
+        <reference ref_id="CVE-2023-0286" source="CVE"/>
+        <advisory from="secalert@redhat.com">
+          <severity>Important</severity>
+          <cve impact="important">CVE-2023-0286</cve>
+          <cve impact="low">CVE-2022-4304</cve>
+        </advisory>
+      </metadata>
+      <criteria operator="OR">
+        <criterion comment="Red Hat Enterprise Linux must be installed" test_ref="oval:com.redhat.rhsa:tst:20230001999"/>
+        <criteria operator="AND">
+          <criterion comment="openssl-libs is earlier than 1:3.0.1-47.el9_1" test_ref="oval:com.redhat.rhsa:tst:20230001001"/>
+        </criteria>
+      </criteria>
+    </definition>
+  </definitions>
+  <tests>
+    <red-def:rpminfo_test check="at least one" id="oval:com.redhat.rhsa:tst:20230001001" version="1">
+      <red-def:object object_ref="oval:com.redhat.rhsa:obj:20230001001"/>
+      <red-def:state state_ref="oval:com.redhat.rhsa:ste:20230001001"/>
+    </red-def:rpminfo_test>
+  </tests>
+  <objects>
+    <red-def:rpminfo_object id="oval:com.redhat.rhsa:obj:20230001001" version="1">
+      <red-def:name>openssl-libs</red-def:name>
+    </red-def:rpminfo_object>
+  </objects>
+  <states>
+    <red-def:rpminfo_state id="oval:com.redhat.rhsa:ste:20230001001" version="1">
+      <red-def:arch datatype="string" operation="pattern match">aarch64|x86_64</red-def:arch>
+      <red-def:evr datatype="evr_string" operation="less than">1:3.0.1-47.el9_1</red-def:evr>
+    </red-def:rpminfo_state>
+  </states>
+</oval_definitions>`
+
+var _ = Describe("Advisory", func() {
+	var db *Database
+
+	BeforeEach(func() {
+		dir := GinkgoT().TempDir()
+		csafPath := filepath.Join(dir, "rhsa-2023_0340.json")
+		ovalPath := filepath.Join(dir, "rhel-9.oval.xml")
+		Expect(os.WriteFile(csafPath, []byte(csafAdvisory), 0o644)).To(Succeed())
+		Expect(os.WriteFile(ovalPath, []byte(ovalDefinitionsFile), 0o644)).To(Succeed())
+
+		var err error
+		db, err = Load(csafPath, ovalPath)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("When reading a CSAF advisory", func() {
+		It("should read the fixed binary packages", func() {
+			fixes := db.Fixes("bash", "x86_64", rpm.EVR{Version: "5.1.8", Release: "5.el9"})
+			Expect(fixes).To(Equal([]Fix{{
+				Name:     "bash",
+				Arches:   []string{"x86_64"},
+				Fixed:    rpm.EVR{Version: "5.1.8", Release: "6.el9_1"},
+				CVE:      "CVE-2022-3715",
+				Severity: "Important",
+				Advisory: "RHSA-2023:0340",
+			}}))
+		})
+		It("should resolve packages from product references without a purl", func() {
+			Expect(db.Fixes("bash-doc", "noarch", rpm.EVR{Version: "5.1.8", Release: "4.el9"})).To(HaveLen(1))
+		})
+		It("should not report packages that are already fixed", func() {
+			Expect(db.Fixes("bash", "x86_64", rpm.EVR{Version: "5.1.8", Release: "6.el9_1"})).To(BeEmpty())
+		})
+		It("should not compare packages built for another RHEL release", func() {
+			Expect(db.Fixes("bash", "x86_64", rpm.EVR{Version: "4.4.20", Release: "4.el8"})).To(BeEmpty())
+		})
+		It("should skip source packages", func() {
+			Expect(db.Fixes("bash", "src", rpm.EVR{Version: "5.1.8", Release: "5.el9"})).To(BeEmpty())
+		})
+	})
+
+	Context("When reading OVAL definitions", func() {
+		It("should read a fix for each CVE with its impact", func() {
+			fixes := db.Fixes("openssl-libs", "x86_64", rpm.EVR{Epoch: 1, Version: "3.0.1", Release: "43.el9_0"})
+			Expect(fixes).To(HaveLen(2))
+			Expect(fixes[0].Severity).To(Equal("Important"))
+			Expect(fixes[0].Advisory).To(Equal("RHSA-2023:0001"))
+			Expect(fixes[1].CVE).To(Equal("CVE-2022-4304"))
+			Expect(fixes[1].Severity).To(Equal("Low"))
+		})
+		It("should only apply to the listed architectures", func() {
+			Expect(db.Fixes("openssl-libs", "s390x", rpm.EVR{Epoch: 1, Version: "3.0.1", Release: "43.el9_0"})).To(BeEmpty())
+		})
+	})
+
+	It("should reject files in an unknown format", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "advisories.txt")
+		Expect(os.WriteFile(path, []byte("RHSA-2023:0340"), 0o644)).To(Succeed())
+		_, err := Load(path)
+		Expect(err).To(MatchError(ContainSubstring("unrecognized format")))
+	})
+})
@@ -0,0 +1,214 @@
+package advisory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/opdev/container-certification/internal/rpm"
+)
+
+// csafDocument is the part of a CSAF 2.0 document that describes fixed packages.
+// Red Hat VEX files are CSAF documents too.
+type csafDocument struct {
+	Document struct {
+		CSAFVersion string `json:"csaf_version"`
+		Tracking    struct {
+			ID string `json:"id"`
+		} `json:"tracking"`
+		AggregateSeverity struct {
+			Text string `json:"text"`
+		} `json:"aggregate_severity"`
+	} `json:"document"`
+	ProductTree struct {
+		Branches      []csafBranch `json:"branches"`
+		Relationships []struct {
+			FullProductName  csafProduct `json:"full_product_name"`
+			ProductReference string      `json:"product_reference"`
+		} `json:"relationships"`
+	} `json:"product_tree"`
+	Vulnerabilities []struct {
+		CVE           string `json:"cve"`
+		ProductStatus struct {
+			Fixed []string `json:"fixed"`
+		} `json:"product_status"`
+		Threats []struct {
+			Category string `json:"category"`
+			Details  string `json:"details"`
+		} `json:"threats"`
+	} `json:"vulnerabilities"`
+}
+
+type csafBranch struct {
+	Branches []csafBranch `json:"branches"`
+	Product  *csafProduct `json:"product"`
+}
+
+type csafProduct struct {
+	ProductID string `json:"product_id"`
+	Helper    struct {
+		PURL string `json:"purl"`
+	} `json:"product_identification_helper"`
+}
+
+// csafPackage is an rpm identified in a CSAF product tree.
+type csafPackage struct {
+	name string
+	arch string
+	evr  rpm.EVR
+}
+
+// parseCSAF returns the fixes for the binary rpms a CSAF advisory or VEX file
+// lists as fixed.
+func parseCSAF(data []byte) ([]Fix, error) {
+	var doc csafDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid CSAF document: %w", err)
+	}
+	if doc.Document.CSAFVersion == "" {
+		return nil, errors.New("not a CSAF document: document.csaf_version is missing")
+	}
+
+	products := map[string]csafPackage{}
+	var walk func(branches []csafBranch)
+	walk = func(branches []csafBranch) {
+		for _, b := range branches {
+			if b.Product != nil {
+				if pkg, ok := csafProductPackage(*b.Product, ""); ok {
+					products[b.Product.ProductID] = pkg
+				}
+			}
+			walk(b.Branches)
+		}
+	}
+	walk(doc.ProductTree.Branches)
+
+	// Fixed products are usually a product stream combined with a package.
+	for _, rel := range doc.ProductTree.Relationships {
+		pkg, ok := products[rel.ProductReference]
+		if !ok {
+			pkg, ok = csafProductPackage(rel.FullProductName, rel.ProductReference)
+		}
+		if ok {
+			products[rel.FullProductName.ProductID] = pkg
+		}
+	}
+
+	var advisoryID string
+	if strings.HasPrefix(doc.Document.Tracking.ID, "RH") {
+		advisoryID = doc.Document.Tracking.ID
+	}
+
+	var fixes []Fix
+	for _, vuln := range doc.Vulnerabilities {
+		severity := doc.Document.AggregateSeverity.Text
+		for _, threat := range vuln.Threats {
+			if threat.Category == "impact" {
+				severity = threat.Details
+			}
+		}
+
+		for _, id := range vuln.ProductStatus.Fixed {
+			pkg, ok := products[id]
+			if !ok || pkg.arch == "src" {
+				continue
+			}
+			fix := Fix{
+				Name:     pkg.name,
+				Fixed:    pkg.evr,
+				CVE:      vuln.CVE,
+				Severity: normalizeSeverity(severity),
+				Advisory: advisoryID,
+			}
+			if pkg.arch != "" {
+				fix.Arches = []string{pkg.arch}
+			}
+			fixes = append(fixes, fix)
+		}
+	}
+
+	return fixes, nil
+}
+
+// csafProductPackage returns the rpm a product describes, from its purl or, failing
+// that, from reference, which may be a name-[epoch:]version-release.arch.
+func csafProductPackage(p csafProduct, reference string) (csafPackage, bool) {
+	if pkg, ok := parseRPMPURL(p.Helper.PURL); ok {
+		return pkg, true
+	}
+	if reference == "" {
+		reference = p.ProductID
+	}
+	return parseNEVRA(reference)
+}
+
+// parseRPMPURL parses a purl such as pkg:rpm/redhat/bash@5.1.8-6.el9_1?arch=x86_64&epoch=0.
+func parseRPMPURL(purl string) (csafPackage, bool) {
+	var pkg csafPackage
+	rest, ok := strings.CutPrefix(purl, "pkg:rpm/")
+	if !ok {
+		return pkg, false
+	}
+	rest, qualifiers, _ := strings.Cut(rest, "?")
+	path, version, ok := strings.Cut(rest, "@")
+	if !ok {
+		return pkg, false
+	}
+
+	name, err := url.PathUnescape(path[strings.LastIndex(path, "/")+1:])
+	if err != nil {
+		return pkg, false
+	}
+	version, err = url.PathUnescape(version)
+	if err != nil {
+		return pkg, false
+	}
+	query, err := url.ParseQuery(qualifiers)
+	if err != nil {
+		return pkg, false
+	}
+
+	evr, err := rpm.ParseEVR(version)
+	if err != nil {
+		return pkg, false
+	}
+	if epoch := query.Get("epoch"); epoch != "" {
+		if evr.Epoch, err = strconv.Atoi(epoch); err != nil {
+			return pkg, false
+		}
+	}
+
+	return csafPackage{name: name, arch: query.Get("arch"), evr: evr}, true
+}
+
+// parseNEVRA parses name-[epoch:]version-release.arch, e.g. bash-0:5.1.8-6.el9_1.x86_64.
+func parseNEVRA(s string) (csafPackage, bool) {
+	var pkg csafPackage
+
+	dot := strings.LastIndex(s, ".")
+	if dot < 0 {
+		return pkg, false
+	}
+	s, pkg.arch = s[:dot], s[dot+1:]
+
+	// The release and version are the last two dash-separated fields.
+	releaseDash := strings.LastIndex(s, "-")
+	if releaseDash < 0 {
+		return pkg, false
+	}
+	versionDash := strings.LastIndex(s[:releaseDash], "-")
+	if versionDash < 0 {
+		return pkg, false
+	}
+
+	evr, err := rpm.ParseEVR(s[versionDash+1:])
+	if err != nil {
+		return pkg, false
+	}
+	pkg.name, pkg.evr = s[:versionDash], evr
+
+	return pkg, pkg.name != "" && pkg.evr.Release != ""
+}
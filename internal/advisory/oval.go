@@ -0,0 +1,165 @@
+package advisory
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/opdev/container-certification/internal/rpm"
+)
+
+// ovalDefinitions is the part of an OVAL definitions file, as Red Hat publishes them,
+// that describes fixed packages. Elements are matched by their local names, so the
+// namespaces of the definition and test schemas do not matter.
+type ovalDefinitions struct {
+	Definitions []ovalDefinition `xml:"definitions>definition"`
+	Tests       []struct {
+		ID     string `xml:"id,attr"`
+		Object struct {
+			Ref string `xml:"object_ref,attr"`
+		} `xml:"object"`
+		State struct {
+			Ref string `xml:"state_ref,attr"`
+		} `xml:"state"`
+	} `xml:"tests>rpminfo_test"`
+	Objects []struct {
+		ID   string `xml:"id,attr"`
+		Name string `xml:"name"`
+	} `xml:"objects>rpminfo_object"`
+	States []struct {
+		ID   string     `xml:"id,attr"`
+		Arch ovalEntity `xml:"arch"`
+		EVR  ovalEntity `xml:"evr"`
+	} `xml:"states>rpminfo_state"`
+}
+
+type ovalDefinition struct {
+	Class    string `xml:"class,attr"`
+	Metadata struct {
+		References []struct {
+			Source string `xml:"source,attr"`
+			RefID  string `xml:"ref_id,attr"`
+		} `xml:"reference"`
+		Advisory struct {
+			Severity string `xml:"severity"`
+			CVEs     []struct {
+				ID     string `xml:",chardata"`
+				Impact string `xml:"impact,attr"`
+			} `xml:"cve"`
+		} `xml:"advisory"`
+	} `xml:"metadata"`
+	Criteria ovalCriteria `xml:"criteria"`
+}
+
+type ovalCriteria struct {
+	Criteria  []ovalCriteria `xml:"criteria"`
+	Criterion []struct {
+		TestRef string `xml:"test_ref,attr"`
+	} `xml:"criterion"`
+}
+
+// testRefs returns the tests referred to anywhere in c.
+func (c ovalCriteria) testRefs() []string {
+	var refs []string
+	for _, criterion := range c.Criterion {
+		refs = append(refs, criterion.TestRef)
+	}
+	for _, nested := range c.Criteria {
+		refs = append(refs, nested.testRefs()...)
+	}
+	return refs
+}
+
+type ovalEntity struct {
+	Value     string `xml:",chardata"`
+	Operation string `xml:"operation,attr"`
+}
+
+// parseOVAL returns the fixes described by the patch definitions of an OVAL file.
+// Only the "package is earlier than" tests of a definition are used: the other
+// criteria, such as the RHEL release or the signing key, are not evaluated.
+func parseOVAL(data []byte) ([]Fix, error) {
+	var doc ovalDefinitions
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OVAL document: %w", err)
+	}
+
+	objects := make(map[string]string, len(doc.Objects))
+	for _, o := range doc.Objects {
+		objects[o.ID] = o.Name
+	}
+
+	type state struct {
+		arches []string
+		evr    rpm.EVR
+	}
+	states := make(map[string]state, len(doc.States))
+	for _, s := range doc.States {
+		if s.EVR.Operation != "less than" {
+			continue
+		}
+		evr, err := rpm.ParseEVR(strings.TrimSpace(s.EVR.Value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid evr in state %s: %w", s.ID, err)
+		}
+		st := state{evr: evr}
+		if arch := strings.TrimSpace(s.Arch.Value); arch != "" {
+			st.arches = strings.Split(arch, "|")
+		}
+		states[s.ID] = st
+	}
+
+	type test struct {
+		name string
+		state
+	}
+	tests := make(map[string]test, len(doc.Tests))
+	for _, t := range doc.Tests {
+		name, ok := objects[t.Object.Ref]
+		if !ok {
+			continue
+		}
+		st, ok := states[t.State.Ref]
+		if !ok {
+			continue
+		}
+		tests[t.ID] = test{name: name, state: st}
+	}
+
+	var fixes []Fix
+	for _, def := range doc.Definitions {
+		if def.Class != "patch" {
+			continue
+		}
+
+		var advisoryID string
+		for _, ref := range def.Metadata.References {
+			if ref.Source == "RHSA" {
+				advisoryID = ref.RefID
+			}
+		}
+
+		for _, ref := range def.Criteria.testRefs() {
+			t, ok := tests[ref]
+			if !ok {
+				continue
+			}
+			for _, cve := range def.Metadata.Advisory.CVEs {
+				severity := cve.Impact
+				if severity == "" {
+					severity = def.Metadata.Advisory.Severity
+				}
+				fixes = append(fixes, Fix{
+					Name:     t.name,
+					Arches:   t.arches,
+					Fixed:    t.evr,
+					CVE:      strings.TrimSpace(cve.ID),
+					Severity: normalizeSeverity(severity),
+					Advisory: advisoryID,
+				})
+			}
+		}
+	}
+
+	return fixes, nil
+}
@@ -3,11 +3,10 @@ package checks
 import (
 	"context"
 	"fmt"
-	"net/http"
-	"time"
 
 	"github.com/opdev/knex/types"
 
+	"github.com/opdev/container-certification/internal/external"
 	"github.com/opdev/container-certification/internal/policy"
 	"github.com/opdev/container-certification/internal/pyxis"
 )
@@ -17,49 +16,57 @@ import (
 // ContainerCheckConfig contains configuration relevant to an individual check's execution.
 type ContainerCheckConfig struct {
 	DockerConfig, PyxisAPIToken, CertificationProjectID, PyxisHost string
+
+	// PolicyFiles are loaded in addition to the built-in policies.
+	PolicyFiles []string
+
+	// Insecure and RegistryCAFile configure checks that connect to the
+	// image's registry, as they do for CraneEngine.
+	Insecure       bool
+	RegistryCAFile string
+
+	// AdvisoryFiles are used by checks that look for fixable vulnerabilities,
+	// unless their params name other files.
+	AdvisoryFiles []string
+
+	// PyxisRetry configures how checks that query Pyxis retry failed requests.
+	PyxisRetry pyxis.RetryPolicy
 }
 
 // InitializeContainerChecks returns the appropriate checks for policy p given cfg.
 func InitializeContainerChecks(_ context.Context, p policy.Policy, cfg ContainerCheckConfig) ([]types.Check, error) {
-	switch p {
-	case policy.PolicyContainer:
-		return []types.Check{
-			&policy.HasLicenseCheck{},
-			policy.NewHasUniqueTagCheck(cfg.DockerConfig),
-			&policy.MaxLayersCheck{},
-			&policy.HasNoProhibitedPackagesCheck{},
-			&policy.HasRequiredLabelsCheck{},
-			&policy.RunAsNonRootCheck{},
-			&policy.HasModifiedFilesCheck{},
-			policy.NewBasedOnUbiCheck(pyxis.NewPyxisClient(
-				cfg.PyxisHost,
-				cfg.PyxisAPIToken,
-				cfg.CertificationProjectID,
-				&http.Client{Timeout: 60 * time.Second})),
-		}, nil
-	case policy.PolicyRoot:
-		return []types.Check{
-			&policy.HasLicenseCheck{},
-			policy.NewHasUniqueTagCheck(cfg.DockerConfig),
-			&policy.MaxLayersCheck{},
-			&policy.HasNoProhibitedPackagesCheck{},
-			&policy.HasRequiredLabelsCheck{},
-			&policy.HasModifiedFilesCheck{},
-			policy.NewBasedOnUbiCheck(pyxis.NewPyxisClient(
-				cfg.PyxisHost,
-				cfg.PyxisAPIToken,
-				cfg.CertificationProjectID,
-				&http.Client{Timeout: 60 * time.Second})),
-		}, nil
-	case policy.PolicyScratch:
-		return []types.Check{
-			&policy.HasLicenseCheck{},
-			policy.NewHasUniqueTagCheck(cfg.DockerConfig),
-			&policy.MaxLayersCheck{},
-			&policy.HasRequiredLabelsCheck{},
-			&policy.RunAsNonRootCheck{},
-		}, nil
+	policies, err := LoadPolicies(cfg.PolicyFiles...)
+	if err != nil {
+		return nil, err
+	}
+
+	pol, err := policyFor(policies, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return pol.Build(cfg)
+}
+
+// Build returns the checks in the policy, configured with cfg.
+func (p Policy) Build(cfg ContainerCheckConfig) ([]types.Check, error) {
+	checks := make([]types.Check, 0, len(p.Checks))
+	for _, pc := range p.Checks {
+		if pc.external != nil {
+			checks = append(checks, external.New(pc.Name, pc.Level, *pc.external))
+			continue
+		}
+
+		ch, err := registry[pc.Name].build(cfg, pc.params)
+		if err != nil {
+			return nil, fmt.Errorf("could not configure check %s for policy %s: %w", pc.Name, p.Name, err)
+		}
+
+		if ch.Metadata().Level != pc.Level {
+			ch = leveledCheck{Check: ch, level: pc.Level}
+		}
+		checks = append(checks, ch)
 	}
 
-	return nil, fmt.Errorf("provided container policy %s is unknown", p)
+	return checks, nil
 }
@@ -0,0 +1,405 @@
+package checks
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/opdev/container-certification/internal/external"
+	"github.com/opdev/container-certification/internal/policy"
+)
+
+// policyFileVersion is the only policy file version currently understood.
+const policyFileVersion = 1
+
+// levels are the enforcement levels a check may be given in a policy file. Checks at
+// the optional level run, but are not included in results.
+var levels = []string{"best", "better", "good", "optional"}
+
+//go:embed policies/*.yaml
+var builtinPolicies embed.FS
+
+// Policy is a named list of checks, as defined in a policy file.
+type Policy struct {
+	Name        string
+	Description string
+	// Source is the file the policy was loaded from. Built-in policies are
+	// prefixed with "builtin:".
+	Source string
+	Checks []PolicyCheck
+}
+
+// PolicyCheck is a check listed in a policy, and how it is configured.
+type PolicyCheck struct {
+	Name  string
+	Level string
+	// params holds the check's parameters, which are decoded when the check
+	// is built.
+	params any
+	// external is set for checks run by an executable rather than built in.
+	external *external.Config
+}
+
+// PolicyFileError is a problem found in a policy file, and where it was found.
+type PolicyFileError struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *PolicyFileError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+}
+
+// LoadPolicies returns the built-in policies, along with the policies defined in
+// files. A policy in files replaces a built-in policy with the same name, and it is an
+// error for two files to define the same policy.
+func LoadPolicies(files ...string) (map[string]Policy, error) {
+	policies, err := loadBuiltinPolicies()
+	if err != nil {
+		return nil, err
+	}
+
+	loadedFrom := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read policy file: %w", err)
+		}
+
+		p, err := ParsePolicy(file, data)
+		if err != nil {
+			return nil, err
+		}
+
+		if other, ok := loadedFrom[p.Name]; ok {
+			return nil, fmt.Errorf("policy %s is defined in both %s and %s", p.Name, other, file)
+		}
+		loadedFrom[p.Name] = file
+		policies[p.Name] = p
+	}
+
+	return policies, nil
+}
+
+// loadBuiltinPolicies parses the policy files embedded in the binary.
+func loadBuiltinPolicies() (map[string]Policy, error) {
+	entries, err := builtinPolicies.ReadDir("policies")
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make(map[string]Policy, len(entries))
+	for _, entry := range entries {
+		data, err := builtinPolicies.ReadFile(path.Join("policies", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		p, err := ParsePolicy("builtin:"+entry.Name(), data)
+		if err != nil {
+			return nil, err
+		}
+		policies[p.Name] = p
+	}
+
+	return policies, nil
+}
+
+// PolicyNames returns the names of policies, sorted.
+func PolicyNames(policies map[string]Policy) []string {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParsePolicy parses and validates the policy file data read from file. All the
+// problems found are returned, each as a *PolicyFileError giving the line they are on.
+func ParsePolicy(file string, data []byte) (Policy, error) {
+	p := policyParser{file: file}
+	return p.parse(data)
+}
+
+// policyParser walks the YAML node tree of a policy file, rather than decoding it
+// into a struct, so that every problem can be reported with its position.
+type policyParser struct {
+	file string
+	errs []error
+}
+
+func (p *policyParser) errorf(node *yaml.Node, format string, args ...any) {
+	e := &PolicyFileError{File: p.file, Msg: fmt.Sprintf(format, args...)}
+	if node != nil {
+		e.Line, e.Column = node.Line, node.Column
+	}
+	p.errs = append(p.errs, e)
+}
+
+func (p *policyParser) parse(data []byte) (Policy, error) {
+	pol := Policy{Source: p.file}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		// yaml errors already carry the line number.
+		p.errorf(nil, "%s", strings.TrimPrefix(err.Error(), "yaml: "))
+		return pol, errors.Join(p.errs...)
+	}
+	if len(doc.Content) == 0 {
+		p.errorf(nil, "policy file is empty")
+		return pol, errors.Join(p.errs...)
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		p.errorf(root, "a policy must be a mapping with version, name and checks")
+		return pol, errors.Join(p.errs...)
+	}
+
+	var version *yaml.Node
+	var checks *yaml.Node
+	p.fields(root, func(key, value *yaml.Node) {
+		switch key.Value {
+		case "version":
+			version = value
+		case "name":
+			pol.Name = p.scalar(value)
+		case "description":
+			pol.Description = p.scalar(value)
+		case "checks":
+			checks = value
+		default:
+			p.errorf(key, "unknown field %q", key.Value)
+		}
+	})
+
+	switch {
+	case version == nil:
+		p.errorf(root, "version is required")
+	case version.Value != fmt.Sprint(policyFileVersion):
+		p.errorf(version, "unsupported policy file version %q, expected %d", version.Value, policyFileVersion)
+	}
+
+	if pol.Name == "" {
+		p.errorf(root, "name is required")
+	}
+
+	if checks == nil || checks.Kind != yaml.SequenceNode || len(checks.Content) == 0 {
+		p.errorf(firstNonNil(checks, root), "checks must be a non-empty list")
+		return pol, errors.Join(p.errs...)
+	}
+
+	seen := map[string]int{}
+	for _, item := range checks.Content {
+		check, ok := p.check(item)
+		if !ok {
+			continue
+		}
+		if line, dup := seen[check.Name]; dup {
+			p.errorf(item, "check %s is already listed on line %d", check.Name, line)
+			continue
+		}
+		seen[check.Name] = item.Line
+		pol.Checks = append(pol.Checks, check)
+	}
+
+	return pol, errors.Join(p.errs...)
+}
+
+// check parses a single entry in the checks list.
+func (p *policyParser) check(node *yaml.Node) (PolicyCheck, bool) {
+	var check PolicyCheck
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "each check must be a mapping with a name and level")
+		return check, false
+	}
+
+	errCount := len(p.errs)
+	var nameNode, levelNode, paramsNode, externalNode *yaml.Node
+	p.fields(node, func(key, value *yaml.Node) {
+		switch key.Value {
+		case "name":
+			nameNode, check.Name = value, p.scalar(value)
+		case "level":
+			levelNode, check.Level = value, p.scalar(value)
+		case "params":
+			paramsNode = value
+		case "external":
+			externalNode = value
+		default:
+			p.errorf(key, "unknown field %q", key.Value)
+		}
+	})
+
+	def, known := registry[check.Name]
+	switch {
+	case check.Name == "":
+		p.errorf(node, "check name is required")
+	case externalNode != nil && known:
+		p.errorf(nameNode, "external check %s has the same name as a built-in check", check.Name)
+	case externalNode == nil && !known:
+		p.errorf(nameNode, "unknown check %q", check.Name)
+	}
+
+	switch {
+	case check.Level == "":
+		p.errorf(node, "level is required for check %s", check.Name)
+	case !containsString(levels, check.Level):
+		p.errorf(levelNode, "invalid level %q, must be one of %s", check.Level, strings.Join(levels, ", "))
+	}
+
+	switch {
+	case externalNode != nil:
+		if paramsNode != nil {
+			p.errorf(paramsNode, "external check %s does not take params, use external.args instead", check.Name)
+		}
+		check.external = p.external(check.Name, externalNode)
+	case known:
+		check.params = p.params(check.Name, def, paramsNode)
+	}
+
+	return check, len(p.errs) == errCount
+}
+
+// params decodes the parameters of a check into the check's params struct. Keys
+// that the check does not accept are reported.
+func (p *policyParser) params(checkName string, def checkDefinition, node *yaml.Node) any {
+	if def.newParams == nil {
+		if node != nil {
+			p.errorf(node, "check %s does not take any params", checkName)
+		}
+		return nil
+	}
+
+	params := def.newParams()
+	if node == nil {
+		return params
+	}
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "params for check %s must be a mapping", checkName)
+		return params
+	}
+
+	known := yamlFieldNames(params)
+	p.fields(node, func(key, value *yaml.Node) {
+		if !containsString(known, key.Value) {
+			p.errorf(key, "unknown param %q for check %s, expected one of %s", key.Value, checkName, strings.Join(known, ", "))
+		}
+	})
+
+	if err := node.Decode(params); err != nil {
+		p.errorf(node, "invalid params for check %s: %s", checkName, strings.TrimPrefix(err.Error(), "yaml: "))
+	}
+
+	return params
+}
+
+// external decodes the definition of an external check. A relative command path
+// is resolved from the directory of the policy file.
+func (p *policyParser) external(checkName string, node *yaml.Node) *external.Config {
+	config := &external.Config{}
+	if node.Kind != yaml.MappingNode {
+		p.errorf(node, "external for check %s must be a mapping with a command", checkName)
+		return config
+	}
+
+	known := yamlFieldNames(config)
+	p.fields(node, func(key, value *yaml.Node) {
+		if !containsString(known, key.Value) {
+			p.errorf(key, "unknown field %q for external check %s, expected one of %s", key.Value, checkName, strings.Join(known, ", "))
+		}
+	})
+
+	if err := node.Decode(config); err != nil {
+		p.errorf(node, "invalid external check %s: %s", checkName, strings.TrimPrefix(err.Error(), "yaml: "))
+		return config
+	}
+
+	switch {
+	case config.Command == "":
+		p.errorf(node, "command is required for external check %s", checkName)
+	case !filepath.IsAbs(config.Command) && strings.ContainsRune(config.Command, filepath.Separator):
+		config.Command = filepath.Join(filepath.Dir(p.file), config.Command)
+	}
+
+	return config
+}
+
+// fields calls fn with each key and value of the mapping node, reporting
+// duplicated keys.
+func (p *policyParser) fields(node *yaml.Node, fn func(key, value *yaml.Node)) {
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if seen[key.Value] {
+			p.errorf(key, "field %q is set more than once", key.Value)
+			continue
+		}
+		seen[key.Value] = true
+		fn(key, value)
+	}
+}
+
+// scalar returns the value of a scalar node, reporting any other kind of node.
+func (p *policyParser) scalar(node *yaml.Node) string {
+	if node.Kind != yaml.ScalarNode {
+		p.errorf(node, "expected a string")
+		return ""
+	}
+	return node.Value
+}
+
+// yamlFieldNames returns the yaml keys of the struct v points to.
+func yamlFieldNames(v any) []string {
+	t := reflect.TypeOf(v).Elem()
+	names := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func firstNonNil(nodes ...*yaml.Node) *yaml.Node {
+	for _, n := range nodes {
+		if n != nil {
+			return n
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// policyFor returns the policy named p.
+func policyFor(policies map[string]Policy, p policy.Policy) (Policy, error) {
+	pol, ok := policies[p]
+	if !ok {
+		return Policy{}, fmt.Errorf("provided container policy %s is unknown", p)
+	}
+	return pol, nil
+}
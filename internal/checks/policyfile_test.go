@@ -0,0 +1,280 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/policy"
+)
+
+// policyFileErrors returns the *PolicyFileErrors joined in err.
+func policyFileErrors(err error) []*PolicyFileError {
+	var errs []*PolicyFileError
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return errs
+	}
+	for _, e := range joined.Unwrap() {
+		var pfe *PolicyFileError
+		if errors.As(e, &pfe) {
+			errs = append(errs, pfe)
+		}
+	}
+	return errs
+}
+
+type tunableParams struct {
+	Threshold int `yaml:"threshold"`
+}
+
+var _ = Describe("Policy files", func() {
+	Context("When loading the built-in policies", func() {
+		It("should define the container, root and scratch policies", func() {
+			policies, err := LoadPolicies()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(PolicyNames(policies)).To(Equal([]string{policy.PolicyContainer, policy.PolicyRoot, policy.PolicyScratch}))
+		})
+		It("should build the same checks as before policy files", func() {
+			checks, err := InitializeContainerChecks(context.TODO(), policy.PolicyScratch, ContainerCheckConfig{})
+			Expect(err).ToNot(HaveOccurred())
+			names := make([]string, 0, len(checks))
+			for _, ch := range checks {
+				names = append(names, ch.Name())
+			}
+			Expect(names).To(Equal([]string{"HasLicense", "HasUniqueTag", "LayerCountAcceptable", "HasRequiredLabel", "RunAsNonRoot"}))
+		})
+		It("should not include RunAsNonRoot in the root policy", func() {
+			policies, err := LoadPolicies()
+			Expect(err).ToNot(HaveOccurred())
+			for _, pc := range policies[policy.PolicyRoot].Checks {
+				Expect(pc.Name).ToNot(Equal("RunAsNonRoot"))
+			}
+		})
+	})
+
+	Context("When a policy file is loaded with a flag", func() {
+		It("should replace the built-in policy of the same name", func() {
+			file := filepath.Join(GinkgoT().TempDir(), "container.yaml")
+			Expect(os.WriteFile(file, []byte(`version: 1
+name: container
+checks:
+  - name: HasLicense
+    level: optional
+`), 0o644)).To(Succeed())
+
+			checks, err := InitializeContainerChecks(context.TODO(), policy.PolicyContainer, ContainerCheckConfig{PolicyFiles: []string{file}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(checks).To(HaveLen(1))
+			Expect(checks[0].Metadata().Level).To(Equal("optional"))
+		})
+		It("should reject unknown policies", func() {
+			_, err := InitializeContainerChecks(context.TODO(), "nonexistent", ContainerCheckConfig{})
+			Expect(err).To(MatchError(ContainSubstring("unknown")))
+		})
+	})
+
+	Context("When a policy file is invalid", func() {
+		It("should point at the line of each problem", func() {
+			_, err := ParsePolicy("bad.yaml", []byte(`version: 1
+name: bad
+checks:
+  - name: HasLicense
+    level: best
+  - name: NoSuchCheck
+    level: best
+  - name: RunAsNonRoot
+    level: mandatory
+  - name: HasLicense
+    level: good
+`))
+			Expect(err).To(HaveOccurred())
+			errs := policyFileErrors(err)
+			Expect(errs).To(HaveLen(3))
+			Expect(errs[0].Line).To(Equal(6))
+			Expect(errs[0].Msg).To(ContainSubstring("NoSuchCheck"))
+			Expect(errs[1].Line).To(Equal(9))
+			Expect(errs[1].Msg).To(ContainSubstring("mandatory"))
+			Expect(errs[2].Line).To(Equal(10))
+			Expect(err.Error()).To(ContainSubstring("bad.yaml:6:"))
+		})
+		It("should reject unsupported versions and unknown fields", func() {
+			_, err := ParsePolicy("bad.yaml", []byte(`version: 2
+name: bad
+owner: someone
+checks:
+  - name: HasLicense
+    level: best
+`))
+			errs := policyFileErrors(err)
+			Expect(errs).To(HaveLen(2))
+			Expect(errs[0].Line).To(Equal(3))
+			Expect(errs[1].Line).To(Equal(1))
+		})
+		It("should report yaml syntax errors", func() {
+			_, err := ParsePolicy("bad.yaml", []byte("version: 1\nchecks: [\n"))
+			Expect(err).To(MatchError(ContainSubstring("line")))
+		})
+		It("should reject params for checks that take none", func() {
+			_, err := ParsePolicy("bad.yaml", []byte(`version: 1
+name: bad
+checks:
+  - name: RunAsNonRoot
+    level: best
+    params:
+      threshold: 1
+`))
+			errs := policyFileErrors(err)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Line).To(Equal(7))
+		})
+	})
+
+	Context("When a built-in check is given params", func() {
+		It("should configure the check with them", func() {
+			p, err := ParsePolicy("strict.yaml", []byte(`version: 1
+name: strict
+checks:
+  - name: LayerCountAcceptable
+    level: best
+    params:
+      maxLayers: 20
+  - name: HasRequiredLabel
+    level: good
+    params:
+      additionalLabels: [maintainer]
+`))
+			Expect(err).ToNot(HaveOccurred())
+			checks, err := p.Build(ContainerCheckConfig{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(checks[0].Metadata().Description).To(ContainSubstring("less than 20 layers"))
+			Expect(checks[1].Help().Suggestion).To(ContainSubstring("maintainer"))
+		})
+		It("should reject a negative layer maximum", func() {
+			p, err := ParsePolicy("strict.yaml", []byte(`version: 1
+name: strict
+checks:
+  - name: LayerCountAcceptable
+    level: best
+    params:
+      maxLayers: -1
+`))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = p.Build(ContainerCheckConfig{})
+			Expect(err).To(MatchError(ContainSubstring("maxLayers")))
+		})
+		It("should reject an unknown modified files mode", func() {
+			p, err := ParsePolicy("strict.yaml", []byte(`version: 1
+name: strict
+checks:
+  - name: HasModifiedFiles
+    level: best
+    params:
+      mode: rpm
+`))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = p.Build(ContainerCheckConfig{})
+			Expect(err).To(MatchError(ContainSubstring("verify")))
+		})
+	})
+
+	Context("When a check is built by name", func() {
+		It("should use the check's default params", func() {
+			ch, err := NewCheck("LayerCountAcceptable", ContainerCheckConfig{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ch.Metadata().Description).To(ContainSubstring("less than 40 layers"))
+			Expect(CheckParams("LayerCountAcceptable")).To(Equal([]string{"maxLayers"}))
+			Expect(CheckParams("RunAsNonRoot")).To(BeEmpty())
+		})
+		It("should reject unknown checks", func() {
+			_, err := NewCheck("NoSuchCheck", ContainerCheckConfig{})
+			Expect(err).To(MatchError(ContainSubstring("NoSuchCheck")))
+		})
+	})
+
+	Context("When a policy lists an external check", func() {
+		It("should build it at the policy's level, resolving its command from the policy file", func() {
+			p, err := ParsePolicy("/etc/policies/inhouse.yaml", []byte(`version: 1
+name: inhouse
+checks:
+  - name: NoTelnet
+    level: good
+    external:
+      command: ./bin/no-telnet
+      args: ["${IMAGE_FS_PATH}"]
+      description: Checks that telnet is not installed.
+`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.Checks[0].external.Command).To(Equal("/etc/policies/bin/no-telnet"))
+			checks, err := p.Build(ContainerCheckConfig{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(checks[0].Name()).To(Equal("NoTelnet"))
+			Expect(checks[0].Metadata().Level).To(Equal("good"))
+			Expect(checks[0].Metadata().Description).To(Equal("Checks that telnet is not installed."))
+		})
+		It("should reject external checks named like a built-in check, or without a command", func() {
+			_, err := ParsePolicy("bad.yaml", []byte(`version: 1
+name: bad
+checks:
+  - name: HasLicense
+    level: best
+    external:
+      command: /bin/true
+  - name: NoCommand
+    level: best
+    external:
+      args: [x]
+`))
+			errs := policyFileErrors(err)
+			Expect(errs).To(HaveLen(2))
+			Expect(errs[0].Line).To(Equal(4))
+			Expect(errs[1].Line).To(Equal(11))
+			Expect(errs[1].Msg).To(ContainSubstring("command is required"))
+		})
+	})
+
+	Context("When a check takes params", func() {
+		BeforeEach(func() {
+			registry["Tunable"] = checkDefinition{
+				newParams: func() any { return &tunableParams{} },
+				build: func(_ ContainerCheckConfig, params any) (types.Check, error) {
+					Expect(params.(*tunableParams).Threshold).To(Equal(3))
+					return &policy.HasLicenseCheck{}, nil
+				},
+			}
+			DeferCleanup(func() { delete(registry, "Tunable") })
+		})
+		It("should decode them for the check", func() {
+			p, err := ParsePolicy("tunable.yaml", []byte(`version: 1
+name: tunable
+checks:
+  - name: Tunable
+    level: best
+    params:
+      threshold: 3
+`))
+			Expect(err).ToNot(HaveOccurred())
+			_, err = p.Build(ContainerCheckConfig{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("should reject params the check does not know", func() {
+			_, err := ParsePolicy("tunable.yaml", []byte(`version: 1
+name: tunable
+checks:
+  - name: Tunable
+    level: best
+    params:
+      thershold: 3
+`))
+			errs := policyFileErrors(err)
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0].Line).To(Equal(7))
+			Expect(errs[0].Msg).To(ContainSubstring("threshold"))
+		})
+	})
+})
@@ -0,0 +1,176 @@
+package checks
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/policy"
+	"github.com/opdev/container-certification/internal/pyxis"
+	"github.com/opdev/container-certification/internal/transport"
+)
+
+// checkDefinition describes how to build a check named in a policy file.
+type checkDefinition struct {
+	// newParams returns a pointer to a zero value of the check's params
+	// struct, into which the params in a policy file are decoded. It is nil
+	// for checks that take no params.
+	newParams func() any
+	// build returns the check, configured with cfg and the decoded params.
+	build func(cfg ContainerCheckConfig, params any) (types.Check, error)
+}
+
+// registry holds every check that may be listed in a policy file, keyed by check name.
+var registry = map[string]checkDefinition{
+	"HasLicense": {
+		newParams: func() any { return &policy.LicenseOptions{} },
+		build: func(_ ContainerCheckConfig, params any) (types.Check, error) {
+			return policy.NewHasLicenseCheck(*params.(*policy.LicenseOptions)), nil
+		},
+	},
+	"HasUniqueTag": {
+		build: func(cfg ContainerCheckConfig, _ any) (types.Check, error) {
+			return policy.NewHasUniqueTagCheck(cfg.DockerConfig, transport.Options{
+				Insecure: cfg.Insecure,
+				CAFile:   cfg.RegistryCAFile,
+			}), nil
+		},
+	},
+	"LayerCountAcceptable": {
+		newParams: func() any { return &policy.MaxLayersOptions{} },
+		build: func(_ ContainerCheckConfig, params any) (types.Check, error) {
+			opts := *params.(*policy.MaxLayersOptions)
+			if opts.MaxLayers < 0 {
+				return nil, fmt.Errorf("maxLayers must not be negative, got %d", opts.MaxLayers)
+			}
+			return policy.NewMaxLayersCheck(opts), nil
+		},
+	},
+	"HasNoProhibitedPackages": {
+		newParams: func() any { return &policy.ProhibitedPackagesOptions{} },
+		build: func(_ ContainerCheckConfig, params any) (types.Check, error) {
+			return policy.NewHasNoProhibitedPackagesCheck(*params.(*policy.ProhibitedPackagesOptions)), nil
+		},
+	},
+	"HasRequiredLabel": {
+		newParams: func() any { return &policy.RequiredLabelsOptions{} },
+		build: func(_ ContainerCheckConfig, params any) (types.Check, error) {
+			return policy.NewHasRequiredLabelsCheck(*params.(*policy.RequiredLabelsOptions)), nil
+		},
+	},
+	"RunAsNonRoot": {
+		build: func(ContainerCheckConfig, any) (types.Check, error) {
+			return &policy.RunAsNonRootCheck{}, nil
+		},
+	},
+	"SupportsArbitraryUID": {
+		build: func(ContainerCheckConfig, any) (types.Check, error) {
+			return &policy.SupportsArbitraryUIDCheck{}, nil
+		},
+	},
+	"HasModifiedFiles": {
+		newParams: func() any { return &policy.ModifiedFilesOptions{} },
+		build: func(_ ContainerCheckConfig, params any) (types.Check, error) {
+			opts := *params.(*policy.ModifiedFilesOptions)
+			switch opts.Mode {
+			case "", policy.ModifiedFilesModeLayers, policy.ModifiedFilesModeVerify:
+			default:
+				return nil, fmt.Errorf("mode must be %s or %s, got %q", policy.ModifiedFilesModeLayers, policy.ModifiedFilesModeVerify, opts.Mode)
+			}
+			return policy.NewHasModifiedFilesCheck(opts), nil
+		},
+	},
+	"HasNoEmbeddedSecrets": {
+		newParams: func() any { return &policy.EmbeddedSecretsOptions{} },
+		build: func(_ ContainerCheckConfig, params any) (types.Check, error) {
+			check, err := policy.NewHasNoEmbeddedSecretsCheck(*params.(*policy.EmbeddedSecretsOptions))
+			if err != nil {
+				return nil, err
+			}
+			return check, nil
+		},
+	},
+	"HasNoFixableVulnerabilities": {
+		newParams: func() any { return &policy.VulnerabilitiesOptions{} },
+		build: func(cfg ContainerCheckConfig, params any) (types.Check, error) {
+			opts := *params.(*policy.VulnerabilitiesOptions)
+			if len(opts.AdvisoryFiles) == 0 {
+				opts.AdvisoryFiles = cfg.AdvisoryFiles
+			}
+			return policy.NewHasNoFixableVulnerabilitiesCheck(opts), nil
+		},
+	},
+	"HasTrustedPackageSignatures": {
+		newParams: func() any { return &policy.PackageSignaturesOptions{} },
+		build: func(_ ContainerCheckConfig, params any) (types.Check, error) {
+			return policy.NewHasTrustedPackageSignaturesCheck(*params.(*policy.PackageSignaturesOptions)), nil
+		},
+	},
+	"BasedOnUbi": {
+		build: func(cfg ContainerCheckConfig, _ any) (types.Check, error) {
+			client := pyxis.NewPyxisClient(
+				cfg.PyxisHost,
+				cfg.PyxisAPIToken,
+				cfg.CertificationProjectID,
+				&http.Client{Timeout: 60 * time.Second})
+			client.Retry = cfg.PyxisRetry
+			return policy.NewBasedOnUbiCheck(client), nil
+		},
+	},
+}
+
+// CheckNames returns the names of every check that may be listed in a policy.
+func CheckNames() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewCheck returns the check named name, configured with cfg and its default
+// params.
+func NewCheck(name string, cfg ContainerCheckConfig) (types.Check, error) {
+	def, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown check %q", name)
+	}
+
+	var params any
+	if def.newParams != nil {
+		params = def.newParams()
+	}
+	return def.build(cfg, params)
+}
+
+// CheckParams returns the params that may be set for the check named name in a
+// policy file. It returns nil for checks that take no params.
+func CheckParams(name string) []string {
+	def, ok := registry[name]
+	if !ok || def.newParams == nil {
+		return nil
+	}
+	return yamlFieldNames(def.newParams())
+}
+
+// leveledCheck is a check whose enforcement level was set by a policy file.
+type leveledCheck struct {
+	types.Check
+	level string
+}
+
+func (l leveledCheck) Metadata() types.Metadata {
+	m := l.Check.Metadata()
+	m.Level = l.level
+	return m
+}
+
+// Unwrap returns the check as it was built, so that the engine can find the
+// optional interfaces it implements.
+func (l leveledCheck) Unwrap() types.Check {
+	return l.Check
+}
@@ -22,31 +22,64 @@ type cobraRunEFunc = func(cmd *cobra.Command, args []string) error
 // used for individual checks contained in debugging binaries.
 func RunEFunctionWithCheck(ch types.Check) cobraRunEFunc {
 	return func(cmd *cobra.Command, args []string) error {
-		ctx := configureLoggerAndStuffInto(cmd.Context())
-		checks := []types.Check{ch}
-		// TODO(Jose): Should we just rely in a viper config instead, and let each caller bind their own flags?
-		dockerCfg, _ := cmd.Flags().GetString(flags.KeyDockerConfig)
-		platform, _ := cmd.Flags().GetString(flags.KeyPlatform)
-
-		engine := &crane.CraneEngine{
-			DockerConfig: dockerCfg,
-			Image:        args[0],
-			Checks:       checks,
-			Platform:     platform,
-			IsScratch:    false,
-			Insecure:     false, // TODO(Jose): This isn't wired because this probably needs to come from the preflight tool? Maybe not.
-		}
+		return RunChecks(cmd, args[0], []types.Check{ch}, false)
+	}
+}
 
-		if err := engine.ExecuteChecks(ctx); err != nil {
-			return err
-		}
+// RunChecks runs checks against image, configured with the flags bound by
+// BindBaseFlags, and prints the results in the format selected with --output.
+func RunChecks(cmd *cobra.Command, image string, checks []types.Check, isScratch bool) error {
+	ctx := configureLoggerAndStuffInto(cmd.Context())
+	output, _ := cmd.Flags().GetString(flags.KeyOutput)
+	formatter, err := formatterFor(output)
+	if err != nil {
+		return err
+	}
+
+	// TODO(Jose): Should we just rely in a viper config instead, and let each caller bind their own flags?
+	dockerCfg, _ := cmd.Flags().GetString(flags.KeyDockerConfig)
+	platform, _ := cmd.Flags().GetString(flags.KeyPlatform)
+	platforms, _ := cmd.Flags().GetStringSlice(flags.KeyPlatforms)
+	parallelism, _ := cmd.Flags().GetInt(flags.KeyCheckParallelism)
+	checkTimeout, _ := cmd.Flags().GetDuration(flags.KeyCheckTimeout)
+	timeoutOverrides, _ := cmd.Flags().GetStringSlice(flags.KeyCheckTimeoutOverrides)
+	checkTimeouts, err := crane.ParseCheckTimeouts(timeoutOverrides)
+	if err != nil {
+		return err
+	}
+	layerCacheDir, _ := cmd.Flags().GetString(flags.KeyLayerCacheDir)
+	layerCacheMaxMB, _ := cmd.Flags().GetInt64(flags.KeyLayerCacheMaxMB)
+	insecure, _ := cmd.Flags().GetBool(flags.KeyInsecure)
+	registryCAFile, _ := cmd.Flags().GetString(flags.KeyRegistryCAFile)
+
+	engine := &crane.CraneEngine{
+		DockerConfig:       dockerCfg,
+		Image:              image,
+		Checks:             checks,
+		Platform:           platform,
+		Platforms:          platforms,
+		IsScratch:          isScratch,
+		Insecure:           insecure,
+		RegistryCAFile:     registryCAFile,
+		Parallelism:        parallelism,
+		CheckTimeout:       checkTimeout,
+		CheckTimeouts:      checkTimeouts,
+		LayerCacheDir:      layerCacheDir,
+		LayerCacheMaxBytes: layerCacheMaxMB * 1024 * 1024,
+	}
 
-		results := engine.Results(ctx)
-		textResults, _ := formatAsText(ctx, results)
+	if err := engine.ExecuteChecks(ctx); err != nil {
+		return err
+	}
 
-		fmt.Fprintln(cmd.OutOrStdout(), string(textResults))
-		return nil
+	results := engine.Results(ctx)
+	formatted, err := formatter(ctx, results)
+	if err != nil {
+		return fmt.Errorf("could not format results as %s: %w", output, err)
 	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), string(formatted))
+	return nil
 }
 
 type FormatterFunc = func(context.Context, types.Results) (response []byte, formattingError error)
@@ -63,6 +96,9 @@ var formatAsText FormatterFunc = func(_ context.Context, r types.Results) (respo
 		t := v.ElapsedTime.Milliseconds()
 		s := fmt.Sprintf("FAILED  %s in %dms\n", v.Name(), t)
 		b = append(b, []byte(s)...)
+		if suggestion := v.Help().Suggestion; suggestion != "" {
+			b = append(b, []byte("        "+suggestion+"\n")...)
+		}
 	}
 	for _, v := range r.Errors {
 		t := v.ElapsedTime.Milliseconds()
@@ -73,10 +109,16 @@ var formatAsText FormatterFunc = func(_ context.Context, r types.Results) (respo
 	return b, nil
 }
 
-// BindBaseFlags binds flags expected by this package's RunEFunctionWithCheck function.
+// BindBaseFlags binds flags expected by this package's RunEFunctionWithCheck and
+// RunChecks functions.
 func BindBaseFlags(f *pflag.FlagSet) {
 	flags.BindFlagDockerConfigFilePath(f)
 	flags.BindFlagsImagePlatform(f)
+	flags.BindFlagCheckParallelism(f)
+	flags.BindFlagCheckTimeouts(f)
+	flags.BindFlagsLayerCache(f)
+	flags.BindFlagOutput(f)
+	flags.BindFlagsRegistryTLS(f)
 }
 
 func configureLoggerAndStuffInto(ctx context.Context) context.Context {
@@ -0,0 +1,418 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
+)
+
+// toolName identifies this tool in reports that name the tool that produced them.
+const toolName = "container-certification"
+
+// formatters are the output formats that may be selected with --output.
+var formatters = map[string]FormatterFunc{
+	"text":     formatAsText,
+	"json":     formatAsJSON,
+	"junit":    formatAsJUnit,
+	"sarif":    formatAsSARIF,
+	"markdown": formatAsMarkdown,
+}
+
+// formatterFor returns the formatter for output.
+func formatterFor(output string) (FormatterFunc, error) {
+	formatter, ok := formatters[output]
+	if !ok {
+		names := make([]string, 0, len(formatters))
+		for name := range formatters {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown output format %q, must be one of %s", output, strings.Join(names, ", "))
+	}
+	return formatter, nil
+}
+
+const (
+	statusPassed  = "PASSED"
+	statusFailed  = "FAILED"
+	statusErrored = "ERRORED"
+)
+
+// checkResult is a single check result, flattened for the formatters.
+type checkResult struct {
+	types.Result
+	status string
+}
+
+// suggestion returns the help text relevant to the result: the suggestion for a
+// failed check, and the message for one that errored.
+func (r checkResult) suggestion() string {
+	switch r.status {
+	case statusFailed:
+		return r.Help().Suggestion
+	case statusErrored:
+		return r.Help().Message
+	}
+	return ""
+}
+
+// allResults returns the passed, failed and errored results of r, in that order.
+func allResults(r types.Results) []checkResult {
+	all := make([]checkResult, 0, len(r.Passed)+len(r.Failed)+len(r.Errors))
+	for _, v := range r.Passed {
+		all = append(all, checkResult{Result: v, status: statusPassed})
+	}
+	for _, v := range r.Failed {
+		all = append(all, checkResult{Result: v, status: statusFailed})
+	}
+	for _, v := range r.Errors {
+		all = append(all, checkResult{Result: v, status: statusErrored})
+	}
+	return all
+}
+
+// totalElapsed returns the sum of the time taken by each check.
+func totalElapsed(results []checkResult) time.Duration {
+	var total time.Duration
+	for _, v := range results {
+		total += v.ElapsedTime
+	}
+	return total
+}
+
+type jsonReport struct {
+	Image   string      `json:"image"`
+	Passed  bool        `json:"passed"`
+	Results []jsonCheck `json:"results"`
+}
+
+type jsonCheck struct {
+	Name             string `json:"name"`
+	Status           string `json:"status"`
+	ElapsedMillis    int64  `json:"elapsed_ms"`
+	Level            string `json:"level"`
+	Description      string `json:"description"`
+	KnowledgeBaseURL string `json:"knowledgebase_url,omitempty"`
+	CheckURL         string `json:"check_url,omitempty"`
+	Help             string `json:"help,omitempty"`
+	// Findings is set for checks that report findings.
+	Findings []findings.Finding `json:"findings,omitempty"`
+}
+
+var formatAsJSON FormatterFunc = func(_ context.Context, r types.Results) ([]byte, error) {
+	report := jsonReport{Image: r.TestedImage, Passed: r.PassedOverall, Results: []jsonCheck{}}
+	for _, v := range allResults(r) {
+		meta := v.Metadata()
+		check := jsonCheck{
+			Name:             v.Name(),
+			Status:           v.status,
+			ElapsedMillis:    v.ElapsedTime.Milliseconds(),
+			Level:            meta.Level,
+			Description:      meta.Description,
+			KnowledgeBaseURL: meta.KnowledgeBaseURL,
+			CheckURL:         meta.CheckURL,
+			Help:             v.suggestion(),
+		}
+		if checkReport, ok := findings.ReportOf(v.Check); ok {
+			check.Findings = checkReport.Findings
+		}
+		report.Results = append(report.Results, check)
+	}
+	return json.MarshalIndent(report, "", "    ")
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string          `xml:"name,attr"`
+	Classname  string          `xml:"classname,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property,omitempty"`
+	Failure    *junitMessage   `xml:"failure,omitempty"`
+	Error      *junitMessage   `xml:"error,omitempty"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitSeconds formats d as JUnit expects durations, in seconds.
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+var formatAsJUnit FormatterFunc = func(_ context.Context, r types.Results) ([]byte, error) {
+	results := allResults(r)
+	suite := junitTestSuite{
+		Name:     r.TestedImage,
+		Tests:    len(results),
+		Failures: len(r.Failed),
+		Errors:   len(r.Errors),
+		Time:     junitSeconds(totalElapsed(results)),
+	}
+
+	for _, v := range results {
+		meta := v.Metadata()
+		tc := junitTestCase{
+			Name:      v.Name(),
+			Classname: toolName,
+			Time:      junitSeconds(v.ElapsedTime),
+			Properties: []junitProperty{
+				{Name: "description", Value: meta.Description},
+				{Name: "level", Value: meta.Level},
+				{Name: "knowledgebase_url", Value: meta.KnowledgeBaseURL},
+			},
+		}
+
+		details := fmt.Sprintf("%s\n\n%s\nMore information: %s", v.suggestion(), meta.Description, meta.KnowledgeBaseURL)
+		switch v.status {
+		case statusFailed:
+			tc.Failure = &junitMessage{Message: v.suggestion(), Type: statusFailed, Text: details}
+		case statusErrored:
+			tc.Error = &junitMessage{Message: v.suggestion(), Type: statusErrored, Text: details}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	report := junitTestSuites{
+		Name:     toolName,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Errors:   suite.Errors,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	out, err := xml.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+// The SARIF types below cover the parts of the SARIF 2.1.0 schema used to report
+// check results.
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string          `json:"id"`
+	ShortDescription sarifMessage    `json:"shortDescription"`
+	FullDescription  sarifMessage    `json:"fullDescription"`
+	HelpURI          string          `json:"helpUri,omitempty"`
+	Help             *sarifMessage   `json:"help,omitempty"`
+	Properties       sarifProperties `json:"properties"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level      string                   `json:"level"`
+	Message    sarifMessage             `json:"message"`
+	Descriptor sarifDescriptorReference `json:"descriptor"`
+	Properties map[string]any           `json:"properties,omitempty"`
+}
+
+type sarifDescriptorReference struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	RuleIndex  int             `json:"ruleIndex"`
+	Kind       string          `json:"kind"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties map[string]any  `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifProperties struct {
+	Level string `json:"level"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// formatAsSARIF reports each check as a rule. Passed and failed checks are
+// reported as results, and checks that errored as tool execution notifications,
+// since they neither passed nor failed.
+var formatAsSARIF FormatterFunc = func(_ context.Context, r types.Results) ([]byte, error) {
+	run := sarifRun{
+		Tool:        sarifTool{Driver: sarifDriver{Name: toolName, Rules: []sarifRule{}}},
+		Invocations: []sarifInvocation{{ExecutionSuccessful: len(r.Errors) == 0}},
+		Results:     []sarifResult{},
+	}
+
+	// Scanners locate findings in files. The image is the closest equivalent.
+	location := []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+		ArtifactLocation: sarifArtifactLocation{URI: r.TestedImage},
+	}}}
+
+	for i, v := range allResults(r) {
+		meta := v.Metadata()
+		rule := sarifRule{
+			ID:               v.Name(),
+			ShortDescription: sarifMessage{Text: v.Name()},
+			FullDescription:  sarifMessage{Text: meta.Description},
+			HelpURI:          meta.KnowledgeBaseURL,
+			Properties:       sarifProperties{Level: meta.Level},
+		}
+		if suggestion := v.Help().Suggestion; suggestion != "" {
+			rule.Help = &sarifMessage{Text: suggestion}
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+
+		properties := map[string]any{"elapsed_ms": v.ElapsedTime.Milliseconds()}
+		switch v.status {
+		case statusErrored:
+			run.Invocations[0].ToolExecutionNotifications = append(run.Invocations[0].ToolExecutionNotifications, sarifNotification{
+				Level:      "error",
+				Message:    sarifMessage{Text: v.suggestion()},
+				Descriptor: sarifDescriptorReference{ID: v.Name()},
+				Properties: properties,
+			})
+			continue
+		case statusFailed:
+			run.Results = append(run.Results, sarifResult{
+				RuleID:     v.Name(),
+				RuleIndex:  i,
+				Kind:       "fail",
+				Level:      "error",
+				Message:    sarifMessage{Text: fmt.Sprintf("%s failed. %s", v.Name(), v.suggestion())},
+				Locations:  location,
+				Properties: properties,
+			})
+		default:
+			run.Results = append(run.Results, sarifResult{
+				RuleID:     v.Name(),
+				RuleIndex:  i,
+				Kind:       "pass",
+				Level:      "none",
+				Message:    sarifMessage{Text: v.Name() + " passed."},
+				Locations:  location,
+				Properties: properties,
+			})
+		}
+	}
+
+	return json.MarshalIndent(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}}, "", "    ")
+}
+
+var formatAsMarkdown FormatterFunc = func(_ context.Context, r types.Results) ([]byte, error) {
+	results := allResults(r)
+	var b bytes.Buffer
+
+	overall := statusPassed
+	if !r.PassedOverall {
+		overall = statusFailed
+	}
+	fmt.Fprintf(&b, "# Certification results for `%s`\n\n", r.TestedImage)
+	fmt.Fprintf(&b, "**%s**: %d passed, %d failed, %d errored in %dms\n\n",
+		overall, len(r.Passed), len(r.Failed), len(r.Errors), totalElapsed(results).Milliseconds())
+
+	b.WriteString("| Check | Result | Duration | Description |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+	for _, v := range results {
+		meta := v.Metadata()
+		name := v.Name()
+		if meta.KnowledgeBaseURL != "" {
+			name = fmt.Sprintf("[%s](%s)", name, meta.KnowledgeBaseURL)
+		}
+		fmt.Fprintf(&b, "| %s | %s | %dms | %s |\n", name, v.status, v.ElapsedTime.Milliseconds(), markdownCell(meta.Description))
+	}
+
+	var needsAttention []checkResult
+	for _, v := range results {
+		if v.status != statusPassed {
+			needsAttention = append(needsAttention, v)
+		}
+	}
+	if len(needsAttention) > 0 {
+		b.WriteString("\n## Suggestions\n")
+		for _, v := range needsAttention {
+			fmt.Fprintf(&b, "\n### %s (%s)\n\n%s\n", v.Name(), v.status, v.suggestion())
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+// markdownCell escapes s so that it can be placed in a table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
+)
+
+// describedCheck is a check that is only used for its name, metadata and help text.
+type describedCheck struct {
+	name string
+}
+
+func (c describedCheck) Validate(context.Context, types.ImageReference) (bool, error) {
+	return true, nil
+}
+
+func (c describedCheck) Name() string {
+	return c.name
+}
+
+func (c describedCheck) Metadata() types.Metadata {
+	return types.Metadata{
+		Description:      c.name + " | description",
+		Level:            "best",
+		KnowledgeBaseURL: "https://example.com/" + c.name,
+	}
+}
+
+func (c describedCheck) Help() types.HelpText {
+	return types.HelpText{
+		Message:    c.name + " encountered an error.",
+		Suggestion: "Fix " + c.name + ".",
+	}
+}
+
+// reportedCheck is a describedCheck that reports findings.
+type reportedCheck struct {
+	describedCheck
+	report findings.Report
+}
+
+func (c reportedCheck) Report() findings.Report {
+	return c.report
+}
+
+var _ = Describe("Result formatters", func() {
+	results := types.Results{
+		TestedImage:   "quay.io/example/image:v1",
+		PassedOverall: false,
+		Passed:        []types.Result{{Check: describedCheck{name: "Passes"}, ElapsedTime: 1500 * time.Millisecond}},
+		Failed:        []types.Result{{Check: describedCheck{name: "Fails"}, ElapsedTime: 20 * time.Millisecond}},
+		Errors:        []types.Result{{Check: describedCheck{name: "Errors"}, ElapsedTime: 3 * time.Millisecond}},
+	}
+
+	It("should reject unknown output formats", func() {
+		_, err := formatterFor("yaml")
+		Expect(err).To(MatchError(ContainSubstring("junit")))
+	})
+
+	It("should include the suggestion for failures in text", func() {
+		out, err := formatAsText(context.TODO(), results)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(out)).To(ContainSubstring("FAILED  Fails in 20ms\n        Fix Fails."))
+	})
+
+	It("should report every check in JSON", func() {
+		out, err := formatAsJSON(context.TODO(), results)
+		Expect(err).ToNot(HaveOccurred())
+		var report jsonReport
+		Expect(json.Unmarshal(out, &report)).To(Succeed())
+		Expect(report.Results).To(HaveLen(3))
+		Expect(report.Results[1]).To(Equal(jsonCheck{
+			Name:             "Fails",
+			Status:           statusFailed,
+			ElapsedMillis:    20,
+			Level:            "best",
+			Description:      "Fails | description",
+			KnowledgeBaseURL: "https://example.com/Fails",
+			Help:             "Fix Fails.",
+		}))
+	})
+
+	It("should include the findings of each check in JSON", func() {
+		found := []findings.Finding{{Kind: findings.KindPackage, Subject: "openssl-1.0-1.el9.x86_64", Detail: "fixable"}}
+		out, err := formatAsJSON(context.TODO(), types.Results{
+			Failed: []types.Result{{Check: reportedCheck{describedCheck: describedCheck{name: "Vulnerable"}, report: findings.Report{Findings: found}}}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		var report jsonReport
+		Expect(json.Unmarshal(out, &report)).To(Succeed())
+		Expect(report.Results).To(HaveLen(1))
+		Expect(report.Results[0].Findings).To(Equal(found))
+	})
+
+	It("should report failures and errors as JUnit test cases", func() {
+		out, err := formatAsJUnit(context.TODO(), results)
+		Expect(err).ToNot(HaveOccurred())
+		var report junitTestSuites
+		Expect(xml.Unmarshal(out, &report)).To(Succeed())
+		Expect(report.Tests).To(Equal(3))
+		Expect(report.Failures).To(Equal(1))
+		Expect(report.Errors).To(Equal(1))
+		cases := report.Suites[0].TestCases
+		Expect(cases[0].Time).To(Equal("1.500"))
+		Expect(cases[0].Failure).To(BeNil())
+		Expect(cases[1].Failure.Message).To(Equal("Fix Fails."))
+		Expect(cases[2].Error.Message).To(Equal("Errors encountered an error."))
+	})
+
+	It("should report a SARIF rule per check and errors as notifications", func() {
+		out, err := formatAsSARIF(context.TODO(), results)
+		Expect(err).ToNot(HaveOccurred())
+		var log sarifLog
+		Expect(json.Unmarshal(out, &log)).To(Succeed())
+		Expect(log.Version).To(Equal("2.1.0"))
+		run := log.Runs[0]
+		Expect(run.Tool.Driver.Rules).To(HaveLen(3))
+		Expect(run.Results).To(HaveLen(2))
+		Expect(run.Results[1].Kind).To(Equal("fail"))
+		Expect(run.Results[1].RuleIndex).To(Equal(1))
+		Expect(run.Results[1].Locations[0].PhysicalLocation.ArtifactLocation.URI).To(Equal("quay.io/example/image:v1"))
+		Expect(run.Invocations[0].ExecutionSuccessful).To(BeFalse())
+		Expect(run.Invocations[0].ToolExecutionNotifications[0].Descriptor.ID).To(Equal("Errors"))
+	})
+
+	It("should escape table cells in Markdown", func() {
+		out, err := formatAsMarkdown(context.TODO(), results)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(out)).To(ContainSubstring("| [Fails](https://example.com/Fails) | FAILED | 20ms | Fails \\| description |"))
+		Expect(string(out)).To(ContainSubstring("### Fails (FAILED)\n\nFix Fails."))
+	})
+})
@@ -1,17 +1,13 @@
 package crane
 
 import (
-	"archive/tar"
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path"
-	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -24,8 +20,11 @@ import (
 
 	"github.com/opdev/container-certification/internal/authn"
 	"github.com/opdev/container-certification/internal/defaults"
+	"github.com/opdev/container-certification/internal/layercache"
 	"github.com/opdev/container-certification/internal/pyxis"
 	"github.com/opdev/container-certification/internal/rpm"
+	"github.com/opdev/container-certification/internal/sbom"
+	"github.com/opdev/container-certification/internal/transport"
 
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
@@ -53,6 +52,12 @@ type CraneEngine struct {
 	// Platform is the container platform to use. E.g. amd64.
 	Platform string
 
+	// Platforms selects the platforms to certify when Image is a
+	// multi-architecture image index, e.g. amd64 and arm64. Use
+	// AllPlatforms to certify every platform in the index. When set,
+	// Platform is ignored.
+	Platforms []string
+
 	// // IsBundle is an indicator that the asset is a bundle.
 	// IsBundle bool
 
@@ -63,8 +68,35 @@ type CraneEngine struct {
 	// the registry crane connects with.
 	Insecure bool
 
-	imageRef types.ImageReference
-	results  types.Results
+	// RegistryCAFile is a PEM bundle of CAs to trust, in addition to the
+	// system CAs, when connecting to the registry. Insecure takes precedence.
+	RegistryCAFile string
+
+	// Parallelism is the maximum number of checks to run concurrently.
+	// Values less than 2 run the checks sequentially. Results are always
+	// reported in the order of Checks.
+	Parallelism int
+
+	// CheckTimeout is the deadline for each check. Zero means checks
+	// run without a deadline.
+	CheckTimeout time.Duration
+
+	// CheckTimeouts overrides CheckTimeout for individual checks,
+	// keyed by check name.
+	CheckTimeouts map[string]time.Duration
+
+	// LayerCacheDir is a directory in which to keep image layers between
+	// runs. If empty, layers are cached in a temporary directory that is
+	// removed when checks complete.
+	LayerCacheDir string
+
+	// LayerCacheMaxBytes caps the size of the layer cache in LayerCacheDir.
+	// The least recently used layers are evicted beyond this size. Zero
+	// means the cache is not capped.
+	LayerCacheMaxBytes int64
+
+	results         types.Results
+	platformResults []PlatformResults
 }
 
 func export(img cranev1.Image, w io.Writer) error {
@@ -99,27 +131,11 @@ func (c *CraneEngine) ExecuteChecks(ctx context.Context) error {
 		retryOnceAfter(5 * time.Second),
 	}
 
-	if c.Insecure {
-		// Adding WithTransport opt is a workaround to allow for access to HTTPS
-		// container registries with self-signed or non-trusted certificates.
-		//
-		// See https://github.com/google/go-containerregistry/issues/1553 for more context. If this issue
-		// is resolved, then this workaround can likely be removed or adjusted to use new features in the
-		// go-containerregistry project.
-		rt := remote.DefaultTransport.(*http.Transport).Clone()
-		rt.TLSClientConfig = &tls.Config{
-			InsecureSkipVerify: true, //nolint: gosec
-		}
-
-		options = append(options, crane.Insecure, crane.WithTransport(rt))
-	}
-
-	// pull the image and save to fs
-	logger.V(log.DBG).Info("pulling image from target registry")
-	img, err := crane.Pull(c.Image, options...)
+	registryOptions, err := c.registryTransport().CraneOptions()
 	if err != nil {
-		return fmt.Errorf("failed to pull remote container: %v", err)
+		return err
 	}
+	options = append(options, registryOptions...)
 
 	// create tmpdir to receive extracted fs
 	tmpdir, err := os.MkdirTemp(os.TempDir(), "preflight-*")
@@ -133,65 +149,27 @@ func (c *CraneEngine) ExecuteChecks(ctx context.Context) error {
 		}
 	}()
 
-	imageTarPath := path.Join(tmpdir, "cache")
-	if err := os.Mkdir(imageTarPath, 0o755); err != nil {
-		return fmt.Errorf("failed to create cache directory: %s: %v", imageTarPath, err)
-	}
-
-	img = cache.Image(img, cache.NewFilesystemCache(imageTarPath))
-
-	containerFSPath := path.Join(tmpdir, "fs")
-	if err := os.Mkdir(containerFSPath, 0o755); err != nil {
-		return fmt.Errorf("failed to create container expansion directory: %s: %v", containerFSPath, err)
-	}
-
-	// export/flatten, and extract
-	logger.V(log.DBG).Info("exporting and flattening image")
-	r, w := io.Pipe()
-	go func() {
-		logger.V(log.DBG).Info("writing container filesystem", "outputDirectory", containerFSPath)
-
-		// Close the writer with any errors encountered during
-		// extraction. These errors will be returned by the reader end
-		// on subsequent reads. If err == nil, the reader will return
-		// EOF.
-		w.CloseWithError(export(img, w))
-	}()
-
-	logger.V(log.DBG).Info("extracting container filesystem", "path", containerFSPath)
-	if err := untar(ctx, containerFSPath, r); err != nil {
-		return fmt.Errorf("failed to extract tarball: %v", err)
-	}
-
-	// explicitly discarding from the reader for cases where there is data in the reader after it sends an EOF
-	_, err = io.Copy(io.Discard, r)
-	if err != nil {
-		return fmt.Errorf("failed to drain io reader: %v", err)
-	}
-
-	reference, err := name.ParseReference(c.Image)
+	localSrc, isLocal := parseLocalSource(c.Image)
+	images, idx, reference, err := c.loadImages(ctx, tmpdir, localSrc, isLocal, options)
 	if err != nil {
-		return fmt.Errorf("image uri could not be parsed: %v", err)
-	}
-
-	// store the image internals in the engine image reference to pass to validations.
-	c.imageRef = types.ImageReference{
-		ImageURI:        c.Image,
-		ImageFSPath:     containerFSPath,
-		ImageInfo:       img,
-		ImageRegistry:   reference.Context().RegistryStr(),
-		ImageRepository: reference.Context().RepositoryStr(),
-		ImageTagOrSha:   reference.Identifier(),
+		return err
 	}
 
-	if err := writeCertImage(ctx, c.imageRef); err != nil {
-		return fmt.Errorf("could not write cert image: %v", err)
-	}
-
-	if !c.IsScratch {
-		if err := writeRPMManifest(ctx, containerFSPath); err != nil {
-			return fmt.Errorf("could not write rpm manifest: %v", err)
+	var layerCache cache.Cache
+	if c.LayerCacheDir != "" {
+		logger.V(log.DBG).Info("using persistent layer cache", "path", c.LayerCacheDir, "maxBytes", c.LayerCacheMaxBytes)
+		persistentCache, err := layercache.New(c.LayerCacheDir, c.LayerCacheMaxBytes)
+		if err != nil {
+			return fmt.Errorf("failed to open layer cache: %v", err)
+		}
+		defer persistentCache.Close()
+		layerCache = persistentCache
+	} else {
+		imageTarPath := path.Join(tmpdir, "cache")
+		if err := os.Mkdir(imageTarPath, 0o755); err != nil {
+			return fmt.Errorf("failed to create cache directory: %s: %v", imageTarPath, err)
 		}
+		layerCache = cache.NewFilesystemCache(imageTarPath)
 	}
 
 	// if c.IsBundle {
@@ -206,49 +184,63 @@ func (c *CraneEngine) ExecuteChecks(ctx context.Context) error {
 	// 	c.results.TestedOn = runtime.UnknownOpenshiftClusterVersion()
 	// }
 
-	// execute checks
-	logger.V(log.DBG).Info("executing checks")
-	for _, ch := range c.Checks {
-		c.results.TestedImage = c.Image
+	c.platformResults = nil
+	imageRefs := make([]types.ImageReference, 0, len(images))
+	for _, pi := range images {
+		pi.image = cache.Image(pi.image, layerCache)
 
-		logger.V(log.DBG).Info("running check", "check", ch.Name())
-		if ch.Metadata().Level == "optional" {
-			logger.Info(fmt.Sprintf("Check %s is not currently being enforced.", ch.Name()))
+		imageRef := types.ImageReference{ImageURI: c.Image}
+		if isLocal {
+			imageRef.ImageRegistry, imageRef.ImageRepository, imageRef.ImageTagOrSha, err = localReferenceFields(localSrc, reference, pi.image)
+			if err != nil {
+				return fmt.Errorf("could not describe local image: %v", err)
+			}
+		} else {
+			imageRef.ImageRegistry = reference.Context().RegistryStr()
+			imageRef.ImageRepository = reference.Context().RepositoryStr()
+			imageRef.ImageTagOrSha = reference.Identifier()
 		}
 
-		// run the validation
-		checkStartTime := time.Now()
-		checkPassed, err := ch.Validate(ctx, c.imageRef)
-		checkElapsedTime := time.Since(checkStartTime)
-
+		imageRef, results, err := c.certify(ctx, tmpdir, pi, imageRef, isLocal)
 		if err != nil {
-			logger.WithValues("result", "ERROR", "err", err.Error()).Info("check completed", "check", ch.Name())
-			c.results.Errors = appendUnlessOptional(c.results.Errors, types.Result{Check: ch, ElapsedTime: checkElapsedTime})
-			continue
+			return err
 		}
+		imageRefs = append(imageRefs, imageRef)
 
-		if !checkPassed {
-			logger.WithValues("result", "FAILED").Info("check completed", "check", ch.Name())
-			c.results.Failed = appendUnlessOptional(c.results.Failed, types.Result{Check: ch, ElapsedTime: checkElapsedTime})
+		if pi.platform == "" {
+			c.results = results
 			continue
 		}
+		c.platformResults = append(c.platformResults, PlatformResults{Platform: pi.platform, Results: results})
+	}
 
-		logger.WithValues("result", "PASSED").Info("check completed", "check", ch.Name())
-		c.results.Passed = appendUnlessOptional(c.results.Passed, types.Result{Check: ch, ElapsedTime: checkElapsedTime})
+	if c.platformResults != nil {
+		for _, pr := range c.platformResults {
+			logger.Info("platform results", "platform", pr.Platform, "passed", pr.Results.PassedOverall,
+				"failed", len(pr.Results.Failed), "errors", len(pr.Results.Errors))
+		}
+		c.results = combinePlatformResults(c.Image, c.platformResults)
 	}
 
-	if len(c.results.Errors) > 0 || len(c.results.Failed) > 0 {
-		c.results.PassedOverall = false
-	} else {
-		c.results.PassedOverall = true
+	if err := writeResults(ctx, c.results, defaults.DefaultTestResultsFilename); err != nil {
+		return fmt.Errorf("could not write results: %v", err)
 	}
 
 	// Inform the user of the tag-digest binding.
 	// By this point, we should have already resolved the digest so
 	// we don't handle this error, but fail safe and don't log a potentially
 	// incorrect line message to the user.
-	if resolvedDigest, err := c.imageRef.ImageInfo.Digest(); err == nil {
-		msg, warn := tagDigestBindingInfo(c.imageRef.ImageTagOrSha, resolvedDigest.String())
+	if len(imageRefs) == 0 {
+		return nil
+	}
+	var resolvedDigest cranev1.Hash
+	if idx != nil {
+		resolvedDigest, err = idx.Digest()
+	} else {
+		resolvedDigest, err = imageRefs[0].ImageInfo.Digest()
+	}
+	if err == nil {
+		msg, warn := tagDigestBindingInfo(imageRefs[0].ImageTagOrSha, resolvedDigest.String())
 		if warn {
 			logger.Info(fmt.Sprintf("Warning: %s", msg))
 		} else {
@@ -259,6 +251,170 @@ func (c *CraneEngine) ExecuteChecks(ctx context.Context) error {
 	return nil
 }
 
+// loadImages loads the images to certify. When c.Platforms is set and the image is
+// an image index, one image is returned for each selected platform, along with the
+// index. Otherwise a single image, selected using c.Platform, is returned.
+func (c *CraneEngine) loadImages(ctx context.Context, tmpdir string, localSrc localSource, isLocal bool, options []crane.Option) ([]platformImage, cranev1.ImageIndex, name.Reference, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	var img cranev1.Image
+	var idx cranev1.ImageIndex
+	var reference name.Reference
+	var err error
+
+	switch {
+	case isLocal:
+		// load the image from the local filesystem
+		logger.V(log.DBG).Info("loading image from local source", "transport", localSrc.transport, "path", localSrc.path)
+		if len(c.Platforms) > 0 {
+			idx, img, reference, err = localSrc.index(ctx, tmpdir)
+		} else {
+			img, reference, err = localSrc.image(ctx, tmpdir, c.Platform)
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to load local container: %v", err)
+		}
+	case len(c.Platforms) > 0:
+		reference, err = name.ParseReference(c.Image)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("image uri could not be parsed: %v", err)
+		}
+
+		logger.V(log.DBG).Info("fetching image manifest from target registry")
+		desc, err := remote.Get(reference, crane.GetOptions(options...).Remote...)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to pull remote container: %v", err)
+		}
+
+		if desc.MediaType.IsIndex() {
+			idx, err = desc.ImageIndex()
+		} else {
+			img, err = desc.Image()
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to pull remote container: %v", err)
+		}
+	default:
+		// pull the image and save to fs
+		logger.V(log.DBG).Info("pulling image from target registry")
+		img, err = crane.Pull(c.Image, options...)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to pull remote container: %v", err)
+		}
+
+		reference, err = name.ParseReference(c.Image)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("image uri could not be parsed: %v", err)
+		}
+	}
+
+	if idx == nil {
+		if len(c.Platforms) > 0 {
+			logger.Info("image is not a multi-architecture image index, so it will be certified as a single image")
+		}
+		return []platformImage{{image: img}}, nil, reference, nil
+	}
+
+	available, err := indexPlatforms(idx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	selected, err := selectPlatforms(available, c.Platforms)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	logger.V(log.DBG).Info("certifying image index", "available", available, "selected", selected)
+
+	images := make([]platformImage, 0, len(selected))
+	for _, platform := range selected {
+		img, err := imageForPlatform(idx, platform)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		images = append(images, platformImage{platform: platform, image: img})
+	}
+
+	return images, idx, reference, nil
+}
+
+// certify extracts the filesystem of pi into workdir, writes its artifacts, and runs
+// the checks against it. It returns imageRef completed with the image internals, and
+// the results. Artifacts are named for pi.platform when it is set, and then include
+// the results of the platform so that they can be submitted on their own.
+func (c *CraneEngine) certify(ctx context.Context, workdir string, pi platformImage, imageRef types.ImageReference, offline bool) (types.ImageReference, types.Results, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	if pi.platform != "" {
+		logger.Info("certifying platform", "platform", pi.platform)
+		workdir = path.Join(workdir, platformPathName(pi.platform))
+		if err := os.Mkdir(workdir, 0o755); err != nil {
+			return types.ImageReference{}, types.Results{}, fmt.Errorf("failed to create platform directory: %s: %v", workdir, err)
+		}
+	}
+
+	containerFSPath := path.Join(workdir, "fs")
+	if err := os.Mkdir(containerFSPath, 0o755); err != nil {
+		return types.ImageReference{}, types.Results{}, fmt.Errorf("failed to create container expansion directory: %s: %v", containerFSPath, err)
+	}
+
+	// export/flatten, and extract
+	logger.V(log.DBG).Info("exporting and flattening image")
+	r, w := io.Pipe()
+	go func() {
+		logger.V(log.DBG).Info("writing container filesystem", "outputDirectory", containerFSPath)
+
+		// Close the writer with any errors encountered during
+		// extraction. These errors will be returned by the reader end
+		// on subsequent reads. If err == nil, the reader will return
+		// EOF.
+		w.CloseWithError(export(pi.image, w))
+	}()
+
+	logger.V(log.DBG).Info("extracting container filesystem", "path", containerFSPath)
+	summary, err := untar(ctx, containerFSPath, r)
+	if err != nil {
+		return types.ImageReference{}, types.Results{}, fmt.Errorf("failed to extract tarball: %v", err)
+	}
+	summary.log(logger)
+
+	// explicitly discarding from the reader for cases where there is data in the reader after it sends an EOF
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return types.ImageReference{}, types.Results{}, fmt.Errorf("failed to drain io reader: %v", err)
+	}
+
+	// store the image internals in the image reference to pass to validations.
+	imageRef.ImageFSPath = containerFSPath
+	imageRef.ImageInfo = pi.image
+
+	if err := writeCertImage(ctx, imageRef, PlatformFilename(defaults.DefaultCertImageFilename, pi.platform)); err != nil {
+		return types.ImageReference{}, types.Results{}, fmt.Errorf("could not write cert image: %v", err)
+	}
+
+	if !c.IsScratch {
+		if err := writeRPMManifest(ctx, containerFSPath, PlatformFilename(defaults.DefaultRPMManifestFilename, pi.platform)); err != nil {
+			return types.ImageReference{}, types.Results{}, fmt.Errorf("could not write rpm manifest: %v", err)
+		}
+		if err := writeSBOMs(ctx, imageRef, pi.platform); err != nil {
+			return types.ImageReference{}, types.Results{}, fmt.Errorf("could not write sbom: %v", err)
+		}
+	}
+
+	// execute checks
+	logger.V(log.DBG).Info("executing checks", "parallelism", c.Parallelism, "platform", pi.platform)
+	results := c.recordOutcomes(c.runChecks(ctx, imageRef, offline))
+	results.TestedImage = c.Image
+	results.PassedOverall = len(results.Errors) == 0 && len(results.Failed) == 0
+
+	if pi.platform != "" {
+		if err := writeResults(ctx, results, PlatformFilename(defaults.DefaultTestResultsFilename, pi.platform)); err != nil {
+			return types.ImageReference{}, types.Results{}, fmt.Errorf("could not write results: %v", err)
+		}
+	}
+
+	return imageRef, results, nil
+}
+
 func appendUnlessOptional(results []types.Result, result types.Result) []types.Result {
 	if result.Check.Metadata().Level == "optional" {
 		return results
@@ -289,83 +445,23 @@ func tagDigestBindingInfo(providedIdentifier string, resolvedDigest string) (msg
 	), false
 }
 
-// Results will return the results of check execution.
+// Results will return the results of check execution. When several platforms of an
+// image index were certified, the results for every platform are combined.
 func (c *CraneEngine) Results(ctx context.Context) types.Results {
 	return c.results
 }
 
-// Untar takes a destination path and a reader; a tar reader loops over the tarfile
-// creating the file structure at 'dst' along the way, and writing any files
-func untar(ctx context.Context, dst string, r io.Reader) error {
-	logger := logr.FromContextOrDiscard(ctx)
-	tr := tar.NewReader(r)
-
-	for {
-		header, err := tr.Next()
-
-		switch {
-		// if no more files are found return
-		case err == io.EOF:
-			return nil
-
-		// return any other error
-		case err != nil:
-			return err
-
-		// if the header is nil, just skip it (not sure how this happens)
-		case header == nil:
-			continue
-		}
-
-		// the target location where the dir/file should be created
-		target := filepath.Join(dst, header.Name)
-
-		// the following switch could also be done using fi.Mode(), not sure if there
-		// a benefit of using one vs. the other.
-		// fi := header.FileInfo()
-
-		// check the file type
-		switch header.Typeflag {
-		// if its a dir and it doesn't exist create it
-		case tar.TypeDir:
-			if _, err := os.Stat(target); err != nil {
-				if err := os.MkdirAll(target, 0o755); err != nil {
-					return err
-				}
-			}
-
-		// if it's a file create it
-		case tar.TypeReg:
-			f, err := os.OpenFile(target, os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
-			if err != nil {
-				return err
-			}
-
-			// copy over contents
-			if _, err := io.Copy(f, tr); err != nil {
-				return err
-			}
-
-			// manually close here after each file operation; defering would cause each file close
-			// to wait until all operations have completed.
-			f.Close()
-
-			// if it's a link create it
-		case tar.TypeSymlink:
-			err := os.Symlink(header.Linkname, filepath.Join(dst, header.Name))
-			if err != nil {
-				logger.V(log.DBG).Info(fmt.Sprintf("Error creating link: %s. Ignoring.", header.Name))
-				continue
-			}
-		}
-	}
+// PlatformResults returns the results for each platform certified from an image
+// index, or nil if a single image was certified.
+func (c *CraneEngine) PlatformResults() []PlatformResults {
+	return c.platformResults
 }
 
 // writeCertImage takes imageRef and writes it to disk as JSON representing a pyxis.CertImage
-// struct. The file is written at path certification.DefaultCertImageFilename.
+// struct. The file is written to the artifacts directory as filename.
 //
 //nolint:unparam // ctx is unused. Keep for future use.
-func writeCertImage(ctx context.Context, imageRef types.ImageReference) error {
+func writeCertImage(ctx context.Context, imageRef types.ImageReference, filename string) error {
 	logger := logr.FromContextOrDiscard(ctx)
 
 	config, err := imageRef.ImageInfo.ConfigFile()
@@ -473,7 +569,7 @@ func writeCertImage(ctx context.Context, imageRef types.ImageReference) error {
 
 	artifactWriter := artifacts.WriterFromContext(ctx)
 	if artifactWriter != nil {
-		fileName, err := artifactWriter.WriteFile(defaults.DefaultCertImageFilename, bytes.NewReader(certImageJSON))
+		fileName, err := artifactWriter.WriteFile(filename, bytes.NewReader(certImageJSON))
 		if err != nil {
 			return fmt.Errorf("failed to save file to artifacts directory: %w", err)
 		}
@@ -489,7 +585,7 @@ func getBgName(srcrpm string) string {
 	return strings.Join(parts[0:len(parts)-2], "-")
 }
 
-func writeRPMManifest(ctx context.Context, containerFSPath string) error {
+func writeRPMManifest(ctx context.Context, containerFSPath string, filename string) error {
 	logger := logr.FromContextOrDiscard(ctx)
 	pkgList, err := rpm.GetPackageList(ctx, containerFSPath)
 	if err != nil {
@@ -510,12 +606,9 @@ func writeRPMManifest(ctx context.Context, containerFSPath string) error {
 		}
 
 		if len(packageInfo.PGP) > 0 {
-			matches := regexp.MustCompile(".*, Key ID (.*)").FindStringSubmatch(packageInfo.PGP)
-			if matches != nil {
-				pgpKeyID = matches[1]
-			} else {
+			pgpKeyID = rpm.PGPKeyID(packageInfo.PGP)
+			if pgpKeyID == "" {
 				logger.V(log.DBG).Info("string did not match the format required", "pgp", packageInfo.PGP)
-				pgpKeyID = ""
 			}
 		}
 
@@ -545,7 +638,7 @@ func writeRPMManifest(ctx context.Context, containerFSPath string) error {
 	}
 
 	if artifactWriter := artifacts.WriterFromContext(ctx); artifactWriter != nil {
-		fileName, err := artifactWriter.WriteFile(defaults.DefaultRPMManifestFilename, bytes.NewReader(rpmManifestJSON))
+		fileName, err := artifactWriter.WriteFile(filename, bytes.NewReader(rpmManifestJSON))
 		if err != nil {
 			return fmt.Errorf("failed to save file to artifacts directory: %w", err)
 		}
@@ -556,6 +649,71 @@ func writeRPMManifest(ctx context.Context, containerFSPath string) error {
 	return nil
 }
 
+// writeSBOMs writes SPDX and CycloneDX SBOMs of the rpm packages in the image. The
+// SBOMs are named for platform when it is set.
+func writeSBOMs(ctx context.Context, imageRef types.ImageReference, platform string) error {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	artifactWriter := artifacts.WriterFromContext(ctx)
+	if artifactWriter == nil {
+		return nil
+	}
+
+	pkgList, err := rpm.GetPackageList(ctx, imageRef.ImageFSPath)
+	if err != nil {
+		logger.Error(err, "could not get rpm list, continuing without it")
+	}
+
+	digest, err := imageRef.ImageInfo.Digest()
+	if err != nil {
+		return fmt.Errorf("could not get image digest: %w", err)
+	}
+
+	layers, err := imageRef.ImageInfo.Layers()
+	if err != nil {
+		return fmt.Errorf("could not get image layers: %w", err)
+	}
+
+	packageLayers, err := rpm.PackageLayers(ctx, layers)
+	if err != nil {
+		logger.Error(err, "could not determine the layer each package was installed in, continuing without it")
+	}
+
+	distro, err := sbom.ReadDistro(imageRef.ImageFSPath)
+	if err != nil {
+		logger.Error(err, "could not determine the distribution of the image, continuing without it")
+	}
+
+	doc := sbom.SBOM{
+		Image:    imageRef.ImageURI,
+		Digest:   digest.String(),
+		Created:  time.Now(),
+		Packages: sbom.Packages(pkgList, packageLayers, distro),
+	}
+
+	for _, format := range []struct {
+		filename string
+		marshal  func() ([]byte, error)
+	}{
+		{filename: defaults.DefaultSPDXFilename, marshal: doc.SPDX},
+		{filename: defaults.DefaultCycloneDXFilename, marshal: doc.CycloneDX},
+	} {
+		data, err := format.marshal()
+		if err != nil {
+			return fmt.Errorf("could not marshal %s: %w", format.filename, err)
+		}
+
+		fileName, err := artifactWriter.WriteFile(PlatformFilename(format.filename, platform), bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to save file to artifacts directory: %w", err)
+		}
+
+		logger.V(log.TRC).Info("sbom written to disk", "filename", fileName)
+	}
+
+	return nil
+}
+
 func sumLayerSizeBytes(layers []pyxis.Layer) int64 {
 	var sum int64
 	for _, layer := range layers {
@@ -579,6 +737,11 @@ func convertLabels(imageLabels map[string]string) []pyxis.Label {
 	return pyxisLabels
 }
 
+// registryTransport returns the options for connecting to the image's registry.
+func (c *CraneEngine) registryTransport() transport.Options {
+	return transport.Options{Insecure: c.Insecure, CAFile: c.RegistryCAFile}
+}
+
 // retryOnceAfter is a crane option that retries once after t duration.
 func retryOnceAfter(t time.Duration) crane.Option {
 	return func(o *crane.Options) {
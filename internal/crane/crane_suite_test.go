@@ -0,0 +1,13 @@
+package crane
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCrane(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Crane Engine Suite")
+}
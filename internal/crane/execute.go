@@ -0,0 +1,206 @@
+package crane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/opdev/knex/log"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
+)
+
+// unwrapper is implemented by checks that wrap another check, such as checks whose
+// level was overridden by a policy.
+type unwrapper interface {
+	Unwrap() types.Check
+}
+
+// underlying returns the check that ch wraps, if any, so that the optional
+// interfaces below are found on wrapped checks too.
+func underlying(ch types.Check) types.Check {
+	for {
+		u, ok := ch.(unwrapper)
+		if !ok {
+			return ch
+		}
+		ch = u.Unwrap()
+	}
+}
+
+// registryDependent is implemented by checks that need to reach the image's
+// registry, and therefore cannot run against an image loaded from a local source.
+type registryDependent interface {
+	RequiresRegistry() bool
+}
+
+// requiresRegistry returns true if ch needs access to the image's registry.
+func requiresRegistry(ch types.Check) bool {
+	rd, ok := underlying(ch).(registryDependent)
+	return ok && rd.RequiresRegistry()
+}
+
+// notApplicableCheck wraps a check that was not run because it requires the image's
+// registry and the image was loaded from a local source. It is reported as an error,
+// so that the results show that the check did not run.
+type notApplicableCheck struct {
+	types.Check
+}
+
+func (n notApplicableCheck) Help() types.HelpText {
+	help := n.Check.Help()
+	help.Message = fmt.Sprintf("Check %s is not applicable offline because it requires access to the image's registry. Certify the image from its registry to run it.", n.Check.Name())
+	return help
+}
+
+func (n notApplicableCheck) Unwrap() types.Check {
+	return n.Check
+}
+
+// serialCheck is implemented by checks that are not safe to run concurrently
+// with other checks. These checks run one at a time before any concurrent
+// checks are started.
+type serialCheck interface {
+	RequiresSerialExecution() bool
+}
+
+// requiresSerialExecution returns true if ch has opted out of concurrent execution.
+func requiresSerialExecution(ch types.Check) bool {
+	sc, ok := underlying(ch).(serialCheck)
+	return ok && sc.RequiresSerialExecution()
+}
+
+// checkOutcome is the result of executing a single check.
+type checkOutcome struct {
+	// notApplicable is true if the check was not run because it requires the
+	// image's registry.
+	notApplicable bool
+	passed        bool
+	err           error
+	elapsed       time.Duration
+	// timeout is set if the check exceeded its deadline.
+	timeout time.Duration
+	// report is set if the check reports findings.
+	report *findings.Report
+}
+
+// runChecks executes c.Checks, running up to c.Parallelism checks at a time. The
+// returned outcomes are in the same order as c.Checks, regardless of the order
+// in which the checks completed.
+func (c *CraneEngine) runChecks(ctx context.Context, imageRef types.ImageReference, offline bool) []checkOutcome {
+	outcomes := make([]checkOutcome, len(c.Checks))
+
+	workers := c.Parallelism
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Checks that opted out of concurrency run first, on their own.
+	concurrent := make([]int, 0, len(c.Checks))
+	for i, ch := range c.Checks {
+		if workers > 1 && !requiresSerialExecution(ch) {
+			concurrent = append(concurrent, i)
+			continue
+		}
+		outcomes[i] = c.runCheck(ctx, ch, imageRef, offline)
+	}
+
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for _, i := range concurrent {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			outcomes[i] = c.runCheck(ctx, c.Checks[i], imageRef, offline)
+		}(i)
+	}
+	wg.Wait()
+
+	return outcomes
+}
+
+// runCheck validates imageRef against ch and logs the outcome.
+func (c *CraneEngine) runCheck(ctx context.Context, ch types.Check, imageRef types.ImageReference, offline bool) checkOutcome {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	if offline && requiresRegistry(ch) {
+		logger.WithValues("result", "NOT APPLICABLE").Info("check completed", "check", ch.Name())
+		logger.Info(fmt.Sprintf("Check %s is not applicable offline because it requires access to the image's registry.", ch.Name()))
+		return checkOutcome{notApplicable: true}
+	}
+
+	logger.V(log.DBG).Info("running check", "check", ch.Name())
+	if ch.Metadata().Level == "optional" {
+		logger.Info(fmt.Sprintf("Check %s is not currently being enforced.", ch.Name()))
+	}
+
+	// run the validation
+	timeout := c.timeoutFor(ch)
+	checkStartTime := time.Now()
+	checkPassed, err := validateWithTimeout(ctx, ch, imageRef, timeout)
+	checkElapsedTime := time.Since(checkStartTime)
+
+	switch {
+	case errors.Is(err, ErrCheckTimedOut):
+		logger.WithValues("result", "TIMED OUT", "err", err.Error(), "elapsed", checkElapsedTime.String()).Info("check completed", "check", ch.Name())
+		return checkOutcome{err: err, elapsed: checkElapsedTime, timeout: timeout}
+	case err != nil:
+		logger.WithValues("result", "ERROR", "err", err.Error()).Info("check completed", "check", ch.Name())
+	case !checkPassed:
+		logger.WithValues("result", "FAILED").Info("check completed", "check", ch.Name())
+	default:
+		logger.WithValues("result", "PASSED").Info("check completed", "check", ch.Name())
+	}
+
+	outcome := checkOutcome{passed: checkPassed, err: err, elapsed: checkElapsedTime}
+	if err == nil {
+		outcome.report = reportFor(ch)
+	}
+
+	return outcome
+}
+
+// recordOutcomes sorts outcomes, which correspond index for index with c.Checks,
+// into results.
+func (c *CraneEngine) recordOutcomes(outcomes []checkOutcome) types.Results {
+	var results types.Results
+	for i, outcome := range outcomes {
+		result := types.Result{Check: c.Checks[i], ElapsedTime: outcome.elapsed}
+		switch {
+		case outcome.notApplicable:
+			result.Check = notApplicableCheck{Check: c.Checks[i]}
+		case outcome.timeout > 0:
+			result.Check = timedOutCheck{Check: c.Checks[i], timeout: outcome.timeout, elapsed: outcome.elapsed}
+		}
+
+		if _, ok := underlying(c.Checks[i]).(findings.Reporter); ok {
+			// Keep the findings of this validation, which the check replaces when it
+			// is run against another platform. A check that did not complete has none.
+			var report findings.Report
+			if outcome.report != nil {
+				report = *outcome.report
+			}
+			result.Check = findingsCheck{Check: result.Check, report: report}
+		}
+
+		switch {
+		case outcome.notApplicable || outcome.err != nil:
+			results.Errors = appendUnlessOptional(results.Errors, result)
+		case !outcome.passed:
+			results.Failed = appendUnlessOptional(results.Failed, result)
+		default:
+			results.Passed = appendUnlessOptional(results.Passed, result)
+		}
+	}
+
+	return results
+}
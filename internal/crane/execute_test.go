@@ -0,0 +1,139 @@
+package crane
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opdev/knex/types"
+)
+
+// fakeCheck is a types.Check that sleeps for delay, or until its context is done, and
+// then returns passed and err.
+type fakeCheck struct {
+	name   string
+	delay  time.Duration
+	passed bool
+	err    error
+	serial bool
+	// registry is returned by RequiresRegistry.
+	registry bool
+	running  *int32
+	maxSeen  *int32
+	// onValidate, if set, is called when validation starts.
+	onValidate func()
+}
+
+func (f *fakeCheck) Validate(ctx context.Context, _ types.ImageReference) (bool, error) {
+	if f.onValidate != nil {
+		f.onValidate()
+	}
+	if f.running != nil {
+		n := atomic.AddInt32(f.running, 1)
+		defer atomic.AddInt32(f.running, -1)
+		for {
+			seen := atomic.LoadInt32(f.maxSeen)
+			if n <= seen || atomic.CompareAndSwapInt32(f.maxSeen, seen, n) {
+				break
+			}
+		}
+	}
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	return f.passed, f.err
+}
+
+func (f *fakeCheck) RequiresSerialExecution() bool { return f.serial }
+func (f *fakeCheck) RequiresRegistry() bool        { return f.registry }
+func (f *fakeCheck) Name() string                  { return f.name }
+func (f *fakeCheck) Metadata() types.Metadata      { return types.Metadata{Level: "best"} }
+func (f *fakeCheck) Help() types.HelpText          { return types.HelpText{} }
+
+func resultNames(results []types.Result) []string {
+	names := make([]string, 0, len(results))
+	for _, r := range results {
+		names = append(names, r.Name())
+	}
+	return names
+}
+
+var _ = Describe("Check execution", func() {
+	var (
+		engine           CraneEngine
+		running, maxSeen int32
+	)
+
+	BeforeEach(func() {
+		running, maxSeen = 0, 0
+		engine = CraneEngine{
+			Checks: []types.Check{
+				&fakeCheck{name: "slowPass", delay: 60 * time.Millisecond, passed: true, running: &running, maxSeen: &maxSeen},
+				&fakeCheck{name: "fastFail", delay: 5 * time.Millisecond, running: &running, maxSeen: &maxSeen},
+				&fakeCheck{name: "midError", delay: 30 * time.Millisecond, err: errors.New("boom"), running: &running, maxSeen: &maxSeen},
+				&fakeCheck{name: "fastPass", delay: 1 * time.Millisecond, passed: true, running: &running, maxSeen: &maxSeen},
+				&fakeCheck{name: "secondFail", delay: 10 * time.Millisecond, running: &running, maxSeen: &maxSeen},
+			},
+		}
+	})
+
+	Context("When checks run concurrently", func() {
+		It("should report results in declaration order", func() {
+			engine.Parallelism = 5
+			results := engine.recordOutcomes(engine.runChecks(context.TODO(), types.ImageReference{}, false))
+
+			Expect(resultNames(results.Passed)).To(Equal([]string{"slowPass", "fastPass"}))
+			Expect(resultNames(results.Failed)).To(Equal([]string{"fastFail", "secondFail"}))
+			Expect(resultNames(results.Errors)).To(Equal([]string{"midError"}))
+		})
+		It("should not exceed the worker limit", func() {
+			engine.Parallelism = 2
+			engine.runChecks(context.TODO(), types.ImageReference{}, false)
+			Expect(maxSeen).To(BeNumerically("<=", 2))
+		})
+	})
+
+	Context("When parallelism is not set", func() {
+		It("should run checks one at a time", func() {
+			engine.runChecks(context.TODO(), types.ImageReference{}, false)
+			Expect(maxSeen).To(BeNumerically("==", 1))
+		})
+	})
+
+	Context("When the image was loaded from a local source", func() {
+		It("should report checks that require the registry as not applicable", func() {
+			validated := false
+			engine.Checks = []types.Check{
+				&fakeCheck{name: "needsRegistry", registry: true, onValidate: func() { validated = true }},
+				&fakeCheck{name: "local", passed: true},
+			}
+			results := engine.recordOutcomes(engine.runChecks(context.TODO(), types.ImageReference{}, true))
+
+			Expect(validated).To(BeFalse())
+			Expect(resultNames(results.Passed)).To(Equal([]string{"local"}))
+			Expect(resultNames(results.Errors)).To(Equal([]string{"needsRegistry"}))
+			Expect(results.Errors[0].Help().Message).To(ContainSubstring("not applicable offline"))
+		})
+	})
+
+	Context("When a check opts out of concurrency", func() {
+		It("should run that check on its own", func() {
+			othersRunning := int32(-1)
+			serial := &fakeCheck{name: "serial", passed: true, serial: true, onValidate: func() {
+				othersRunning = atomic.LoadInt32(&running)
+			}}
+			engine.Checks = append(engine.Checks, serial)
+			engine.Parallelism = 5
+
+			outcomes := engine.runChecks(context.TODO(), types.ImageReference{}, false)
+			Expect(outcomes).To(HaveLen(len(engine.Checks)))
+			Expect(outcomes[len(outcomes)-1].passed).To(BeTrue())
+			Expect(othersRunning).To(BeNumerically("==", 0))
+		})
+	})
+})
@@ -0,0 +1,69 @@
+package crane
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
+)
+
+// maxFindingsInHelp is the number of findings listed in the help message of a
+// failed check. All of them are included in the results.
+const maxFindingsInHelp = 10
+
+// reportFor returns the findings of ch if it reports them.
+func reportFor(ch types.Check) *findings.Report {
+	reporter, ok := underlying(ch).(findings.Reporter)
+	if !ok {
+		return nil
+	}
+
+	report := reporter.Report()
+	return &report
+}
+
+// findingsCheck wraps a check that reports findings, so that the results keep the
+// findings of the validation they are for, and the help text reported for a check
+// that found problems lists them and how to fix them.
+type findingsCheck struct {
+	types.Check
+	report findings.Report
+}
+
+func (f findingsCheck) Report() findings.Report {
+	return f.report
+}
+
+func (f findingsCheck) Unwrap() types.Check {
+	return f.Check
+}
+
+func (f findingsCheck) Help() types.HelpText {
+	help := f.Check.Help()
+	if len(f.report.Findings) == 0 {
+		return help
+	}
+
+	subjects := make([]string, 0, maxFindingsInHelp)
+	for i, finding := range f.report.Findings {
+		if i == maxFindingsInHelp {
+			subjects = append(subjects, fmt.Sprintf("and %d more", len(f.report.Findings)-maxFindingsInHelp))
+			break
+		}
+		subject := finding.Subject
+		if finding.Layer != "" {
+			subject = fmt.Sprintf("%s (layer %s)", subject, finding.Layer)
+		}
+		subjects = append(subjects, subject)
+	}
+
+	help.Message = fmt.Sprintf("Check %s found %d problem(s) in %s: %s.",
+		f.Check.Name(), len(f.report.Findings), f.report.Inspected, strings.Join(subjects, ", "))
+	if f.report.Remediation != "" {
+		help.Suggestion = f.report.Remediation
+	}
+
+	return help
+}
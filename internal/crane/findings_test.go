@@ -0,0 +1,90 @@
+package crane
+
+import (
+	"context"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
+)
+
+// reportingCheck is a fakeCheck that reports report as its findings.
+type reportingCheck struct {
+	fakeCheck
+	report findings.Report
+}
+
+func (r *reportingCheck) Report() findings.Report { return r.report }
+
+var _ = Describe("Check findings", func() {
+	var (
+		engine  CraneEngine
+		labels  *reportingCheck
+		results types.Results
+	)
+
+	BeforeEach(func() {
+		labels = &reportingCheck{
+			fakeCheck: fakeCheck{name: "labels"},
+			report: findings.Report{
+				Inspected: "image labels",
+				Findings: []findings.Finding{
+					{Kind: findings.KindLabel, Subject: "vendor"},
+					{Kind: findings.KindLabel, Subject: "release"},
+				},
+				Remediation: "Add the vendor and release labels",
+			},
+		}
+		engine = CraneEngine{
+			Checks: []types.Check{
+				labels,
+				&reportingCheck{fakeCheck: fakeCheck{name: "packages", passed: true}, report: findings.Report{Inspected: "rpm packages"}},
+				&fakeCheck{name: "silent", passed: true},
+			},
+		}
+		results = engine.recordOutcomes(engine.runChecks(context.TODO(), types.ImageReference{}, false))
+	})
+
+	It("should record the report of every check that has one in its result", func() {
+		report, ok := findings.ReportOf(results.Failed[0].Check)
+		Expect(ok).To(BeTrue())
+		Expect(report.Findings).To(HaveLen(2))
+
+		Expect(resultNames(results.Passed)).To(Equal([]string{"packages", "silent"}))
+		report, ok = findings.ReportOf(results.Passed[0].Check)
+		Expect(ok).To(BeTrue())
+		Expect(report.Inspected).To(Equal("rpm packages"))
+		_, ok = findings.ReportOf(results.Passed[1].Check)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should keep the findings of the validation the result is for", func() {
+		labels.report = findings.Report{Inspected: "image labels"}
+		labels.passed = true
+		engine.recordOutcomes(engine.runChecks(context.TODO(), types.ImageReference{}, false))
+
+		report, _ := findings.ReportOf(results.Failed[0].Check)
+		Expect(report.Findings).To(HaveLen(2))
+	})
+
+	It("should describe the findings in the help text of failed checks", func() {
+		Expect(results.Failed).To(HaveLen(1))
+		help := results.Failed[0].Help()
+		Expect(help.Message).To(ContainSubstring("vendor, release"))
+		Expect(help.Suggestion).To(Equal("Add the vendor and release labels"))
+	})
+
+	It("should include the findings in the entries of the results artifact", func() {
+		response := newUserResponse(results)
+		Expect(response.Results.Failed).To(HaveLen(1))
+		Expect(response.Results.Failed[0].Findings).To(Equal(labels.report.Findings))
+		Expect(response.Results.Passed[1].Findings).To(BeNil())
+
+		b, err := json.Marshal(response)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(b)).To(ContainSubstring(`"findings":[{"kind":"label","subject":"vendor"}`))
+	})
+})
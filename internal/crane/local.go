@@ -0,0 +1,312 @@
+package crane
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/name"
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"github.com/opdev/knex/log"
+)
+
+// Transports that point at an image on the local filesystem rather than in a
+// registry. The syntax mirrors the transports used by skopeo and podman.
+const (
+	transportOCI           = "oci:"
+	transportOCIArchive    = "oci-archive:"
+	transportDockerArchive = "docker-archive:"
+)
+
+// ociRefNameAnnotation is the annotation an OCI layout uses to name the manifests it contains.
+const ociRefNameAnnotation = "org.opencontainers.image.ref.name"
+
+// localSource is an image reference that points at an OCI layout, an OCI archive,
+// or a docker-archive tarball on disk.
+type localSource struct {
+	transport string
+	path      string
+	// ref optionally selects an image within the source. For OCI layouts this
+	// is a ref name or digest, and for docker archives it is a docker reference.
+	ref string
+}
+
+// parseLocalSource parses image as a local image source. The boolean return is
+// false if image does not use one of the local transports.
+func parseLocalSource(image string) (localSource, bool) {
+	for _, transport := range []string{transportOCI, transportOCIArchive, transportDockerArchive} {
+		if !strings.HasPrefix(image, transport) {
+			continue
+		}
+
+		src := localSource{transport: transport}
+		src.path, src.ref, _ = strings.Cut(strings.TrimPrefix(image, transport), ":")
+		return src, true
+	}
+
+	return localSource{}, false
+}
+
+// image loads the image from the local source. workdir is used to unpack archives,
+// and platform selects an architecture when the source contains an image index.
+// The returned reference is nil when the source does not name the image.
+func (s localSource) image(ctx context.Context, workdir string, platform string) (cranev1.Image, name.Reference, error) {
+	if s.transport == transportDockerArchive {
+		if s.path == "" {
+			return nil, nil, fmt.Errorf("a path is required for %s image references", strings.TrimSuffix(s.transport, ":"))
+		}
+		return s.dockerArchiveImage()
+	}
+
+	layoutPath, err := s.layoutPath(ctx, workdir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ociLayoutImage(layoutPath, s.ref, platform)
+}
+
+// index loads the image index selected from the local source, so that each of its
+// platforms can be certified. The returned index is nil if the source selects a
+// single image, in which case that image is returned instead.
+func (s localSource) index(ctx context.Context, workdir string) (cranev1.ImageIndex, cranev1.Image, name.Reference, error) {
+	if s.transport == transportDockerArchive {
+		// docker archives cannot hold an image index.
+		img, reference, err := s.image(ctx, workdir, "")
+		return nil, img, reference, err
+	}
+
+	layoutPath, err := s.layoutPath(ctx, workdir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	img, idx, reference, err := ociLayoutSelect(layoutPath, s.ref)
+	return idx, img, reference, err
+}
+
+// layoutPath returns the path of the OCI layout for the source, unpacking it into
+// workdir first if the source is an OCI archive.
+func (s localSource) layoutPath(ctx context.Context, workdir string) (string, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	if s.path == "" {
+		return "", fmt.Errorf("a path is required for %s image references", strings.TrimSuffix(s.transport, ":"))
+	}
+
+	if s.transport != transportOCIArchive {
+		return s.path, nil
+	}
+
+	layoutPath := filepath.Join(workdir, "oci-layout")
+	if err := os.Mkdir(layoutPath, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create oci layout directory: %s: %v", layoutPath, err)
+	}
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return "", fmt.Errorf("could not open oci archive: %w", err)
+	}
+	defer f.Close()
+
+	logger.V(log.DBG).Info("unpacking oci archive", "archive", s.path, "path", layoutPath)
+	summary, err := untar(ctx, layoutPath, f)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract oci archive: %v", err)
+	}
+	summary.log(logger)
+
+	return layoutPath, nil
+}
+
+// dockerArchiveImage loads an image from a tarball produced by `docker save` or
+// `podman save --format docker-archive`.
+func (s localSource) dockerArchiveImage() (cranev1.Image, name.Reference, error) {
+	var tag *name.Tag
+	if s.ref != "" {
+		t, err := name.NewTag(s.ref)
+		if err != nil {
+			return nil, nil, fmt.Errorf("docker-archive reference could not be parsed: %v", err)
+		}
+		tag = &t
+	}
+
+	img, err := tarball.ImageFromPath(s.path, tag)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load docker archive: %w", err)
+	}
+
+	if tag != nil {
+		return img, *tag, nil
+	}
+
+	// No reference was given, so the archive contains exactly one image. Use its
+	// first repo tag, if it has one, to describe the image.
+	manifest, err := tarball.LoadManifest(func() (io.ReadCloser, error) { return os.Open(s.path) })
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read docker archive manifest: %w", err)
+	}
+
+	if len(manifest) == 1 && len(manifest[0].RepoTags) > 0 {
+		ref, err := name.ParseReference(manifest[0].RepoTags[0])
+		if err == nil {
+			return img, ref, nil
+		}
+	}
+
+	return img, nil, nil
+}
+
+// ociLayoutImage selects an image from the OCI layout at path. If ref is set, the
+// manifest with a matching ref name annotation or digest is selected. Otherwise the
+// layout must contain a single manifest, or one manifest per platform. Image indexes
+// are resolved using platform.
+func ociLayoutImage(path string, ref string, platform string) (cranev1.Image, name.Reference, error) {
+	img, idx, reference, err := ociLayoutSelect(path, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if idx == nil {
+		return img, reference, nil
+	}
+
+	img, err = imageForPlatform(idx, platform)
+	if err != nil {
+		if ref == "" {
+			return nil, nil, fmt.Errorf("oci layout %s contains more than one image, and none could be selected: %w", path, err)
+		}
+		return nil, nil, err
+	}
+
+	return img, reference, nil
+}
+
+// ociLayoutSelect selects the manifest identified by ref from the OCI layout at path,
+// following the same rules as ociLayoutImage. If the selected manifest is an image
+// index, or the layout holds one image per platform, the index is returned and the
+// image is nil.
+func ociLayoutSelect(path string, ref string) (cranev1.Image, cranev1.ImageIndex, name.Reference, error) {
+	idx, err := layout.ImageIndexFromPath(path)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not read oci layout: %w", err)
+	}
+
+	indexManifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not read oci layout index: %w", err)
+	}
+
+	if ref == "" && len(indexManifest.Manifests) > 1 {
+		// Without a reference, a layout holding several manifests is treated
+		// as a multi-platform image.
+		return nil, idx, nil, nil
+	}
+
+	var desc *cranev1.Descriptor
+	for i, d := range indexManifest.Manifests {
+		if ref == "" || d.Digest.String() == ref || refNameMatches(d.Annotations[ociRefNameAnnotation], ref) {
+			if desc != nil {
+				return nil, nil, nil, fmt.Errorf("oci layout %s contains more than one image matching %q", path, ref)
+			}
+			desc = &indexManifest.Manifests[i]
+		}
+	}
+	if desc == nil {
+		return nil, nil, nil, fmt.Errorf("no image matching %q found in oci layout %s", ref, path)
+	}
+
+	reference := referenceFromRefName(desc.Annotations[ociRefNameAnnotation])
+
+	if desc.MediaType.IsImage() {
+		img, err := idx.Image(desc.Digest)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("could not load image %s from oci layout: %w", desc.Digest, err)
+		}
+		return img, nil, reference, nil
+	}
+
+	if !desc.MediaType.IsIndex() {
+		return nil, nil, nil, fmt.Errorf("unsupported media type in oci layout: %s", desc.MediaType)
+	}
+
+	child, err := idx.ImageIndex(desc.Digest)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not load image index %s from oci layout: %w", desc.Digest, err)
+	}
+
+	return nil, child, reference, nil
+}
+
+// imageForPlatform returns the linux image for platform from idx. platform is a
+// platform name, or an architecture, which selects its first variant in idx.
+func imageForPlatform(idx cranev1.ImageIndex, platform string) (cranev1.Image, error) {
+	indexManifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("could not read image index: %w", err)
+	}
+
+	for _, d := range indexManifest.Manifests {
+		if d.Platform == nil || d.Platform.OS != "linux" || !platformMatches(platformName(d.Platform), platform) {
+			continue
+		}
+		return idx.Image(d.Digest)
+	}
+
+	return nil, fmt.Errorf("no image found for platform linux/%s", platform)
+}
+
+// refNameMatches returns true if the ref name annotation refName identifies ref. Tools
+// disagree on whether the annotation holds a full reference or just the tag, so both
+// forms are accepted.
+func refNameMatches(refName string, ref string) bool {
+	if refName == "" {
+		return false
+	}
+	return refName == ref || strings.HasSuffix(refName, ":"+ref)
+}
+
+// referenceFromRefName parses a ref name annotation into a reference, returning nil
+// if the annotation does not contain a full image reference.
+func referenceFromRefName(refName string) name.Reference {
+	if !strings.Contains(refName, "/") {
+		return nil
+	}
+
+	reference, err := name.ParseReference(refName)
+	if err != nil {
+		return nil
+	}
+	return reference
+}
+
+// localReferenceFields returns the registry, repository and tag or digest that describe
+// an image loaded from src. If the source did not name the image, the repository is
+// derived from the path and the digest is used in place of a tag.
+func localReferenceFields(src localSource, reference name.Reference, img cranev1.Image) (registry, repository, tagOrSha string, err error) {
+	if reference != nil {
+		return reference.Context().RegistryStr(), reference.Context().RepositoryStr(), reference.Identifier(), nil
+	}
+
+	digest, err := img.Digest()
+	if err != nil {
+		return "", "", "", fmt.Errorf("could not get image digest: %w", err)
+	}
+
+	base := filepath.Base(filepath.Clean(src.path))
+	repository = strings.TrimSuffix(base, filepath.Ext(base))
+
+	tagOrSha = digest.String()
+	if src.ref != "" && !strings.HasPrefix(src.ref, "sha256:") {
+		tagOrSha = src.ref
+	}
+
+	return "", repository, tagOrSha, nil
+}
@@ -0,0 +1,121 @@
+package crane
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Local image sources", func() {
+	Context("When parsing image references", func() {
+		It("should recognize an oci layout", func() {
+			src, ok := parseLocalSource("oci:/path/to/layout:v1.0")
+			Expect(ok).To(BeTrue())
+			Expect(src.transport).To(Equal(transportOCI))
+			Expect(src.path).To(Equal("/path/to/layout"))
+			Expect(src.ref).To(Equal("v1.0"))
+		})
+		It("should recognize an oci archive without a reference", func() {
+			src, ok := parseLocalSource("oci-archive:image.tar")
+			Expect(ok).To(BeTrue())
+			Expect(src.transport).To(Equal(transportOCIArchive))
+			Expect(src.path).To(Equal("image.tar"))
+			Expect(src.ref).To(BeEmpty())
+		})
+		It("should keep the full docker reference of a docker archive", func() {
+			src, ok := parseLocalSource("docker-archive:image.tar:quay.io/foo/bar:1.0")
+			Expect(ok).To(BeTrue())
+			Expect(src.transport).To(Equal(transportDockerArchive))
+			Expect(src.path).To(Equal("image.tar"))
+			Expect(src.ref).To(Equal("quay.io/foo/bar:1.0"))
+		})
+		It("should not treat a registry reference as local", func() {
+			_, ok := parseLocalSource("quay.io/foo/bar:1.0")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Context("When loading images", func() {
+		var (
+			img    cranev1.Image
+			tmpdir string
+		)
+
+		BeforeEach(func() {
+			var err error
+			img, err = random.Image(1024, 2)
+			Expect(err).ToNot(HaveOccurred())
+			tmpdir = GinkgoT().TempDir()
+		})
+
+		It("should load an image from an oci layout by ref name", func() {
+			p, err := layout.Write(filepath.Join(tmpdir, "layout"), empty.Index)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.AppendImage(img, layout.WithAnnotations(map[string]string{
+				ociRefNameAnnotation: "quay.io/foo/bar:1.0",
+			}))).To(Succeed())
+
+			src, _ := parseLocalSource("oci:" + filepath.Join(tmpdir, "layout") + ":1.0")
+			loaded, reference, err := src.image(context.TODO(), tmpdir, "amd64")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reference).ToNot(BeNil())
+
+			registry, repository, tag, err := localReferenceFields(src, reference, loaded)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(registry).To(Equal("quay.io"))
+			Expect(repository).To(Equal("foo/bar"))
+			Expect(tag).To(Equal("1.0"))
+
+			want, _ := img.Digest()
+			got, _ := loaded.Digest()
+			Expect(got).To(Equal(want))
+		})
+
+		It("should load an unnamed image from a docker archive", func() {
+			archive := filepath.Join(tmpdir, "image.tar")
+			f, err := os.Create(archive)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tarball.Write(nil, img, f)).To(Succeed())
+			Expect(f.Close()).To(Succeed())
+
+			src, _ := parseLocalSource("docker-archive:" + archive)
+			loaded, reference, err := src.image(context.TODO(), tmpdir, "amd64")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reference).To(BeNil())
+
+			registry, repository, tagOrSha, err := localReferenceFields(src, reference, loaded)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(registry).To(BeEmpty())
+			Expect(repository).To(Equal("image"))
+			Expect(tagOrSha).To(HavePrefix("sha256:"))
+		})
+
+		It("should load a tagged image from a docker archive", func() {
+			archive := filepath.Join(tmpdir, "image.tar")
+			tag, err := name.NewTag("quay.io/foo/bar:2.0")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(tarball.WriteToFile(archive, tag, img)).To(Succeed())
+
+			src, _ := parseLocalSource("docker-archive:" + archive)
+			_, reference, err := src.image(context.TODO(), tmpdir, "amd64")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reference).ToNot(BeNil())
+			Expect(reference.Identifier()).To(Equal("2.0"))
+		})
+
+		It("should fail when the oci layout does not exist", func() {
+			src, _ := parseLocalSource("oci:" + filepath.Join(tmpdir, "missing"))
+			_, _, err := src.image(context.TODO(), tmpdir, "amd64")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
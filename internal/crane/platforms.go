@@ -0,0 +1,162 @@
+package crane
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/opdev/knex/types"
+)
+
+// AllPlatforms may be given in CraneEngine.Platforms to certify every platform
+// in an image index.
+const AllPlatforms = "all"
+
+// PlatformResults holds the results of the checks run against one platform of a
+// multi-architecture image.
+type PlatformResults struct {
+	Platform string
+	Results  types.Results
+}
+
+// platformImage is an image to certify. platform is empty when the engine is
+// certifying a single image rather than the platforms of an index.
+type platformImage struct {
+	platform string
+	image    cranev1.Image
+}
+
+// platformName returns the name of a linux platform: its architecture, followed by
+// its variant if it has one, e.g. arm/v7.
+func platformName(p *cranev1.Platform) string {
+	if p.Variant == "" {
+		return p.Architecture
+	}
+	return p.Architecture + "/" + p.Variant
+}
+
+// platformMatches returns true if the platform named name was requested, either by
+// its name or by its architecture alone, which matches each of its variants.
+func platformMatches(name string, requested string) bool {
+	return name == requested || strings.HasPrefix(name, requested+"/")
+}
+
+// indexPlatforms returns the names of the linux platforms in idx, in the order they
+// appear in the index. Manifests without a linux platform, such as attestations, are
+// skipped.
+func indexPlatforms(idx cranev1.ImageIndex) ([]string, error) {
+	indexManifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("could not read image index: %w", err)
+	}
+
+	platforms := make([]string, 0, len(indexManifest.Manifests))
+	for _, d := range indexManifest.Manifests {
+		if d.Platform == nil || d.Platform.OS != "linux" || containsString(platforms, platformName(d.Platform)) {
+			continue
+		}
+		platforms = append(platforms, platformName(d.Platform))
+	}
+
+	return platforms, nil
+}
+
+// selectPlatforms returns the platforms in available that were requested, in the
+// order of available. It is an error to request a platform the index does not have.
+func selectPlatforms(available []string, requested []string) ([]string, error) {
+	if containsString(requested, AllPlatforms) {
+		return available, nil
+	}
+
+	selected := make([]string, 0, len(requested))
+	matched := make(map[string]bool, len(requested))
+	for _, name := range available {
+		for _, p := range requested {
+			if platformMatches(name, p) {
+				matched[p] = true
+				if !containsString(selected, name) {
+					selected = append(selected, name)
+				}
+			}
+		}
+	}
+
+	for _, p := range requested {
+		if !matched[p] {
+			return nil, fmt.Errorf("platform linux/%s not found in image index, which contains: %s", p, strings.Join(available, ", "))
+		}
+	}
+
+	return selected, nil
+}
+
+// PlatformFilename returns filename with platform inserted before its extension,
+// e.g. cert-image-arm64.json, or cert-image-arm-v7.json for arm/v7. The filename is
+// unchanged when platform is empty.
+func PlatformFilename(filename string, platform string) string {
+	if platform == "" {
+		return filename
+	}
+
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(filename, ext), platformPathName(platform), ext)
+}
+
+// platformPathName returns platform as a single path element, e.g. arm-v7.
+func platformPathName(platform string) string {
+	return strings.ReplaceAll(platform, "/", "-")
+}
+
+// platformCheck wraps a check that was run against one platform of a
+// multi-architecture image, so that the results for each platform can be told
+// apart when they are reported together.
+type platformCheck struct {
+	types.Check
+	platform string
+}
+
+func (p platformCheck) Name() string {
+	return fmt.Sprintf("%s (linux/%s)", p.Check.Name(), p.platform)
+}
+
+func (p platformCheck) Unwrap() types.Check {
+	return p.Check
+}
+
+// combinePlatformResults merges the results for each platform into a single set of
+// results, grouped by platform. The image passes only if every platform passed.
+func combinePlatformResults(image string, platformResults []PlatformResults) types.Results {
+	combined := types.Results{
+		TestedImage:   image,
+		PassedOverall: len(platformResults) > 0,
+	}
+
+	wrap := func(results []types.Result, platform string) []types.Result {
+		wrapped := make([]types.Result, 0, len(results))
+		for _, r := range results {
+			r.Check = platformCheck{Check: r.Check, platform: platform}
+			wrapped = append(wrapped, r)
+		}
+		return wrapped
+	}
+
+	for _, pr := range platformResults {
+		combined.PassedOverall = combined.PassedOverall && pr.Results.PassedOverall
+		combined.Passed = append(combined.Passed, wrap(pr.Results.Passed, pr.Platform)...)
+		combined.Failed = append(combined.Failed, wrap(pr.Results.Failed, pr.Platform)...)
+		combined.Errors = append(combined.Errors, wrap(pr.Results.Errors, pr.Platform)...)
+	}
+
+	return combined
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
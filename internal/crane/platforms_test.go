@@ -0,0 +1,203 @@
+package crane
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opdev/knex/types"
+	"github.com/redhat-openshift-ecosystem/openshift-preflight/artifacts"
+
+	"github.com/opdev/container-certification/internal/defaults"
+)
+
+// imageRecordingCheck is a passing fakeCheck that records the image it validated.
+type imageRecordingCheck struct {
+	fakeCheck
+	validated []types.ImageReference
+}
+
+func (r *imageRecordingCheck) Validate(ctx context.Context, imageRef types.ImageReference) (bool, error) {
+	r.validated = append(r.validated, imageRef)
+	return true, nil
+}
+
+var _ = Describe("Multi-architecture images", func() {
+	Context("When selecting platforms", func() {
+		available := []string{"amd64", "arm64", "ppc64le", "s390x"}
+
+		It("should select every platform for all", func() {
+			Expect(selectPlatforms(available, []string{AllPlatforms})).To(Equal(available))
+		})
+		It("should keep the order of the index", func() {
+			Expect(selectPlatforms(available, []string{"s390x", "amd64"})).To(Equal([]string{"amd64", "s390x"}))
+		})
+		It("should select each variant of an architecture", func() {
+			variants := []string{"amd64", "arm/v6", "arm/v7", "arm64/v8"}
+			Expect(selectPlatforms(variants, []string{"arm"})).To(Equal([]string{"arm/v6", "arm/v7"}))
+			Expect(selectPlatforms(variants, []string{"arm/v7", "arm64"})).To(Equal([]string{"arm/v7", "arm64/v8"}))
+			_, err := selectPlatforms(variants, []string{"arm/v5"})
+			Expect(err).To(MatchError(ContainSubstring("linux/arm/v5")))
+		})
+		It("should reject platforms that are not in the index", func() {
+			_, err := selectPlatforms(available, []string{"riscv64"})
+			Expect(err).To(MatchError(ContainSubstring("linux/riscv64")))
+		})
+	})
+
+	Context("When naming artifacts", func() {
+		It("should include the platform", func() {
+			Expect(PlatformFilename("cert-image.json", "arm64")).To(Equal("cert-image-arm64.json"))
+		})
+		It("should include the variant of the platform", func() {
+			Expect(PlatformFilename("cert-image.json", "arm/v7")).To(Equal("cert-image-arm-v7.json"))
+		})
+		It("should leave single-platform names alone", func() {
+			Expect(PlatformFilename("rpm-manifest.json", "")).To(Equal("rpm-manifest.json"))
+		})
+	})
+
+	Context("When combining results", func() {
+		It("should group results by platform and fail if any platform failed", func() {
+			passing := &fakeCheck{name: "passing", passed: true}
+			failing := &fakeCheck{name: "failing"}
+			combined := combinePlatformResults("image", []PlatformResults{
+				{Platform: "amd64", Results: types.Results{PassedOverall: true, Passed: []types.Result{{Check: passing}, {Check: failing}}}},
+				{Platform: "arm64", Results: types.Results{Passed: []types.Result{{Check: passing}}, Failed: []types.Result{{Check: failing}}}},
+			})
+
+			Expect(combined.PassedOverall).To(BeFalse())
+			Expect(resultNames(combined.Passed)).To(Equal([]string{"passing (linux/amd64)", "failing (linux/amd64)", "passing (linux/arm64)"}))
+			Expect(resultNames(combined.Failed)).To(Equal([]string{"failing (linux/arm64)"}))
+		})
+	})
+
+	Context("When certifying an image index from an oci layout", func() {
+		var layoutPath string
+
+		BeforeEach(func() {
+			layoutPath = filepath.Join(GinkgoT().TempDir(), "layout")
+			p, err := layout.Write(layoutPath, empty.Index)
+			Expect(err).ToNot(HaveOccurred())
+			for _, arch := range []string{"amd64", "arm64", "s390x"} {
+				img, err := random.Image(256, 1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.AppendImage(img, layout.WithPlatform(cranev1.Platform{OS: "linux", Architecture: arch}))).To(Succeed())
+			}
+		})
+
+		It("should run the checks against each selected platform", func() {
+			engine := CraneEngine{
+				Image:     "oci:" + layoutPath,
+				Platforms: []string{"arm64", "amd64"},
+				IsScratch: true,
+				Checks:    []types.Check{&fakeCheck{name: "passing", passed: true}},
+			}
+			Expect(engine.ExecuteChecks(context.TODO())).To(Succeed())
+
+			platformResults := engine.PlatformResults()
+			Expect(platformResults).To(HaveLen(2))
+			Expect(platformResults[0].Platform).To(Equal("amd64"))
+			Expect(platformResults[1].Platform).To(Equal("arm64"))
+
+			results := engine.Results(context.TODO())
+			Expect(results.PassedOverall).To(BeTrue())
+			Expect(resultNames(results.Passed)).To(Equal([]string{"passing (linux/amd64)", "passing (linux/arm64)"}))
+		})
+
+		It("should write the results of each platform as an artifact", func() {
+			artifactsDir := GinkgoT().TempDir()
+			writer, err := artifacts.NewFilesystemWriter(artifacts.WithDirectory(artifactsDir))
+			Expect(err).ToNot(HaveOccurred())
+			engine := CraneEngine{
+				Image:     "oci:" + layoutPath,
+				Platforms: []string{"arm64", "amd64"},
+				IsScratch: true,
+				Checks:    []types.Check{&fakeCheck{name: "passing", passed: true}},
+			}
+			Expect(engine.ExecuteChecks(artifacts.ContextWithWriter(context.TODO(), writer))).To(Succeed())
+
+			for _, platform := range []string{"amd64", "arm64"} {
+				b, err := os.ReadFile(filepath.Join(artifactsDir, PlatformFilename(defaults.DefaultTestResultsFilename, platform)))
+				Expect(err).ToNot(HaveOccurred())
+				var response userResponse
+				Expect(json.Unmarshal(b, &response)).To(Succeed())
+				Expect(response.Passed).To(BeTrue())
+				Expect(response.Results.Passed).To(HaveLen(1))
+				Expect(response.Results.Passed[0].Name).To(Equal("passing"))
+				Expect(filepath.Join(artifactsDir, PlatformFilename(defaults.DefaultCertImageFilename, platform))).To(BeAnExistingFile())
+			}
+		})
+
+		It("should validate the image of each platform", func() {
+			check := &imageRecordingCheck{fakeCheck: fakeCheck{name: "recording"}}
+			engine := CraneEngine{
+				Image:     "oci:" + layoutPath,
+				Platforms: []string{"arm64", "amd64"},
+				IsScratch: true,
+				Checks:    []types.Check{check},
+			}
+			Expect(engine.ExecuteChecks(context.TODO())).To(Succeed())
+
+			Expect(check.validated).To(HaveLen(2))
+			for i, platform := range []string{"amd64", "arm64"} {
+				Expect(filepath.Base(filepath.Dir(check.validated[i].ImageFSPath))).To(Equal(platform))
+			}
+			amd64Digest, err := check.validated[0].ImageInfo.Digest()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(check.validated[1].ImageInfo.Digest()).ToNot(Equal(amd64Digest))
+		})
+	})
+
+	Context("When certifying an image index with variants of an architecture", func() {
+		var (
+			layoutPath string
+			digests    map[string]cranev1.Hash
+		)
+
+		BeforeEach(func() {
+			layoutPath = filepath.Join(GinkgoT().TempDir(), "layout")
+			p, err := layout.Write(layoutPath, empty.Index)
+			Expect(err).ToNot(HaveOccurred())
+			digests = map[string]cranev1.Hash{}
+			for _, variant := range []string{"v6", "v7"} {
+				img, err := random.Image(256, 1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.AppendImage(img, layout.WithPlatform(cranev1.Platform{OS: "linux", Architecture: "arm", Variant: variant}))).To(Succeed())
+				digests["arm/"+variant], err = img.Digest()
+				Expect(err).ToNot(HaveOccurred())
+			}
+		})
+
+		It("should certify and write the artifacts of each variant", func() {
+			artifactsDir := GinkgoT().TempDir()
+			writer, err := artifacts.NewFilesystemWriter(artifacts.WithDirectory(artifactsDir))
+			Expect(err).ToNot(HaveOccurred())
+			check := &imageRecordingCheck{fakeCheck: fakeCheck{name: "recording"}}
+			engine := CraneEngine{
+				Image:     "oci:" + layoutPath,
+				Platforms: []string{AllPlatforms},
+				IsScratch: true,
+				Checks:    []types.Check{check},
+			}
+			Expect(engine.ExecuteChecks(artifacts.ContextWithWriter(context.TODO(), writer))).To(Succeed())
+
+			platformResults := engine.PlatformResults()
+			Expect(platformResults).To(HaveLen(2))
+			Expect(check.validated).To(HaveLen(2))
+			for i, platform := range []string{"arm/v6", "arm/v7"} {
+				Expect(platformResults[i].Platform).To(Equal(platform))
+				Expect(check.validated[i].ImageInfo.Digest()).To(Equal(digests[platform]))
+				Expect(filepath.Join(artifactsDir, PlatformFilename(defaults.DefaultCertImageFilename, platform))).To(BeAnExistingFile())
+				Expect(filepath.Join(artifactsDir, PlatformFilename(defaults.DefaultTestResultsFilename, platform))).To(BeAnExistingFile())
+			}
+		})
+	})
+})
@@ -0,0 +1,109 @@
+package crane
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-logr/logr"
+
+	"github.com/opdev/knex/log"
+	"github.com/opdev/knex/types"
+	"github.com/redhat-openshift-ecosystem/openshift-preflight/artifacts"
+
+	"github.com/opdev/container-certification/internal/findings"
+)
+
+// testLibraryName identifies this library in the results submitted to Pyxis.
+const testLibraryName = "container-certification"
+
+// userResponse is the results artifact submitted to Pyxis as test results. It has
+// the JSON shape of types.UserResponse, whose result types are not exported, with
+// the findings of each check added to its entry.
+type userResponse struct {
+	Image       string        `json:"image"`
+	Passed      bool          `json:"passed"`
+	LibraryInfo testLibrary   `json:"test_library"`
+	Results     resultsByKind `json:"results"`
+}
+
+type testLibrary struct {
+	Name string `json:"name"`
+}
+
+type resultsByKind struct {
+	Passed []checkExecutionInfo `json:"passed"`
+	Failed []checkExecutionInfo `json:"failed"`
+	Errors []checkExecutionInfo `json:"errors"`
+}
+
+type checkExecutionInfo struct {
+	Name             string  `json:"name,omitempty"`
+	ElapsedTime      float64 `json:"elapsed_time"`
+	Description      string  `json:"description,omitempty"`
+	Help             string  `json:"help,omitempty"`
+	Suggestion       string  `json:"suggestion,omitempty"`
+	KnowledgeBaseURL string  `json:"knowledgebase_url,omitempty"`
+	CheckURL         string  `json:"check_url,omitempty"`
+	// Findings is set for checks that report findings.
+	Findings []findings.Finding `json:"findings,omitempty"`
+}
+
+// newUserResponse converts results to the results artifact.
+func newUserResponse(results types.Results) userResponse {
+	convert := func(results []types.Result) []checkExecutionInfo {
+		infos := make([]checkExecutionInfo, 0, len(results))
+		for _, r := range results {
+			meta := r.Metadata()
+			help := r.Help()
+			info := checkExecutionInfo{
+				Name:             r.Name(),
+				ElapsedTime:      float64(r.ElapsedTime.Milliseconds()),
+				Description:      meta.Description,
+				Help:             help.Message,
+				Suggestion:       help.Suggestion,
+				KnowledgeBaseURL: meta.KnowledgeBaseURL,
+				CheckURL:         meta.CheckURL,
+			}
+			if report, ok := findings.ReportOf(r.Check); ok {
+				info.Findings = report.Findings
+			}
+			infos = append(infos, info)
+		}
+		return infos
+	}
+
+	return userResponse{
+		Image:       results.TestedImage,
+		Passed:      results.PassedOverall,
+		LibraryInfo: testLibrary{Name: testLibraryName},
+		Results: resultsByKind{
+			Passed: convert(results.Passed),
+			Failed: convert(results.Failed),
+			Errors: convert(results.Errors),
+		},
+	}
+}
+
+// writeResults writes results to the artifacts directory as filename.
+func writeResults(ctx context.Context, results types.Results, filename string) error {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	// calling MarshalIndent so the json file written to disk is human-readable when opened
+	resultsJSON, err := json.MarshalIndent(newUserResponse(results), "", "    ")
+	if err != nil {
+		return fmt.Errorf("could not marshal results: %w", err)
+	}
+
+	if artifactWriter := artifacts.WriterFromContext(ctx); artifactWriter != nil {
+		fileName, err := artifactWriter.WriteFile(filename, bytes.NewReader(resultsJSON))
+		if err != nil {
+			return fmt.Errorf("failed to save file to artifacts directory: %w", err)
+		}
+
+		logger.V(log.TRC).Info("results written to disk", "filename", fileName)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package crane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opdev/knex/types"
+)
+
+// ErrCheckTimedOut is returned for checks that did not complete before their deadline.
+var ErrCheckTimedOut = errors.New("check timed out")
+
+// ParseCheckTimeouts parses per-check timeout overrides given as "CheckName=duration",
+// e.g. "BasedOnUbi=2m", into a map keyed by check name.
+func ParseCheckTimeouts(overrides []string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration, len(overrides))
+	for _, override := range overrides {
+		checkName, value, found := strings.Cut(override, "=")
+		if !found || checkName == "" {
+			return nil, fmt.Errorf("check timeout %q must be in the form CheckName=duration", override)
+		}
+
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("check timeout for %s could not be parsed: %w", checkName, err)
+		}
+
+		timeouts[checkName] = timeout
+	}
+
+	return timeouts, nil
+}
+
+// timeoutFor returns the deadline for ch. A per-check value in CheckTimeouts takes
+// precedence over CheckTimeout. A zero value means the check has no deadline.
+func (c *CraneEngine) timeoutFor(ch types.Check) time.Duration {
+	if timeout, ok := c.CheckTimeouts[ch.Name()]; ok {
+		return timeout
+	}
+	return c.CheckTimeout
+}
+
+// validateWithTimeout runs ch.Validate with a child context of ctx that expires after
+// timeout, and returns ErrCheckTimedOut if Validate returns after the deadline. Checks
+// keep the state of a validation, such as their findings, on the check itself, so
+// Validate is always waited for rather than abandoned. Checks must return once their
+// context is done.
+func validateWithTimeout(ctx context.Context, ch types.Check, imgRef types.ImageReference, timeout time.Duration) (bool, error) {
+	if timeout <= 0 {
+		return ch.Validate(ctx, imgRef)
+	}
+
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	passed, err := ch.Validate(checkCtx, imgRef)
+	switch {
+	case ctx.Err() != nil:
+		// The whole run was cancelled, not just this check.
+		return false, fmt.Errorf("check cancelled: %w", ctx.Err())
+	case errors.Is(checkCtx.Err(), context.DeadlineExceeded) && err != nil:
+		// A check that gives up because of its deadline usually returns a wrapped
+		// context error.
+		return false, fmt.Errorf("%w after %s: %v", ErrCheckTimedOut, timeout, err)
+	case errors.Is(checkCtx.Err(), context.DeadlineExceeded):
+		return false, fmt.Errorf("%w after %s", ErrCheckTimedOut, timeout)
+	}
+	return passed, err
+}
+
+// timedOutCheck wraps a check that exceeded its deadline, so that the help text
+// reported for it explains that it timed out rather than that it failed.
+type timedOutCheck struct {
+	types.Check
+	timeout time.Duration
+	elapsed time.Duration
+}
+
+func (t timedOutCheck) Unwrap() types.Check {
+	return t.Check
+}
+
+func (t timedOutCheck) Help() types.HelpText {
+	help := t.Check.Help()
+	help.Message = fmt.Sprintf("Check %s timed out after %s (limit %s). Please review the preflight.log file for more information.",
+		t.Check.Name(), t.elapsed.Round(time.Millisecond), t.timeout)
+	return help
+}
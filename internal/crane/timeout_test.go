@@ -0,0 +1,101 @@
+package crane
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
+)
+
+// lateReportingCheck is a types.Check that keeps the state of a validation, as the
+// policy checks do, and records it once its context is done.
+type lateReportingCheck struct {
+	report findings.Report
+}
+
+func (r *lateReportingCheck) Validate(ctx context.Context, _ types.ImageReference) (bool, error) {
+	<-ctx.Done()
+	r.report = findings.Report{Inspected: "the image"}
+	return false, ctx.Err()
+}
+
+func (r *lateReportingCheck) Report() findings.Report  { return r.report }
+func (r *lateReportingCheck) Name() string             { return "lateReporting" }
+func (r *lateReportingCheck) Metadata() types.Metadata { return types.Metadata{Level: "best"} }
+func (r *lateReportingCheck) Help() types.HelpText     { return types.HelpText{} }
+
+var _ = Describe("Check timeouts", func() {
+	Context("When parsing timeout overrides", func() {
+		It("should key timeouts by check name", func() {
+			timeouts, err := ParseCheckTimeouts([]string{"BasedOnUbi=2m", "HasUniqueTag=30s"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timeouts).To(HaveKeyWithValue("BasedOnUbi", 2*time.Minute))
+			Expect(timeouts).To(HaveKeyWithValue("HasUniqueTag", 30*time.Second))
+		})
+		It("should reject malformed overrides", func() {
+			_, err := ParseCheckTimeouts([]string{"BasedOnUbi"})
+			Expect(err).To(HaveOccurred())
+			_, err = ParseCheckTimeouts([]string{"BasedOnUbi=soon"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When a check exceeds its deadline", func() {
+		var engine CraneEngine
+
+		BeforeEach(func() {
+			engine = CraneEngine{
+				Checks: []types.Check{
+					&fakeCheck{name: "hangs", delay: time.Second, passed: true},
+					&fakeCheck{name: "quick", passed: true},
+				},
+				CheckTimeout:  time.Second * 5,
+				CheckTimeouts: map[string]time.Duration{"hangs": 20 * time.Millisecond},
+			}
+		})
+
+		It("should record the check as timed out and run the remaining checks", func() {
+			outcomes := engine.runChecks(context.TODO(), types.ImageReference{}, false)
+			Expect(errors.Is(outcomes[0].err, ErrCheckTimedOut)).To(BeTrue())
+			Expect(outcomes[0].timeout).To(Equal(20 * time.Millisecond))
+			Expect(outcomes[0].elapsed).To(BeNumerically("<", time.Second))
+			Expect(outcomes[1].passed).To(BeTrue())
+
+			results := engine.recordOutcomes(outcomes)
+			Expect(results.Errors).To(HaveLen(1))
+			Expect(results.Errors[0].Name()).To(Equal("hangs"))
+			Expect(results.Errors[0].Help().Message).To(ContainSubstring("timed out"))
+			Expect(resultNames(results.Passed)).To(Equal([]string{"quick"}))
+		})
+
+		It("should wait for the check to return before reporting it", func() {
+			check := &lateReportingCheck{}
+			engine.Checks = []types.Check{check}
+			engine.CheckTimeouts = map[string]time.Duration{"lateReporting": 20 * time.Millisecond}
+
+			outcomes := engine.runChecks(context.TODO(), types.ImageReference{}, false)
+			Expect(errors.Is(outcomes[0].err, ErrCheckTimedOut)).To(BeTrue())
+			Expect(check.Report().Inspected).To(Equal("the image"))
+		})
+
+		It("should use the global timeout for checks without an override", func() {
+			Expect(engine.timeoutFor(engine.Checks[1])).To(Equal(5 * time.Second))
+		})
+	})
+
+	Context("When the run is cancelled", func() {
+		It("should not report a timeout", func() {
+			ctx, cancel := context.WithCancel(context.TODO())
+			cancel()
+			_, err := validateWithTimeout(ctx, &fakeCheck{name: "hangs", delay: time.Second}, types.ImageReference{}, time.Minute)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, ErrCheckTimedOut)).To(BeFalse())
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,366 @@
+package crane
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/opdev/knex/log"
+
+	"github.com/opdev/container-certification/internal/fsroot"
+)
+
+// whiteoutPrefix marks files that delete a path from a lower layer. They are
+// consumed when layers are flattened, and never part of the runtime filesystem.
+const whiteoutPrefix = ".wh."
+
+var (
+	errEscapesRoot   = errors.New("path escapes the extraction root")
+	errLinkNotFound  = errors.New("hardlink target does not exist")
+	errLinkDirectory = errors.New("hardlink target is a directory")
+)
+
+// skippedEntry describes a tar entry that was not extracted.
+type skippedEntry struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// untarSummary records what untar did not extract, so that it can be reported.
+type untarSummary struct {
+	Skipped []skippedEntry `json:"skipped"`
+}
+
+func (s *untarSummary) skip(header *tar.Header, reason string) {
+	s.Skipped = append(s.Skipped, skippedEntry{
+		Name:   header.Name,
+		Type:   entryType(header.Typeflag),
+		Reason: reason,
+	})
+}
+
+// log reports the skipped entries, with a count for each reason so that the
+// summary stays readable for large filesystems.
+func (s untarSummary) log(logger logr.Logger) {
+	if len(s.Skipped) == 0 {
+		return
+	}
+
+	byReason := map[string]int{}
+	for _, e := range s.Skipped {
+		byReason[e.Reason]++
+		logger.V(log.DBG).Info("skipped filesystem entry", "name", e.Name, "type", e.Type, "reason", e.Reason)
+	}
+
+	logger.Info("some filesystem entries were not extracted", "skipped", len(s.Skipped), "reasons", byReason)
+}
+
+// dirMetadata is applied to directories once extraction is complete, because
+// writing entries into a directory changes its mtime, and a read-only mode would
+// prevent those entries from being written at all.
+type dirMetadata struct {
+	path  string
+	mode  os.FileMode
+	mtime time.Time
+}
+
+// untar extracts the tar stream r into dst. Entries are confined to dst: names
+// that climb out of it are rejected, and symlinks in parent directories are
+// resolved as the container runtime would, relative to dst rather than to the
+// host. Absolute and escaping symlink targets are rewritten to stay within dst, so
+// checks that follow them read from the image rather than the host. Hardlinks are
+// recreated, and file modes and mtimes are preserved, except that directories are
+// given owner access. Entries that cannot be
+// represented safely, such as devices and whiteouts, are skipped and recorded in
+// the returned summary.
+func untar(ctx context.Context, dst string, r io.Reader) (untarSummary, error) {
+	var summary untarSummary
+	var dirs []dirMetadata
+	tr := tar.NewReader(r)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		header, err := tr.Next()
+
+		switch {
+		// if no more files are found, finish up the directories and return
+		case err == io.EOF:
+			return summary, applyDirMetadata(dirs)
+
+		// return any other error
+		case err != nil:
+			return summary, err
+
+		// if the header is nil, just skip it (not sure how this happens)
+		case header == nil:
+			continue
+		}
+
+		if header.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+
+		name, err := cleanEntryName(header.Name)
+		if err != nil {
+			summary.skip(header, err.Error())
+			continue
+		}
+		if name == "." {
+			// the root of the filesystem is dst itself.
+			continue
+		}
+
+		if strings.HasPrefix(path.Base(name), whiteoutPrefix) {
+			summary.skip(header, "whiteout files are not part of the runtime filesystem")
+			continue
+		}
+
+		parent, err := resolveInRoot(dst, path.Dir(name))
+		if err != nil {
+			summary.skip(header, err.Error())
+			continue
+		}
+		target := filepath.Join(parent, path.Base(name))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := replaceUnlessDir(target); err != nil {
+				return summary, err
+			}
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return summary, err
+			}
+			dirs = append(dirs, dirMetadata{path: target, mode: header.FileInfo().Mode(), mtime: header.ModTime})
+
+		case tar.TypeReg, tar.TypeRegA: //nolint:staticcheck // TypeRegA is still produced by old tar writers.
+			if err := writeFile(parent, target, header, tr); err != nil {
+				return summary, err
+			}
+
+		case tar.TypeSymlink:
+			if err := prepareTarget(parent, target); err != nil {
+				return summary, err
+			}
+			if err := os.Symlink(inRootLinkTarget(name, header.Linkname), target); err != nil {
+				return summary, fmt.Errorf("could not create symlink %s: %w", header.Name, err)
+			}
+
+		case tar.TypeLink:
+			source, err := hardlinkSource(dst, header.Linkname)
+			if err != nil {
+				summary.skip(header, err.Error())
+				continue
+			}
+			if err := prepareTarget(parent, target); err != nil {
+				return summary, err
+			}
+			if err := os.Link(source, target); err != nil {
+				return summary, fmt.Errorf("could not create hardlink %s: %w", header.Name, err)
+			}
+
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			summary.skip(header, "device and fifo files are not extracted")
+
+		default:
+			summary.skip(header, "unsupported entry type")
+		}
+	}
+}
+
+// cleanEntryName returns the cleaned, root-relative form of a tar entry name, or
+// errEscapesRoot if the name climbs out of the root.
+func cleanEntryName(name string) (string, error) {
+	cleaned := path.Clean(name)
+	if path.IsAbs(cleaned) {
+		// absolute names are relative to the root of the image.
+		return strings.TrimPrefix(cleaned, "/"), nil
+	}
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", errEscapesRoot
+	}
+	if cleaned == "/" {
+		return ".", nil
+	}
+	return cleaned, nil
+}
+
+// resolveInRoot resolves the root-relative directory rel to a path under root,
+// following any symlinks along the way as if root were the filesystem root.
+// Components that do not exist yet are taken as they are.
+func resolveInRoot(root string, rel string) (string, error) {
+	resolved, err := fsroot.Resolve(rel, func(name string) (string, bool, error) {
+		full := filepath.Join(root, filepath.FromSlash(name))
+		fi, err := os.Lstat(full)
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			return "", false, nil
+		}
+		target, err := os.Readlink(full)
+		return target, true, err
+	})
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, filepath.FromSlash(resolved)), nil
+}
+
+// inRootLinkTarget returns the target to use for a symlink at name. Targets that
+// are absolute, or that climb out of the root, are rewritten relative to the link
+// so that they resolve to the same place under the extraction root as they would
+// in a container.
+func inRootLinkTarget(name string, linkTarget string) string {
+	dir := path.Dir(name)
+	joined := path.Join(dir, linkTarget)
+	if !path.IsAbs(linkTarget) && joined != ".." && !strings.HasPrefix(joined, "../") {
+		return linkTarget
+	}
+
+	// path.Join stops at the root when climbing out of it, just like the runtime.
+	absTarget := path.Join("/", dir, linkTarget)
+	if path.IsAbs(linkTarget) {
+		absTarget = path.Clean(linkTarget)
+	}
+
+	rel, err := filepath.Rel(path.Join("/", dir), absTarget)
+	if err != nil {
+		return linkTarget
+	}
+	return rel
+}
+
+// hardlinkSource returns the path under root of the existing file that linkname
+// refers to.
+func hardlinkSource(root string, linkname string) (string, error) {
+	name, err := cleanEntryName(linkname)
+	if err != nil {
+		return "", err
+	}
+
+	parent, err := resolveInRoot(root, path.Dir(name))
+	if err != nil {
+		return "", err
+	}
+	source := filepath.Join(parent, path.Base(name))
+
+	fi, err := os.Lstat(source)
+	if err != nil {
+		return "", errLinkNotFound
+	}
+	if fi.IsDir() {
+		return "", errLinkDirectory
+	}
+
+	return source, nil
+}
+
+// prepareTarget makes sure parent exists and that nothing is left at target, so
+// that a new entry replaces, rather than writes through, whatever was there.
+func prepareTarget(parent string, target string) error {
+	if err := os.MkdirAll(parent, 0o755); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(target); err != nil {
+		return err
+	}
+	return nil
+}
+
+// replaceUnlessDir removes target if it exists and is not a directory.
+func replaceUnlessDir(target string) error {
+	fi, err := os.Lstat(target)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && fi.IsDir()) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(target)
+}
+
+// writeFile writes the regular file described by header to target, then applies
+// its mode and mtime.
+func writeFile(parent string, target string, header *tar.Header, r io.Reader) error {
+	if err := prepareTarget(parent, target); err != nil {
+		return err
+	}
+
+	// O_EXCL makes sure a symlink raced into place is never written through.
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	// copy over contents
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+
+	// manually close here after each file operation; defering would cause each file close
+	// to wait until all operations have completed.
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(target, header.FileInfo().Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+		return err
+	}
+
+	return os.Chtimes(target, header.ModTime, header.ModTime)
+}
+
+// applyDirMetadata sets the mode and mtime of the extracted directories, deepest
+// first, so that updating a directory does not disturb the mtime of its parent.
+// Directories are always readable, writable and searchable by their owner, so that
+// the extracted filesystem can be read by the checks and removed afterwards.
+func applyDirMetadata(dirs []dirMetadata) error {
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return len(dirs[i].path) > len(dirs[j].path)
+	})
+
+	for _, d := range dirs {
+		if err := os.Chmod(d.path, d.mode&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)|0o700); err != nil {
+			return err
+		}
+		if err := os.Chtimes(d.path, d.mtime, d.mtime); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// entryType returns a readable name for a tar type flag.
+func entryType(flag byte) string {
+	switch flag {
+	case tar.TypeReg, tar.TypeRegA: //nolint:staticcheck // TypeRegA is still produced by old tar writers.
+		return "file"
+	case tar.TypeDir:
+		return "directory"
+	case tar.TypeSymlink:
+		return "symlink"
+	case tar.TypeLink:
+		return "hardlink"
+	case tar.TypeChar:
+		return "character device"
+	case tar.TypeBlock:
+		return "block device"
+	case tar.TypeFifo:
+		return "fifo"
+	default:
+		return fmt.Sprintf("type %q", flag)
+	}
+}
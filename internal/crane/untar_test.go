@@ -0,0 +1,154 @@
+package crane
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// tarOf returns a tar stream containing headers. Regular files are given body as
+// their contents.
+func tarOf(body string, headers ...*tar.Header) *bytes.Buffer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, h := range headers {
+		if h.Typeflag == tar.TypeReg {
+			h.Size = int64(len(body))
+		}
+		Expect(tw.WriteHeader(h)).To(Succeed())
+		if h.Typeflag == tar.TypeReg {
+			_, err := tw.Write([]byte(body))
+			Expect(err).ToNot(HaveOccurred())
+		}
+	}
+	Expect(tw.Close()).To(Succeed())
+	return &buf
+}
+
+var _ = Describe("Extracting image filesystems", func() {
+	var (
+		parent string
+		root   string
+	)
+
+	BeforeEach(func() {
+		parent = GinkgoT().TempDir()
+		root = filepath.Join(parent, "root")
+		Expect(os.Mkdir(root, 0o755)).To(Succeed())
+	})
+
+	Context("When an entry climbs out of the root", func() {
+		It("should skip it without writing outside the root", func() {
+			summary, err := untar(context.TODO(), root, tarOf("x",
+				&tar.Header{Name: "../escaped", Typeflag: tar.TypeReg, Mode: 0o644},
+			))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filepath.Join(parent, "escaped")).ToNot(BeAnExistingFile())
+			Expect(summary.Skipped).To(ConsistOf(skippedEntry{Name: "../escaped", Type: "file", Reason: errEscapesRoot.Error()}))
+		})
+	})
+
+	Context("When a parent directory is a symlink", func() {
+		It("should resolve it inside the root", func() {
+			_, err := untar(context.TODO(), root, tarOf("x",
+				&tar.Header{Name: "etc", Typeflag: tar.TypeDir, Mode: 0o755},
+				&tar.Header{Name: "abs", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+				&tar.Header{Name: "rel", Typeflag: tar.TypeSymlink, Linkname: "../../../etc"},
+				&tar.Header{Name: "abs/one", Typeflag: tar.TypeReg, Mode: 0o644},
+				&tar.Header{Name: "rel/two", Typeflag: tar.TypeReg, Mode: 0o644},
+			))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filepath.Join(root, "etc", "one")).To(BeARegularFile())
+			Expect(filepath.Join(root, "etc", "two")).To(BeARegularFile())
+			Expect(filepath.Join(parent, "etc")).ToNot(BeADirectory())
+		})
+		It("should rewrite absolute link targets to stay inside the root", func() {
+			_, err := untar(context.TODO(), root, tarOf("",
+				&tar.Header{Name: "usr/lib64/libfoo.so", Typeflag: tar.TypeSymlink, Linkname: "/lib64/libfoo.so.1"},
+			))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.Readlink(filepath.Join(root, "usr", "lib64", "libfoo.so"))).To(Equal("../../lib64/libfoo.so.1"))
+		})
+	})
+
+	Context("When the stream contains hardlinks", func() {
+		It("should link to the existing file", func() {
+			_, err := untar(context.TODO(), root, tarOf("x",
+				&tar.Header{Name: "bin/one", Typeflag: tar.TypeReg, Mode: 0o755},
+				&tar.Header{Name: "bin/two", Typeflag: tar.TypeLink, Linkname: "bin/one"},
+			))
+			Expect(err).ToNot(HaveOccurred())
+			one, err := os.Stat(filepath.Join(root, "bin", "one"))
+			Expect(err).ToNot(HaveOccurred())
+			two, err := os.Stat(filepath.Join(root, "bin", "two"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.SameFile(one, two)).To(BeTrue())
+		})
+		It("should skip links to files outside the root", func() {
+			Expect(os.WriteFile(filepath.Join(parent, "secret"), []byte("x"), 0o600)).To(Succeed())
+			summary, err := untar(context.TODO(), root, tarOf("",
+				&tar.Header{Name: "stolen", Typeflag: tar.TypeLink, Linkname: "../secret"},
+			))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(filepath.Join(root, "stolen")).ToNot(BeAnExistingFile())
+			Expect(summary.Skipped).To(HaveLen(1))
+		})
+	})
+
+	Context("When extracting files and directories", func() {
+		It("should preserve modes and mtimes, keeping directories writable by their owner", func() {
+			mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+			_, err := untar(context.TODO(), root, tarOf("x",
+				&tar.Header{Name: "opt", Typeflag: tar.TypeDir, Mode: 0o555, ModTime: mtime},
+				&tar.Header{Name: "opt/tool", Typeflag: tar.TypeReg, Mode: 0o4750, ModTime: mtime},
+			))
+			Expect(err).ToNot(HaveOccurred())
+
+			dir, err := os.Stat(filepath.Join(root, "opt"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dir.Mode().Perm()).To(Equal(os.FileMode(0o755)))
+			Expect(dir.ModTime().Equal(mtime)).To(BeTrue())
+
+			file, err := os.Stat(filepath.Join(root, "opt", "tool"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(file.Mode().Perm()).To(Equal(os.FileMode(0o750)))
+			Expect(file.Mode() & os.ModeSetuid).ToNot(BeZero())
+			Expect(file.ModTime().Equal(mtime)).To(BeTrue())
+		})
+		It("should leave inaccessible directories removable", func() {
+			_, err := untar(context.TODO(), root, tarOf("x",
+				&tar.Header{Name: "locked", Typeflag: tar.TypeDir, Mode: 0o000},
+				&tar.Header{Name: "locked/file", Typeflag: tar.TypeReg, Mode: 0o644},
+			))
+			Expect(err).ToNot(HaveOccurred())
+
+			dir, err := os.Stat(filepath.Join(root, "locked"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dir.Mode().Perm()).To(Equal(os.FileMode(0o700)))
+
+			Expect(os.RemoveAll(root)).To(Succeed())
+			Expect(root).ToNot(BeAnExistingFile())
+		})
+	})
+
+	Context("When the stream contains entries that cannot be represented", func() {
+		It("should record them in the summary", func() {
+			summary, err := untar(context.TODO(), root, tarOf("",
+				&tar.Header{Name: "dev/null", Typeflag: tar.TypeChar, Mode: 0o666, Devmajor: 1, Devminor: 3},
+				&tar.Header{Name: "run/pipe", Typeflag: tar.TypeFifo, Mode: 0o644},
+				&tar.Header{Name: "etc/.wh.passwd", Typeflag: tar.TypeReg, Mode: 0o644},
+			))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(summary.Skipped).To(HaveLen(3))
+			Expect(summary.Skipped[0].Type).To(Equal("character device"))
+			Expect(summary.Skipped[1].Type).To(Equal("fifo"))
+			Expect(filepath.Join(root, "etc", ".wh.passwd")).ToNot(BeAnExistingFile())
+		})
+	})
+})
@@ -1,13 +1,25 @@
 package defaults
 
+import "time"
+
 // TODO(Jose): Need to evaluate these defaults to make sure they all need to
 // live in this package, or need to be shared.
 var (
 	DefaultCertImageFilename    = "cert-image.json"
 	DefaultRPMManifestFilename  = "rpm-manifest.json"
 	DefaultTestResultsFilename  = "results.json"
+	DefaultSPDXFilename         = "sbom.spdx.json"
+	DefaultCycloneDXFilename    = "sbom.cdx.json"
 	DefaultArtifactsTarFileName = "artifacts.tar"
+	DefaultSubmissionDirName    = "submission"
+	DefaultSubmissionJournal    = "submission-journal.json"
 	DefaultPyxisHost            = "catalog.redhat.com/api/containers"
 	DefaultPyxisEnv             = "prod"
 	SystemdDir                  = "/etc/systemd/system"
+	DefaultCheckParallelism     = 1
+	DefaultCheckTimeout         = time.Duration(0)
+	DefaultLayerCacheMaxMB      = int64(10 * 1024)
+	DefaultPyxisRetries         = 3
+	DefaultPyxisRetryBackoff    = time.Second
+	DefaultPyxisRetryMaxBackoff = 30 * time.Second
 )
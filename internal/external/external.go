@@ -0,0 +1,220 @@
+// Package external runs checks implemented as executables, so that checks can be
+// written in any language and added without rebuilding this module.
+//
+// An external check is run once per image. It receives a JSON Request on stdin and
+// must print a JSON Verdict on stdout, then exit 0. A non-zero exit status means the
+// check could not be run, and is reported as an error rather than a failure.
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/opdev/knex/log"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
+)
+
+// ProtocolVersion is the version of the Request sent to external checks.
+const ProtocolVersion = 1
+
+// Placeholders that are replaced in the args of an external check.
+const (
+	PlaceholderImage       = "${IMAGE}"
+	PlaceholderImageFSPath = "${IMAGE_FS_PATH}"
+)
+
+// maxStderr is how much of an external check's stderr is included in errors.
+const maxStderr = 4096
+
+var errNoVerdict = errors.New("verdict must set passed")
+
+var (
+	_ types.Check       = &Check{}
+	_ findings.Reporter = &Check{}
+)
+
+// Config describes an external check, as given in a policy file.
+type Config struct {
+	// Command is the executable to run.
+	Command string `yaml:"command"`
+	// Args are passed to Command. PlaceholderImage and PlaceholderImageFSPath
+	// are replaced with the image being checked and its extracted filesystem.
+	Args []string `yaml:"args"`
+	// Description is reported as the check's description.
+	Description string `yaml:"description"`
+	// Suggestion tells users how to fix an image that fails the check.
+	Suggestion string `yaml:"suggestion"`
+	// KnowledgeBaseURL links to documentation about the check.
+	KnowledgeBaseURL string `yaml:"knowledgeBaseURL"`
+}
+
+// Request is written to the stdin of an external check as JSON.
+type Request struct {
+	Version int    `json:"version"`
+	Check   string `json:"check"`
+	Image   string `json:"image"`
+	// ImageFSPath is the directory the image filesystem is extracted to.
+	ImageFSPath string `json:"image_fs_path"`
+	// Config and Manifest are the image's config file and manifest, as they
+	// are stored in the registry.
+	Config   json.RawMessage `json:"config"`
+	Manifest json.RawMessage `json:"manifest"`
+}
+
+// Verdict is read from the stdout of an external check.
+type Verdict struct {
+	// Passed is required.
+	Passed *bool `json:"passed"`
+	findings.Report
+}
+
+// Check runs an external check.
+type Check struct {
+	name   string
+	level  string
+	config Config
+	report findings.Report
+}
+
+// New returns the external check name, enforced at level.
+func New(name, level string, config Config) *Check {
+	return &Check{name: name, level: level, config: config}
+}
+
+func (p *Check) Validate(ctx context.Context, imgRef types.ImageReference) (bool, error) {
+	req, err := p.request(imgRef)
+	if err != nil {
+		return false, fmt.Errorf("could not prepare request for external check: %v", err)
+	}
+
+	verdict, err := p.run(ctx, imgRef, req)
+	if err != nil {
+		return false, err
+	}
+
+	p.report = verdict.Report
+	if p.report.Findings == nil {
+		p.report.Findings = []findings.Finding{}
+	}
+	if p.report.Remediation == "" && len(p.report.Findings) > 0 {
+		p.report.Remediation = p.config.Suggestion
+	}
+
+	return *verdict.Passed, nil
+}
+
+func (p *Check) request(imgRef types.ImageReference) ([]byte, error) {
+	req := Request{
+		Version:     ProtocolVersion,
+		Check:       p.name,
+		Image:       imgRef.ImageURI,
+		ImageFSPath: imgRef.ImageFSPath,
+		Config:      json.RawMessage("null"),
+		Manifest:    json.RawMessage("null"),
+	}
+
+	if imgRef.ImageInfo != nil {
+		config, err := imgRef.ImageInfo.RawConfigFile()
+		if err != nil {
+			return nil, fmt.Errorf("could not get image config: %w", err)
+		}
+		manifest, err := imgRef.ImageInfo.RawManifest()
+		if err != nil {
+			return nil, fmt.Errorf("could not get image manifest: %w", err)
+		}
+		req.Config, req.Manifest = config, manifest
+	}
+
+	return json.Marshal(req)
+}
+
+// run executes the check's command with req on stdin, and parses its verdict.
+func (p *Check) run(ctx context.Context, imgRef types.ImageReference, req []byte) (Verdict, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	placeholders := strings.NewReplacer(
+		PlaceholderImage, imgRef.ImageURI,
+		PlaceholderImageFSPath, imgRef.ImageFSPath,
+	)
+	args := make([]string, 0, len(p.config.Args))
+	for _, arg := range p.config.Args {
+		args = append(args, placeholders.Replace(arg))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.config.Command, args...)
+	cmd.Stdin = bytes.NewReader(req)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "IMAGE="+imgRef.ImageURI, "IMAGE_FS_PATH="+imgRef.ImageFSPath)
+
+	logger.V(log.DBG).Info("running external check", "check", p.name, "command", p.config.Command, "args", args)
+	err := cmd.Run()
+	if stderr.Len() > 0 {
+		logger.V(log.DBG).Info("external check wrote to stderr", "check", p.name, "stderr", stderr.String())
+	}
+	if err != nil {
+		return Verdict{}, fmt.Errorf("external check %s did not complete: %w: %s", p.name, err, tail(stderr.String(), maxStderr))
+	}
+
+	var verdict Verdict
+	if err := json.Unmarshal(stdout.Bytes(), &verdict); err != nil {
+		return Verdict{}, fmt.Errorf("external check %s returned an invalid verdict: %w", p.name, err)
+	}
+	if verdict.Passed == nil {
+		return Verdict{}, fmt.Errorf("external check %s returned an invalid verdict: %w", p.name, errNoVerdict)
+	}
+
+	return verdict, nil
+}
+
+// tail returns at most the last n bytes of s, trimmed of surrounding whitespace.
+func tail(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if len(s) > n {
+		s = s[len(s)-n:]
+	}
+	return s
+}
+
+// Report returns the findings returned by the most recent validation.
+func (p *Check) Report() findings.Report {
+	return p.report
+}
+
+func (p *Check) Name() string {
+	return p.name
+}
+
+func (p *Check) Metadata() types.Metadata {
+	description := p.config.Description
+	if description == "" {
+		description = fmt.Sprintf("Runs the external check %s.", p.config.Command)
+	}
+	return types.Metadata{
+		Description:      description,
+		Level:            p.level,
+		KnowledgeBaseURL: p.config.KnowledgeBaseURL,
+		CheckURL:         p.config.KnowledgeBaseURL,
+	}
+}
+
+func (p *Check) Help() types.HelpText {
+	suggestion := p.config.Suggestion
+	if suggestion == "" {
+		suggestion = fmt.Sprintf("Review the findings reported by the external check %s.", p.name)
+	}
+	return types.HelpText{
+		Message:    fmt.Sprintf("Check %s encountered an error. Please review the preflight.log file for more information.", p.name),
+		Suggestion: suggestion,
+	}
+}
@@ -0,0 +1,97 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
+)
+
+// script writes an executable shell script with body and returns its path.
+func script(body string) string {
+	file := filepath.Join(GinkgoT().TempDir(), "check.sh")
+	Expect(os.WriteFile(file, []byte("#!/bin/sh\n"+body+"\n"), 0o755)).To(Succeed())
+	return file
+}
+
+var _ = Describe("External checks", func() {
+	var imgRef types.ImageReference
+
+	BeforeEach(func() {
+		img, err := random.Image(64, 1)
+		Expect(err).ToNot(HaveOccurred())
+		imgRef = types.ImageReference{
+			ImageURI:    "quay.io/example/image:v1",
+			ImageFSPath: GinkgoT().TempDir(),
+			ImageInfo:   img,
+		}
+	})
+
+	It("should send the image on stdin", func() {
+		out := filepath.Join(GinkgoT().TempDir(), "request.json")
+		check := New("Echo", "best", Config{Command: script(`cat > "$1"; echo '{"passed": true}'`), Args: []string{out}})
+		ok, err := check.Validate(context.TODO(), imgRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+
+		data, err := os.ReadFile(out)
+		Expect(err).ToNot(HaveOccurred())
+		var req Request
+		Expect(json.Unmarshal(data, &req)).To(Succeed())
+		Expect(req.Version).To(Equal(ProtocolVersion))
+		Expect(req.Check).To(Equal("Echo"))
+		Expect(req.ImageFSPath).To(Equal(imgRef.ImageFSPath))
+		config, _ := imgRef.ImageInfo.RawConfigFile()
+		Expect(req.Config).To(MatchJSON(config))
+	})
+
+	It("should replace placeholders in args", func() {
+		check := New("Args", "best", Config{
+			Command: script(`if [ "$1" = "$IMAGE_FS_PATH" ]; then echo '{"passed": true}'; else echo '{"passed": false}'; fi`),
+			Args:    []string{PlaceholderImageFSPath},
+		})
+		ok, err := check.Validate(context.TODO(), imgRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+	})
+
+	It("should report the findings of a failed check", func() {
+		check := New("Telnet", "best", Config{
+			Command:    script(`echo '{"passed": false, "inspected": "installed binaries", "findings": [{"kind": "file", "subject": "usr/bin/telnet", "detail": "telnet is not allowed"}]}'`),
+			Suggestion: "Remove telnet.",
+		})
+		ok, err := check.Validate(context.TODO(), imgRef)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeFalse())
+		Expect(check.Report()).To(Equal(findings.Report{
+			Inspected: "installed binaries",
+			Findings: []findings.Finding{{
+				Kind:    findings.KindFile,
+				Subject: "usr/bin/telnet",
+				Detail:  "telnet is not allowed",
+			}},
+			Remediation: "Remove telnet.",
+		}))
+		Expect(check.Metadata().Level).To(Equal("best"))
+	})
+
+	It("should return an error with stderr if the command fails", func() {
+		check := New("Broken", "best", Config{Command: script(`echo "cannot read rpmdb" >&2; exit 3`)})
+		_, err := check.Validate(context.TODO(), imgRef)
+		Expect(err).To(MatchError(ContainSubstring("cannot read rpmdb")))
+	})
+
+	It("should reject a verdict that does not say whether the image passed", func() {
+		check := New("Vague", "best", Config{Command: script(`echo '{"findings": []}'`)})
+		_, err := check.Validate(context.TODO(), imgRef)
+		Expect(errors.Is(err, errNoVerdict)).To(BeTrue())
+	})
+})
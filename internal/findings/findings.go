@@ -0,0 +1,65 @@
+// Package findings describes the details checks report about why an image passed
+// or failed, so that they can be consumed without parsing the preflight log.
+package findings
+
+import "github.com/opdev/knex/types"
+
+// Kind identifies what a Finding is about.
+type Kind string
+
+const (
+	KindLabel   Kind = "label"
+	KindPackage Kind = "package"
+	KindFile    Kind = "file"
+	KindEnv     Kind = "env"
+	KindUser    Kind = "user"
+)
+
+// Finding is a single problem a check found in an image.
+type Finding struct {
+	Kind Kind `json:"kind"`
+	// Subject is what the finding is about, e.g. a label name, a package NVRA,
+	// or a file path.
+	Subject string `json:"subject"`
+	// Layer is the digest of the layer the finding was made in, if known.
+	Layer string `json:"layer,omitempty"`
+	// Package is the NVRA of the package that owns Subject, if any.
+	Package string `json:"package,omitempty"`
+	// Rule is the ID of the rule that matched, for checks that apply rules.
+	Rule string `json:"rule,omitempty"`
+	// Detail explains what is wrong with Subject.
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is what a check inspected, and what it found, during a validation.
+type Report struct {
+	// Inspected describes what the check looked at, e.g. "image labels".
+	Inspected string `json:"inspected"`
+	// Findings is empty when the check found nothing wrong.
+	Findings []Finding `json:"findings"`
+	// Remediation explains how to address the findings.
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// Reporter is implemented by checks that report structured findings. Report
+// returns the findings of the most recent validation.
+type Reporter interface {
+	Report() Report
+}
+
+// ReportOf returns the report of ch, or of the check it wraps, if it reports
+// findings. Checks that wrap another check return it from an Unwrap method. The
+// checks in results wrap checks that reported findings in one that returns the
+// findings of the validation the result is for.
+func ReportOf(ch types.Check) (Report, bool) {
+	for {
+		if reporter, ok := ch.(Reporter); ok {
+			return reporter.Report(), true
+		}
+		u, ok := ch.(interface{ Unwrap() types.Check })
+		if !ok {
+			return Report{}, false
+		}
+		ch = u.Unwrap()
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"runtime"
 
 	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
 
 	"github.com/opdev/container-certification/internal/defaults"
+	"github.com/opdev/container-certification/internal/pyxis"
 )
 
 const (
@@ -15,7 +17,25 @@ const (
 	KeyPyxisEnv      = "pyxis-env"
 	KeyPyxisHost     = "pyxis-host"
 	KeyPlatform      = "platform"
+	KeyPlatforms     = "platforms"
 	KeyCertProjectID = "certification-project-id"
+
+	KeyCheckParallelism      = "check-parallelism"
+	KeyCheckTimeout          = "check-timeout"
+	KeyCheckTimeoutOverrides = "check-timeout-override"
+	KeyLayerCacheDir         = "layer-cache-dir"
+	KeyLayerCacheMaxMB       = "layer-cache-max-mb"
+	KeyPolicyFiles           = "policy-file"
+	KeyOutput                = "output"
+	KeyInsecure              = "insecure"
+	KeyRegistryCAFile        = "registry-ca-file"
+	KeySubmitSBOM            = "submit-sbom"
+	KeySubmitDryRun          = "submit-dry-run"
+	KeyAdvisoryFiles         = "advisory-file"
+
+	KeyPyxisRetries            = "pyxis-retries"
+	KeyPyxisRetryBackoff       = "pyxis-retry-backoff"
+	KeyPyxisRetryNonIdempotent = "pyxis-retry-non-idempotent"
 )
 
 func BindFlagDockerConfigFilePath(f *pflag.FlagSet) {
@@ -39,11 +59,33 @@ func BindFlagPyxisEnv(f *pflag.FlagSet) {
 
 func BindFlagPyxisHost(f *pflag.FlagSet) {
 	f.String(KeyPyxisHost, "", fmt.Sprintf("Host to use for Pyxis submissions. This will override Pyxis Env. Only set this if you know what you are doing.\n"+
-		"If you do set it, it should include just the host, and the URI path. (env: PFLT_PYXIS_HOST)"))
+		"If you do set it, it should include just the host, and the URI path. The host is reached over HTTPS, unless it\n"+
+		"starts with http://, such as http://localhost:8080 for pyxis-mock serve. (env: PFLT_PYXIS_HOST)"))
+}
+
+func BindFlagsPyxisRetry(f *pflag.FlagSet) {
+	f.Int(KeyPyxisRetries, defaults.DefaultPyxisRetries, "Number of times a failed Pyxis request is retried, when Pyxis is unavailable or rate limits requests.\n"+
+		"Set to 0 to disable retries.")
+	f.Duration(KeyPyxisRetryBackoff, defaults.DefaultPyxisRetryBackoff, "Wait before the first retry of a Pyxis request. It doubles, with jitter, for each following retry.\n"+
+		"A Retry-After sent by Pyxis takes precedence.")
+	f.Bool(KeyPyxisRetryNonIdempotent, false, "Also retry Pyxis requests that create resources. A retried request may create a duplicate\n"+
+		"if Pyxis received the original one.")
+}
+
+// PyxisRetryPolicy returns the retry policy for Pyxis requests configured in cfg
+// by the flags of BindFlagsPyxisRetry.
+func PyxisRetryPolicy(cfg *viper.Viper) pyxis.RetryPolicy {
+	return pyxis.RetryPolicy{
+		MaxRetries:         cfg.GetInt(KeyPyxisRetries),
+		InitialBackoff:     cfg.GetDuration(KeyPyxisRetryBackoff),
+		RetryNonIdempotent: cfg.GetBool(KeyPyxisRetryNonIdempotent),
+	}
 }
 
 func BindFlagsImagePlatform(f *pflag.FlagSet) {
 	f.String(KeyPlatform, runtime.GOARCH, "Architecture of image to pull. Defaults to current platform.")
+	f.StringSlice(KeyPlatforms, nil, "Platforms to certify when the image is a multi-architecture image index, e.g. amd64,arm64,arm/v7.\n"+
+		"An architecture without a variant selects each of its variants. Use \"all\" to certify every platform in the index. Overrides --platform.")
 }
 
 func BindFlagCertificationProjectID(f *pflag.FlagSet) {
@@ -56,3 +98,48 @@ func BindFlagCertificationProjectID(f *pflag.FlagSet) {
 		),
 	)
 }
+
+func BindFlagCheckParallelism(f *pflag.FlagSet) {
+	f.Int(KeyCheckParallelism, defaults.DefaultCheckParallelism, "Maximum number of checks to run concurrently. Checks run sequentially by default.")
+}
+
+func BindFlagCheckTimeouts(f *pflag.FlagSet) {
+	f.Duration(KeyCheckTimeout, defaults.DefaultCheckTimeout, "Maximum time each check may run before it is reported as timed out, e.g. 30m. Checks have no deadline by default.")
+	f.StringSlice(KeyCheckTimeoutOverrides, nil, "Per-check timeout in the form CheckName=duration, e.g. BasedOnUbi=2m.\n"+
+		"May be specified multiple times. Overrides --check-timeout for the named check.")
+}
+
+func BindFlagsLayerCache(f *pflag.FlagSet) {
+	f.String(KeyLayerCacheDir, "", "Directory in which to keep image layers between runs, so layers shared across images\n"+
+		"are only downloaded once. If unset, layers are discarded after each run.")
+	f.Int64(KeyLayerCacheMaxMB, defaults.DefaultLayerCacheMaxMB, "Maximum size of the layer cache in megabytes. The least recently used layers are evicted beyond this size.")
+}
+
+func BindFlagPolicyFiles(f *pflag.FlagSet) {
+	f.StringSlice(KeyPolicyFiles, nil, "Path to a YAML policy file to load in addition to the built-in policies.\n"+
+		"A policy with the same name as a built-in policy replaces it. May be specified multiple times.")
+}
+
+func BindFlagOutput(f *pflag.FlagSet) {
+	f.StringP(KeyOutput, "o", "text", "Format in which to print results: text, json, junit, sarif or markdown.")
+}
+
+func BindFlagsRegistryTLS(f *pflag.FlagSet) {
+	f.Bool(KeyInsecure, false, "Skip TLS verification and allow plain HTTP when connecting to the image's registry.")
+	f.String(KeyRegistryCAFile, "", "Path to a PEM bundle of CAs to trust, in addition to the system CAs, when connecting\n"+
+		"to the image's registry. Use this instead of --insecure for registries with a private CA.")
+}
+
+func BindFlagSubmitSBOM(f *pflag.FlagSet) {
+	f.Bool(KeySubmitSBOM, false, "Attach the SPDX and CycloneDX SBOMs of the image to the submission as artifacts.")
+}
+
+func BindFlagSubmitDryRun(f *pflag.FlagSet) {
+	f.Bool(KeySubmitDryRun, false, "Write the requests that would submit the results to Pyxis to the submission directory of the artifacts,\n"+
+		"instead of sending them. The project is still read from Pyxis. The docker config is redacted.")
+}
+
+func BindFlagAdvisoryFiles(f *pflag.FlagSet) {
+	f.StringSlice(KeyAdvisoryFiles, nil, "Path to a Red Hat OVAL, CSAF or VEX file to check packages against for fixable vulnerabilities,\n"+
+		"optionally compressed with bzip2. May be specified multiple times.")
+}
@@ -0,0 +1,63 @@
+// Package fsroot resolves paths within the root of an image filesystem, following
+// symlinks the way they are followed inside a container.
+package fsroot
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// MaxSymlinks bounds the number of symlinks followed while resolving one path,
+// matching the limit used by Linux.
+const MaxSymlinks = 40
+
+// ErrTooManyLinks is returned when resolving a path follows more than MaxSymlinks
+// symlinks, which is usually a loop.
+var ErrTooManyLinks = errors.New("too many levels of symbolic links")
+
+// Readlink returns the target of the symlink at the absolute path name, or false if
+// name is not a symlink or does not exist.
+type Readlink func(name string) (target string, isLink bool, err error)
+
+// Resolve returns the absolute, cleaned form of the slash-separated path p with
+// every symlink in it resolved by readlink, as if the root were the filesystem
+// root. Absolute link targets start again from the root, and .. stops at the root.
+// Components that do not exist are taken as they are.
+func Resolve(p string, readlink Readlink) (string, error) {
+	resolved := "/"
+	remaining := p
+
+	for links := 0; remaining != ""; {
+		var part string
+		part, remaining, _ = strings.Cut(remaining, "/")
+		if part == "" || part == "." {
+			continue
+		}
+
+		next := path.Join(resolved, part)
+		if part == ".." {
+			resolved = next
+			continue
+		}
+
+		target, isLink, err := readlink(next)
+		if err != nil {
+			return "", err
+		}
+		if !isLink {
+			resolved = next
+			continue
+		}
+
+		if links++; links > MaxSymlinks {
+			return "", ErrTooManyLinks
+		}
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		remaining = target + "/" + remaining
+	}
+
+	return resolved, nil
+}
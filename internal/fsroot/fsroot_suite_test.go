@@ -0,0 +1,13 @@
+package fsroot
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFsroot(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Fsroot Suite")
+}
@@ -0,0 +1,49 @@
+package fsroot
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resolve", func() {
+	links := map[string]string{
+		"/var/run":         "../run",
+		"/opt/app":         "/srv/app",
+		"/srv/app/data":    "../../../../data",
+		"/loop/a":          "b",
+		"/loop/b":          "a",
+		"/broken/readlink": "",
+	}
+	readlink := func(name string) (string, bool, error) {
+		if name == "/broken/readlink" {
+			return "", false, errors.New("readlink failed")
+		}
+		target, ok := links[name]
+		return target, ok, nil
+	}
+
+	DescribeTable("should resolve symlinks within the root",
+		func(p string, want string) {
+			Expect(Resolve(p, readlink)).To(Equal(want))
+		},
+		Entry("a path without symlinks", "usr/bin/", "/usr/bin"),
+		Entry("the root", "", "/"),
+		Entry("a relative symlink", "/var/run/app", "/run/app"),
+		Entry("an absolute symlink", "/opt/app/bin", "/srv/app/bin"),
+		Entry("a symlink that climbs out of the root", "/opt/app/data/x", "/data/x"),
+		Entry("a path that climbs out of the root", "/../../etc", "/etc"),
+		Entry("a .. after a symlink", "/opt/app/..", "/srv"),
+	)
+
+	It("should stop following a symlink loop", func() {
+		_, err := Resolve("/loop/a/x", readlink)
+		Expect(err).To(MatchError(ErrTooManyLinks))
+	})
+
+	It("should return the error of readlink", func() {
+		_, err := Resolve("/broken/readlink/x", readlink)
+		Expect(err).To(MatchError("readlink failed"))
+	})
+})
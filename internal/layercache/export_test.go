@@ -0,0 +1,9 @@
+package layercache
+
+import cranev1 "github.com/google/go-containerregistry/pkg/v1"
+
+// MetaPath exposes metaPath to the tests, which are in package layercache_test so
+// that the Entry type does not clash with the Entry of ginkgo.
+func (c *Cache) MetaPath(h cranev1.Hash) string {
+	return c.metaPath(h)
+}
@@ -0,0 +1,557 @@
+// Package layercache provides a persistent, content-addressed cache of image
+// layers that can be shared across runs and processes.
+package layercache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/cache"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+var _ cache.Cache = &Cache{}
+
+const (
+	blobsDir   = "blobs"
+	diffIDsDir = "diffids"
+	metaSuffix = ".json"
+	tmpPrefix  = ".tmp-"
+
+	// staleTempAge is how old a temporary file must be before Prune assumes the
+	// process writing it has gone away.
+	staleTempAge = 24 * time.Hour
+)
+
+// Cache is a layer cache rooted at a directory on disk. Compressed layer blobs are
+// stored by digest, and a small metadata file next to each blob records its diff ID,
+// size, media type, and when it was last used.
+//
+// All files are written to a temporary name and renamed into place once complete and
+// verified, so several processes can share a cache directory without seeing partial
+// blobs. When the cache grows beyond its size cap, the least recently used layers are
+// evicted.
+//
+// Layers returned by Get keep their blob open until the Cache is closed, so they
+// stay readable if another process evicts them, and the layers stored or retrieved
+// through a Cache are never evicted by its own size cap.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu   sync.Mutex
+	open map[cranev1.Hash]*os.File
+	used map[cranev1.Hash]bool
+}
+
+// Entry describes a layer stored in the cache.
+type Entry struct {
+	Digest    cranev1.Hash    `json:"digest"`
+	DiffID    cranev1.Hash    `json:"diff_id"`
+	Size      int64           `json:"size"`
+	MediaType types.MediaType `json:"media_type"`
+	// LastUsed is when the layer was last stored or retrieved. It is tracked
+	// using the modification time of the metadata file.
+	LastUsed time.Time `json:"-"`
+}
+
+// New returns a Cache rooted at dir, creating it if necessary. If maxBytes is greater
+// than zero, least recently used layers are evicted when the cache exceeds it.
+func New(dir string, maxBytes int64) (*Cache, error) {
+	for _, d := range []string{filepath.Join(dir, blobsDir), filepath.Join(dir, diffIDsDir)} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return nil, fmt.Errorf("could not create layer cache directory: %s: %w", d, err)
+		}
+	}
+
+	return &Cache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		open:     map[cranev1.Hash]*os.File{},
+		used:     map[cranev1.Hash]bool{},
+	}, nil
+}
+
+// Close releases the blobs held open by layers returned from Get. Those layers
+// cannot be read once the Cache is closed.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for digest, f := range c.open {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(c.open, digest)
+	}
+	return errors.Join(errs...)
+}
+
+// Dir returns the root directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+func (c *Cache) blobPath(h cranev1.Hash) string {
+	return filepath.Join(c.dir, blobsDir, h.Algorithm+"-"+h.Hex)
+}
+
+func (c *Cache) metaPath(h cranev1.Hash) string {
+	return c.blobPath(h) + metaSuffix
+}
+
+func (c *Cache) diffIDPath(h cranev1.Hash) string {
+	return filepath.Join(c.dir, diffIDsDir, h.Algorithm+"-"+h.Hex)
+}
+
+// Put returns a layer that stores its compressed contents in the cache the first
+// time they are read in full.
+func (c *Cache) Put(l cranev1.Layer) (cranev1.Layer, error) {
+	digest, err := l.Digest()
+	if err != nil {
+		return nil, err
+	}
+	diffID, err := l.DiffID()
+	if err != nil {
+		return nil, err
+	}
+
+	return &cachingLayer{Layer: l, cache: c, digest: digest, diffID: diffID}, nil
+}
+
+// Get returns the cached layer with digest or diff ID h, or cache.ErrNotFound.
+func (c *Cache) Get(h cranev1.Hash) (cranev1.Layer, error) {
+	entry, err := c.entry(h)
+	if errors.Is(err, os.ErrNotExist) {
+		// h may be a diff ID rather than a digest.
+		digest, lerr := c.digestForDiffID(h)
+		if lerr != nil {
+			return nil, cache.ErrNotFound
+		}
+		entry, err = c.entry(digest)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, cache.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := c.openBlob(entry.Digest)
+	if err != nil {
+		return nil, cache.ErrNotFound
+	}
+
+	// Record the use for LRU eviction. Failing to do so is harmless.
+	now := time.Now()
+	_ = os.Chtimes(c.metaPath(entry.Digest), now, now)
+
+	return partial.CompressedToLayer(&cachedLayer{file: f, entry: entry})
+}
+
+// openBlob opens the blob with digest h, or returns the file opened by an earlier
+// call, and marks the layer as used so that commit does not evict it.
+func (c *Cache) openBlob(h cranev1.Hash) (*os.File, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if f, ok := c.open[h]; ok {
+		return f, nil
+	}
+	f, err := os.Open(c.blobPath(h))
+	if err != nil {
+		return nil, err
+	}
+	c.open[h] = f
+	c.used[h] = true
+	return f, nil
+}
+
+// inUse reports whether the layer with digest h was stored or retrieved through c.
+func (c *Cache) inUse(h cranev1.Hash) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.used[h]
+}
+
+// Delete removes the layer with digest or diff ID h from the cache.
+func (c *Cache) Delete(h cranev1.Hash) error {
+	entry, err := c.entry(h)
+	if errors.Is(err, os.ErrNotExist) {
+		digest, lerr := c.digestForDiffID(h)
+		if lerr != nil {
+			return cache.ErrNotFound
+		}
+		entry, err = c.entry(digest)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return cache.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.remove(entry)
+}
+
+// Entries returns all layers in the cache, least recently used first.
+func (c *Cache) Entries() ([]Entry, error) {
+	files, err := os.ReadDir(filepath.Join(c.dir, blobsDir))
+	if err != nil {
+		return nil, fmt.Errorf("could not read layer cache: %w", err)
+	}
+
+	entries := make([]Entry, 0, len(files)/2)
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), metaSuffix) || strings.HasPrefix(f.Name(), tmpPrefix) {
+			continue
+		}
+
+		algorithm, encoded, found := strings.Cut(strings.TrimSuffix(f.Name(), metaSuffix), "-")
+		if !found {
+			continue
+		}
+
+		entry, err := c.entry(cranev1.Hash{Algorithm: algorithm, Hex: encoded})
+		if err != nil {
+			// Another process may have evicted this entry since the directory was read.
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.Before(entries[j].LastUsed)
+	})
+
+	return entries, nil
+}
+
+// Size returns the total size in bytes of all layers in the cache.
+func (c *Cache) Size() (int64, error) {
+	entries, err := c.Entries()
+	if err != nil {
+		return 0, err
+	}
+	return totalSize(entries), nil
+}
+
+// Prune evicts the least recently used layers until the cache is no larger than
+// maxBytes, and returns the evicted entries. A maxBytes of zero empties the cache.
+func (c *Cache) Prune(maxBytes int64) ([]Entry, error) {
+	return c.prune(maxBytes, func(cranev1.Hash) bool { return false })
+}
+
+// prune evicts the least recently used layers for which keep returns false until
+// the cache is no larger than maxBytes.
+func (c *Cache) prune(maxBytes int64, keep func(cranev1.Hash) bool) ([]Entry, error) {
+	c.removeStaleTemps()
+
+	entries, err := c.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	size := totalSize(entries)
+	removed := []Entry{}
+	for _, entry := range entries {
+		if size <= maxBytes {
+			break
+		}
+		if keep(entry.Digest) {
+			continue
+		}
+		if err := c.remove(entry); err != nil {
+			return removed, err
+		}
+		size -= entry.Size
+		removed = append(removed, entry)
+	}
+
+	return removed, nil
+}
+
+// removeStaleTemps removes temporary files left behind by processes that exited
+// before they finished writing to the cache.
+func (c *Cache) removeStaleTemps() {
+	for _, dir := range []string{filepath.Join(c.dir, blobsDir), filepath.Join(c.dir, diffIDsDir)} {
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			if !strings.HasPrefix(f.Name(), tmpPrefix) {
+				continue
+			}
+			info, err := f.Info()
+			if err != nil || time.Since(info.ModTime()) < staleTempAge {
+				continue
+			}
+			_ = os.Remove(filepath.Join(dir, f.Name()))
+		}
+	}
+}
+
+// entry reads the metadata for the blob with digest h.
+func (c *Cache) entry(h cranev1.Hash) (Entry, error) {
+	path := c.metaPath(h)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return Entry{}, fmt.Errorf("layer cache metadata is malformed: %s: %w", path, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry.LastUsed = info.ModTime()
+
+	return entry, nil
+}
+
+func (c *Cache) digestForDiffID(diffID cranev1.Hash) (cranev1.Hash, error) {
+	b, err := os.ReadFile(c.diffIDPath(diffID))
+	if err != nil {
+		return cranev1.Hash{}, err
+	}
+	return cranev1.NewHash(strings.TrimSpace(string(b)))
+}
+
+// remove deletes entry. The metadata goes first so that other processes stop
+// finding the layer before its blob disappears. Files that are already gone were
+// removed by another process, which is not an error.
+func (c *Cache) remove(entry Entry) error {
+	for _, path := range []string{c.metaPath(entry.Digest), c.blobPath(entry.Digest), c.diffIDPath(entry.DiffID)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("could not remove cached layer %s: %w", entry.Digest, err)
+		}
+	}
+	return nil
+}
+
+// commit moves a completely written and verified blob at tmpPath into the cache,
+// records its metadata, and evicts old layers if the cache is over its cap. Layers
+// used through c are kept even if that leaves the cache over its cap.
+func (c *Cache) commit(tmpPath string, entry Entry) error {
+	c.mu.Lock()
+	c.used[entry.Digest] = true
+	c.mu.Unlock()
+
+	if err := os.Rename(tmpPath, c.blobPath(entry.Digest)); err != nil {
+		return fmt.Errorf("could not store layer %s: %w", entry.Digest, err)
+	}
+
+	if err := writeFileAtomic(c.diffIDPath(entry.DiffID), []byte(entry.Digest.String())); err != nil {
+		return err
+	}
+
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("could not marshal layer cache metadata: %w", err)
+	}
+	if err := writeFileAtomic(c.metaPath(entry.Digest), meta); err != nil {
+		return err
+	}
+
+	if c.maxBytes > 0 {
+		if _, err := c.prune(c.maxBytes, c.inUse); err != nil {
+			return fmt.Errorf("could not evict layers from cache: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes b to a temporary file next to path, and renames it into place.
+func writeFileAtomic(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), tmpPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("could not create temporary file in layer cache: %w", err)
+	}
+	defer os.Remove(f.Name()) // no-op once renamed
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+func totalSize(entries []Entry) int64 {
+	var sum int64
+	for _, entry := range entries {
+		sum += entry.Size
+	}
+	return sum
+}
+
+// cachingLayer wraps a layer so that its compressed contents are written to the
+// cache as they are read.
+type cachingLayer struct {
+	cranev1.Layer
+	cache          *Cache
+	digest, diffID cranev1.Hash
+}
+
+func (l *cachingLayer) Digest() (cranev1.Hash, error) {
+	return l.digest, nil
+}
+
+func (l *cachingLayer) DiffID() (cranev1.Hash, error) {
+	return l.diffID, nil
+}
+
+// Compressed returns the compressed layer contents, storing them in the cache once
+// the returned reader has been read to the end and closed.
+func (l *cachingLayer) Compressed() (io.ReadCloser, error) {
+	rc, err := l.Layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+
+	if l.digest.Algorithm != "sha256" {
+		// We can only verify what we write.
+		return rc, nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Join(l.cache.dir, blobsDir), tmpPrefix+"*")
+	if err != nil {
+		rc.Close()
+		return nil, fmt.Errorf("could not create temporary file in layer cache: %w", err)
+	}
+
+	return &teeReadCloser{rc: rc, tmp: tmp, hash: sha256.New(), layer: l}, nil
+}
+
+// Uncompressed returns the uncompressed layer contents. The compressed layer is
+// stored in the cache first, so that subsequent reads are served from disk.
+func (l *cachingLayer) Uncompressed() (io.ReadCloser, error) {
+	if cached, err := l.cache.Get(l.digest); err == nil {
+		return cached.Uncompressed()
+	}
+
+	rc, err := l.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(io.Discard, rc)
+	if cerr := rc.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cached, err := l.cache.Get(l.digest)
+	if err != nil {
+		// The layer could not be cached, e.g. because its digest did not
+		// match its contents. Fall back to the source layer.
+		return l.Layer.Uncompressed()
+	}
+	return cached.Uncompressed()
+}
+
+// teeReadCloser copies everything read from rc into tmp, and commits tmp to the
+// cache on Close if rc was read to EOF and its contents match the layer digest.
+type teeReadCloser struct {
+	rc    io.ReadCloser
+	tmp   *os.File
+	hash  hash.Hash
+	size  int64
+	eof   bool
+	werr  error
+	layer *cachingLayer
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.rc.Read(p)
+	if n > 0 && t.werr == nil {
+		if _, werr := t.tmp.Write(p[:n]); werr != nil {
+			// A failure to cache should not fail the read.
+			t.werr = werr
+		}
+		t.hash.Write(p[:n])
+		t.size += int64(n)
+	}
+	if errors.Is(err, io.EOF) {
+		t.eof = true
+	}
+	return n, err
+}
+
+func (t *teeReadCloser) Close() error {
+	err := t.rc.Close()
+	cerr := t.tmp.Close()
+
+	complete := t.eof && t.werr == nil && cerr == nil &&
+		hex.EncodeToString(t.hash.Sum(nil)) == t.layer.digest.Hex
+	if !complete {
+		os.Remove(t.tmp.Name())
+		return err
+	}
+
+	mediaType, merr := t.layer.MediaType()
+	if merr != nil {
+		mediaType = types.DockerLayer
+	}
+
+	if commitErr := t.layer.cache.commit(t.tmp.Name(), Entry{
+		Digest:    t.layer.digest,
+		DiffID:    t.layer.diffID,
+		Size:      t.size,
+		MediaType: mediaType,
+	}); commitErr != nil {
+		os.Remove(t.tmp.Name())
+	}
+
+	return err
+}
+
+// cachedLayer is a partial.CompressedLayer backed by a blob in the cache.
+type cachedLayer struct {
+	file  *os.File
+	entry Entry
+}
+
+func (l *cachedLayer) Digest() (cranev1.Hash, error) {
+	return l.entry.Digest, nil
+}
+
+func (l *cachedLayer) DiffID() (cranev1.Hash, error) {
+	return l.entry.DiffID, nil
+}
+
+func (l *cachedLayer) Compressed() (io.ReadCloser, error) {
+	return io.NopCloser(io.NewSectionReader(l.file, 0, l.entry.Size)), nil
+}
+
+func (l *cachedLayer) Size() (int64, error) {
+	return l.entry.Size, nil
+}
+
+func (l *cachedLayer) MediaType() (types.MediaType, error) {
+	return l.entry.MediaType, nil
+}
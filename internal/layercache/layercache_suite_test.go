@@ -0,0 +1,13 @@
+package layercache_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestLayerCache(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Layer Cache Suite")
+}
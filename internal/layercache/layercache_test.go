@@ -0,0 +1,164 @@
+package layercache_test
+
+import (
+	"io"
+	"os"
+	"time"
+
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/cache"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/opdev/container-certification/internal/layercache"
+)
+
+// store puts l in c and reads it in full so that it is committed.
+func store(c *layercache.Cache, l cranev1.Layer) {
+	cl, err := c.Put(l)
+	Expect(err).ToNot(HaveOccurred())
+	rc, err := cl.Compressed()
+	Expect(err).ToNot(HaveOccurred())
+	_, err = io.Copy(io.Discard, rc)
+	Expect(err).ToNot(HaveOccurred())
+	Expect(rc.Close()).To(Succeed())
+}
+
+var _ = Describe("Layer cache", func() {
+	var (
+		c     *layercache.Cache
+		layer cranev1.Layer
+	)
+
+	BeforeEach(func() {
+		var err error
+		c, err = layercache.New(GinkgoT().TempDir(), 0)
+		Expect(err).ToNot(HaveOccurred())
+		layer, err = random.Layer(1024, "application/vnd.oci.image.layer.v1.tar+gzip")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	Context("When a layer has been read in full", func() {
+		BeforeEach(func() {
+			store(c, layer)
+		})
+		It("should be retrievable by digest", func() {
+			digest, _ := layer.Digest()
+			cached, err := c.Get(digest)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cached.Digest()).To(Equal(digest))
+		})
+		It("should be retrievable by diff ID", func() {
+			diffID, _ := layer.DiffID()
+			cached, err := c.Get(diffID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cached.DiffID()).To(Equal(diffID))
+		})
+		It("should be listed with its size", func() {
+			size, _ := layer.Size()
+			entries, err := c.Entries()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Size).To(Equal(size))
+			Expect(c.Size()).To(Equal(size))
+		})
+		It("should be removed by Delete", func() {
+			digest, _ := layer.Digest()
+			Expect(c.Delete(digest)).To(Succeed())
+			_, err := c.Get(digest)
+			Expect(err).To(MatchError(cache.ErrNotFound))
+		})
+	})
+
+	Context("When a layer is only partially read", func() {
+		It("should not be cached", func() {
+			cl, err := c.Put(layer)
+			Expect(err).ToNot(HaveOccurred())
+			rc, err := cl.Compressed()
+			Expect(err).ToNot(HaveOccurred())
+			_, err = rc.Read(make([]byte, 16))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(rc.Close()).To(Succeed())
+
+			digest, _ := layer.Digest()
+			_, err = c.Get(digest)
+			Expect(err).To(MatchError(cache.ErrNotFound))
+		})
+	})
+
+	Context("When the cache is pruned", func() {
+		It("should evict the least recently used layers first", func() {
+			other, err := random.Layer(1024, "application/vnd.oci.image.layer.v1.tar+gzip")
+			Expect(err).ToNot(HaveOccurred())
+			store(c, layer)
+			store(c, other)
+
+			// Make the first layer the oldest.
+			digest, _ := layer.Digest()
+			old := time.Now().Add(-time.Hour)
+			Expect(os.Chtimes(c.MetaPath(digest), old, old)).To(Succeed())
+
+			otherSize, _ := other.Size()
+			evicted, err := c.Prune(otherSize)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(evicted).To(HaveLen(1))
+			Expect(evicted[0].Digest).To(Equal(digest))
+
+			otherDigest, _ := other.Digest()
+			_, err = c.Get(otherDigest)
+			Expect(err).ToNot(HaveOccurred())
+		})
+		It("should keep serving layers that were retrieved before they were evicted", func() {
+			store(c, layer)
+			digest, _ := layer.Digest()
+			cached, err := c.Get(digest)
+			Expect(err).ToNot(HaveOccurred())
+
+			// Another process empties the cache.
+			other, err := layercache.New(c.Dir(), 0)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(other.Prune(0)).To(HaveLen(1))
+
+			rc, err := cached.Compressed()
+			Expect(err).ToNot(HaveOccurred())
+			defer rc.Close()
+			got, err := io.ReadAll(rc)
+			Expect(err).ToNot(HaveOccurred())
+			want, err := layer.Compressed()
+			Expect(err).ToNot(HaveOccurred())
+			defer want.Close()
+			Expect(io.ReadAll(want)).To(Equal(got))
+		})
+		It("should not evict layers used by the cache that went over its cap", func() {
+			unused, err := random.Layer(1024, "application/vnd.oci.image.layer.v1.tar+gzip")
+			Expect(err).ToNot(HaveOccurred())
+			other, err := random.Layer(1024, "application/vnd.oci.image.layer.v1.tar+gzip")
+			Expect(err).ToNot(HaveOccurred())
+			store(c, unused)
+			store(c, layer)
+
+			// Make the layer in use the oldest.
+			digest, _ := layer.Digest()
+			old := time.Now().Add(-time.Hour)
+			Expect(os.Chtimes(c.MetaPath(digest), old, old)).To(Succeed())
+
+			otherSize, _ := other.Size()
+			capped, err := layercache.New(c.Dir(), otherSize)
+			Expect(err).ToNot(HaveOccurred())
+			defer capped.Close()
+			_, err = capped.Get(digest)
+			Expect(err).ToNot(HaveOccurred())
+			store(capped, other)
+
+			entries, err := capped.Entries()
+			Expect(err).ToNot(HaveOccurred())
+			var digests []cranev1.Hash
+			for _, entry := range entries {
+				digests = append(digests, entry.Digest)
+			}
+			otherDigest, _ := other.Digest()
+			Expect(digests).To(ConsistOf(digest, otherDigest))
+		})
+	})
+})
@@ -1,3 +1,14 @@
 package policy
 
 var certDocumentationURL = "https://access.redhat.com/documentation/en-us/red_hat_software_certification/8.45/html/red_hat_openshift_software_certification_policy_guide/assembly-requirements-for-container-images_openshift-sw-cert-policy-introduction"
+
+// configuredList returns list, or defaults if list is nil, followed by additional. It
+// lets options either replace a check's default list or extend it.
+func configuredList[T any](list, additional, defaults []T) []T {
+	if list == nil {
+		list = defaults
+	}
+	configured := make([]T, 0, len(list)+len(additional))
+	configured = append(configured, list...)
+	return append(configured, additional...)
+}
@@ -15,7 +15,7 @@ import (
 )
 
 const (
-	licensePath         = "/licenses"
+	defaultLicensePath  = "/licenses"
 	minLicenseFileCount = 1
 )
 
@@ -23,9 +23,28 @@ var errLicensesNotADir = errors.New("licenses is not a directory")
 
 var _ types.Check = &HasLicenseCheck{}
 
+// LicenseOptions configures a HasLicenseCheck.
+type LicenseOptions struct {
+	// Path is the directory in the image that must contain the licenses. It
+	// defaults to /licenses.
+	Path string `yaml:"path"`
+}
+
+// NewHasLicenseCheck returns a HasLicenseCheck configured with opts.
+func NewHasLicenseCheck(opts LicenseOptions) *HasLicenseCheck {
+	p := &HasLicenseCheck{}
+	if opts.Path != "" {
+		// Rooting the path keeps it within the image filesystem when joined.
+		p.path = filepath.Join("/", opts.Path)
+	}
+	return p
+}
+
 // HasLicenseCheck evaluates that the image contains a license definition available at
-// /licenses.
-type HasLicenseCheck struct{}
+// /licenses, or the configured license path.
+type HasLicenseCheck struct {
+	path string
+}
 
 func (p *HasLicenseCheck) Validate(ctx context.Context, imgRef types.ImageReference) (bool, error) {
 	licenseFileList, err := p.getDataToValidate(ctx, imgRef.ImageFSPath)
@@ -40,6 +59,7 @@ func (p *HasLicenseCheck) Validate(ctx context.Context, imgRef types.ImageRefere
 
 //nolint:unparam // ctx is unused. Keep for future use.
 func (p *HasLicenseCheck) getDataToValidate(ctx context.Context, mountedPath string) ([]fs.DirEntry, error) {
+	licensePath := p.licensePath()
 	fullPath := filepath.Join(mountedPath, licensePath)
 	fileinfo, err := os.Stat(fullPath)
 	if err != nil {
@@ -75,13 +95,21 @@ func (p *HasLicenseCheck) validate(ctx context.Context, licenseFileList []fs.Dir
 	return len(licenseFileList) >= minLicenseFileCount && nonZeroLength, nil
 }
 
+// licensePath returns the configured license directory, or /licenses if none was set.
+func (p *HasLicenseCheck) licensePath() string {
+	if p.path == "" {
+		return defaultLicensePath
+	}
+	return p.path
+}
+
 func (p *HasLicenseCheck) Name() string {
 	return "HasLicense"
 }
 
 func (p *HasLicenseCheck) Metadata() types.Metadata {
 	return types.Metadata{
-		Description:      "Checking if terms and conditions applicable to the software including open source licensing information are present. The license must be at /licenses",
+		Description:      "Checking if terms and conditions applicable to the software including open source licensing information are present. The license must be at " + p.licensePath(),
 		Level:            "best",
 		KnowledgeBaseURL: certDocumentationURL,
 		CheckURL:         certDocumentationURL,
@@ -91,6 +119,6 @@ func (p *HasLicenseCheck) Metadata() types.Metadata {
 func (p *HasLicenseCheck) Help() types.HelpText {
 	return types.HelpText{
 		Message:    "Check HasLicense encountered an error. Please review the preflight.log file for more information.",
-		Suggestion: "Create a directory named /licenses and include all relevant licensing and/or terms and conditions as text file(s) in that directory.",
+		Suggestion: fmt.Sprintf("Create a directory named %s and include all relevant licensing and/or terms and conditions as text file(s) in that directory.", p.licensePath()),
 	}
 }
@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -15,6 +14,7 @@ import (
 	"github.com/opdev/knex/log"
 	"github.com/opdev/knex/types"
 
+	"github.com/opdev/container-certification/internal/findings"
 	"github.com/opdev/container-certification/internal/rpm"
 
 	"github.com/go-logr/logr"
@@ -23,12 +23,88 @@ import (
 	"github.com/spf13/afero"
 )
 
-var _ types.Check = &HasModifiedFilesCheck{}
+var (
+	_ types.Check       = &HasModifiedFilesCheck{}
+	_ findings.Reporter = &HasModifiedFilesCheck{}
+)
+
+// ModifiedFilesOptions configures a HasModifiedFilesCheck. Files that match any of its
+// exclusions may be modified by later layers. Paths are relative to the image root; a
+// leading slash is ignored. The default exclusions apply only to the layers mode.
+type ModifiedFilesOptions struct {
+	// ExcludedDirectories replaces the default excluded directories. Everything
+	// within an excluded directory is excluded.
+	ExcludedDirectories []string `yaml:"excludedDirectories"`
+	// AdditionalExcludedDirectories are excluded in addition to ExcludedDirectories.
+	AdditionalExcludedDirectories []string `yaml:"additionalExcludedDirectories"`
+	// ExcludedPaths replaces the default list of paths excluded exactly as written.
+	ExcludedPaths []string `yaml:"excludedPaths"`
+	// AdditionalExcludedPaths are excluded in addition to ExcludedPaths.
+	AdditionalExcludedPaths []string `yaml:"additionalExcludedPaths"`
+	// ExcludedPrefixSuffixes replaces the default list of prefix and suffix pairs.
+	// A path is excluded if it starts with the prefix and ends with the suffix.
+	ExcludedPrefixSuffixes []PrefixSuffix `yaml:"excludedPrefixSuffixes"`
+	// AdditionalExcludedPrefixSuffixes are excluded in addition to ExcludedPrefixSuffixes.
+	AdditionalExcludedPrefixSuffixes []PrefixSuffix `yaml:"additionalExcludedPrefixSuffixes"`
+	// Mode is how modifications are found: ModifiedFilesModeLayers, the default,
+	// or ModifiedFilesModeVerify.
+	Mode string `yaml:"mode"`
+	// VerifyConfigFiles verifies the files rpm marks as configuration in verify
+	// mode. They are skipped by default, since they are meant to be edited.
+	VerifyConfigFiles bool `yaml:"verifyConfigFiles"`
+	// VerifyDocFiles verifies the files rpm marks as documentation in verify
+	// mode. Missing documentation is never reported, since images are commonly
+	// installed without it.
+	VerifyDocFiles bool `yaml:"verifyDocFiles"`
+}
+
+// PrefixSuffix excludes paths that start with Prefix and end with Suffix.
+type PrefixSuffix struct {
+	Prefix string `yaml:"prefix"`
+	Suffix string `yaml:"suffix"`
+}
+
+// NewHasModifiedFilesCheck returns a HasModifiedFilesCheck configured with opts.
+func NewHasModifiedFilesCheck(opts ModifiedFilesOptions) *HasModifiedFilesCheck {
+	verify := opts.Mode == ModifiedFilesModeVerify
+	directories, paths, prefixSuffixes := defaultExcludedDirectories, defaultExcludedPaths, defaultExcludedPrefixSuffixes
+	if verify {
+		// The defaults allow for files that later layers commonly touch. Verify mode
+		// relies on the rpm file flags, such as %config, instead.
+		directories, paths, prefixSuffixes = nil, nil, nil
+	}
+
+	excl := fileExclusions{
+		directories:    configuredList(normalizeAll(opts.ExcludedDirectories), normalizeAll(opts.AdditionalExcludedDirectories), directories),
+		paths:          map[string]struct{}{},
+		prefixSuffixes: configuredList(opts.ExcludedPrefixSuffixes, opts.AdditionalExcludedPrefixSuffixes, prefixSuffixes),
+	}
+	for _, path := range configuredList(normalizeAll(opts.ExcludedPaths), normalizeAll(opts.AdditionalExcludedPaths), paths) {
+		excl.paths[path] = struct{}{}
+	}
+	for i, ps := range excl.prefixSuffixes {
+		excl.prefixSuffixes[i].Prefix = strings.TrimPrefix(ps.Prefix, "/")
+	}
+
+	return &HasModifiedFilesCheck{
+		exclusions:        &excl,
+		verify:            verify,
+		verifyConfigFiles: opts.VerifyConfigFiles,
+		verifyDocFiles:    opts.VerifyDocFiles,
+	}
+}
 
 // HasModifiedFilesCheck evaluates that no files from the base layer have been modified by
 // subsequent layers by comparing the file list installed by Packages against the file list
-// modified in subsequent layers.
-type HasModifiedFilesCheck struct{}
+// modified in subsequent layers. In verify mode, it instead compares the files in the
+// final image with the rpm database, the way rpm -Va does.
+type HasModifiedFilesCheck struct {
+	exclusions        *fileExclusions
+	verify            bool
+	verifyConfigFiles bool
+	verifyDocFiles    bool
+	report            findings.Report
+}
 
 const whiteoutPrefix = ".wh."
 
@@ -45,6 +121,11 @@ func (pm packageMeta) Compare(other packageMeta) int {
 	return 0
 }
 
+// nvra returns the name-version-release.arch of the package.
+func (pm packageMeta) nvra() string {
+	return fmt.Sprintf("%s-%s-%s.%s", pm.Name, pm.Version, pm.Release, pm.Arch)
+}
+
 type packageFilesRef struct {
 	// LayerFiles contains a slice of files created/modified in layer
 	LayerFiles []string
@@ -59,6 +140,10 @@ type packageFilesRef struct {
 
 // Validate runs the check of whether any Red Hat files were modified
 func (p *HasModifiedFilesCheck) Validate(ctx context.Context, imgRef types.ImageReference) (bool, error) {
+	if p.verify {
+		return p.verifyInstalledFiles(ctx, imgRef.ImageFSPath)
+	}
+
 	fs := afero.NewOsFs()
 	layerIDs, packageFiles, packageDist, err := p.gatherDataToValidate(ctx, imgRef, fs)
 	if err != nil {
@@ -140,7 +225,7 @@ func (p *HasModifiedFilesCheck) gatherDataToValidate(ctx context.Context, imgRef
 
 		pkgNameList := extractPackageNameVersionRelease(pkgList)
 
-		packageFiles, err := installedFileMapWithExclusions(ctx, pkgList)
+		packageFiles, err := installedFileMapWithExclusions(ctx, pkgList, p.fileExclusions())
 		if err != nil {
 			return nil, nil, "", err
 		}
@@ -184,6 +269,16 @@ func (p *HasModifiedFilesCheck) gatherDataToValidate(ctx context.Context, imgRef
 func (p *HasModifiedFilesCheck) validate(ctx context.Context, layerIDs []string, packageFiles map[string]packageFilesRef, packageDist string) (bool, error) {
 	logger := logr.FromContextOrDiscard(ctx)
 
+	p.report = findings.Report{
+		Inspected: fmt.Sprintf("rpm-installed files modified in %d layers", len(layerIDs)),
+		Findings:  []findings.Finding{},
+	}
+	defer func() {
+		if len(p.report.Findings) > 0 {
+			p.report.Remediation = p.Help().Suggestion
+		}
+	}()
+
 	disallowedModifications := false
 	for idx, layerID := range layerIDs {
 		logger := logger.WithValues("layer", layerID)
@@ -232,6 +327,13 @@ func (p *HasModifiedFilesCheck) validate(ctx context.Context, layerIDs []string,
 
 				// Nope, nope, nope. File was modified without using RPM
 				logger.V(log.DBG).Info("found disallowed modification in layer", "file", modifiedFile)
+				p.report.Findings = append(p.report.Findings, findings.Finding{
+					Kind:    findings.KindFile,
+					Subject: modifiedFile,
+					Layer:   layerID,
+					Package: currentPackage.nvra(),
+					Detail:  "file installed by a Red Hat package was modified outside of rpm",
+				})
 				disallowedModifications = true
 				continue
 			}
@@ -241,6 +343,12 @@ func (p *HasModifiedFilesCheck) validate(ctx context.Context, layerIDs []string,
 
 			if (previousOsRelease && !currentOsRelease) || (previousPackage.Arch != currentPackage.Arch) {
 				// If either of these differ, that's a fail
+				p.report.Findings = append(p.report.Findings, findings.Finding{
+					Kind:    findings.KindPackage,
+					Subject: currentPackage.nvra(),
+					Layer:   layerID,
+					Detail:  fmt.Sprintf("replaces %s with a build for a different OS release or architecture", previousPackage.nvra()),
+				})
 				return false, nil
 			}
 
@@ -251,6 +359,19 @@ func (p *HasModifiedFilesCheck) validate(ctx context.Context, layerIDs []string,
 	return !disallowedModifications, nil
 }
 
+// fileExclusions returns the configured exclusions, or the defaults if none were set.
+func (p *HasModifiedFilesCheck) fileExclusions() fileExclusions {
+	if p.exclusions == nil {
+		return defaultFileExclusions
+	}
+	return *p.exclusions
+}
+
+// Report returns the modifications found by the most recent validation.
+func (p *HasModifiedFilesCheck) Report() findings.Report {
+	return p.report
+}
+
 func (p HasModifiedFilesCheck) Name() string {
 	return "HasModifiedFiles"
 }
@@ -303,17 +424,48 @@ func findRPMDB(ctx context.Context, layer v1.Layer) (found bool, pkglist []*rpmd
 	return found, pkglist
 }
 
-// directoryIsExcluded excludes a directory and any file contained in that directory.
-func directoryIsExcluded(ctx context.Context, s string) bool {
-	excl := map[string]struct{}{
-		"etc":               {},
-		"var":               {},
-		"run":               {},
-		"usr/lib/.build-id": {},
-		"usr/tmp":           {},
-	}
+// defaultExcludedDirectories are excluded, along with any file contained in them,
+// unless the check is configured otherwise.
+var defaultExcludedDirectories = []string{
+	"etc",
+	"var",
+	"run",
+	"usr/lib/.build-id",
+	"usr/tmp",
+}
+
+// defaultExcludedPaths are excluded exactly as written.
+var defaultExcludedPaths = []string{
+	"etc/resolv.conf",
+	"etc/hostname",
+	// etc and etc/ are both required as both can present the directory
+	// in a tarball. Same goes for other directories.
+	"etc",
+	"etc/",
+	"run",
+	"run/",
+}
+
+var defaultExcludedPrefixSuffixes = []PrefixSuffix{
+	{Prefix: "usr/lib64/", Suffix: ".cache"},
+}
+
+var defaultFileExclusions = fileExclusions{
+	directories:    defaultExcludedDirectories,
+	paths:          setOf(defaultExcludedPaths),
+	prefixSuffixes: defaultExcludedPrefixSuffixes,
+}
+
+// fileExclusions are the rpm-installed files that may be modified by later layers.
+type fileExclusions struct {
+	directories    []string
+	paths          map[string]struct{}
+	prefixSuffixes []PrefixSuffix
+}
 
-	for k := range excl {
+// directoryIsExcluded excludes a directory and any file contained in that directory.
+func (e fileExclusions) directoryIsExcluded(ctx context.Context, s string) bool {
+	for _, k := range e.directories {
 		if strings.HasPrefix(s, filepath.Clean(k+"/")) || k == s {
 			logger := logr.FromContextOrDiscard(ctx)
 			logger.V(log.TRC).Info("directory excluded", "directory", s)
@@ -325,19 +477,8 @@ func directoryIsExcluded(ctx context.Context, s string) bool {
 }
 
 // pathIsExcluded checks if s is excluded explicitly as written.
-func pathIsExcluded(ctx context.Context, s string) bool {
-	excl := map[string]struct{}{
-		"etc/resolv.conf": {},
-		"etc/hostname":    {},
-		// etc and etc/ are both required as both can present the directory
-		// in a tarball. Same goes for other directories.
-		"etc":  {},
-		"etc/": {},
-		"run":  {},
-		"run/": {},
-	}
-
-	_, found := excl[s]
+func (e fileExclusions) pathIsExcluded(ctx context.Context, s string) bool {
+	_, found := e.paths[s]
 	if found {
 		logger := logr.FromContextOrDiscard(ctx)
 		logger.V(log.TRC).Info("file excluded", "file", s)
@@ -347,15 +488,8 @@ func pathIsExcluded(ctx context.Context, s string) bool {
 }
 
 // prefixAndSuffixIsExcluded will check both start and end of path
-func prefixAndSuffixIsExcluded(ctx context.Context, s string) bool {
-	excl := []struct {
-		Prefix string
-		Suffix string
-	}{
-		{Prefix: "usr/lib64/", Suffix: ".cache"},
-	}
-
-	for _, v := range excl {
+func (e fileExclusions) prefixAndSuffixIsExcluded(ctx context.Context, s string) bool {
+	for _, v := range e.prefixSuffixes {
 		if strings.HasPrefix(s, v.Prefix) && strings.HasSuffix(s, v.Suffix) {
 			logger := logr.FromContextOrDiscard(ctx)
 			logger.V(log.TRC).Info("prefix and suffix excluded", "filename", s, "prefix", v.Prefix, "suffix", v.Suffix)
@@ -366,6 +500,14 @@ func prefixAndSuffixIsExcluded(ctx context.Context, s string) bool {
 	return false
 }
 
+func setOf(list []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(list))
+	for _, s := range list {
+		set[s] = struct{}{}
+	}
+	return set
+}
+
 // normalize will clean a filepath of extraneous characters like ./, //, etc.
 // and strip a leading slash. E.g. /foo/../baz --> baz
 func normalize(s string) string {
@@ -376,9 +518,21 @@ func normalize(s string) string {
 	return filepath.Clean(strings.TrimPrefix(s, "/"))
 }
 
+// normalizeAll normalizes each path in paths. A nil list stays nil.
+func normalizeAll(paths []string) []string {
+	if paths == nil {
+		return nil
+	}
+	normalized := make([]string, 0, len(paths))
+	for _, p := range paths {
+		normalized = append(normalized, normalize(p))
+	}
+	return normalized
+}
+
 // installedFileMapWithExclusions gets a map of installed filenames that have been cleaned
 // of extra slashes, dotslashes, and leading slashes.
-func installedFileMapWithExclusions(ctx context.Context, pkglist []*rpmdb.PackageInfo) (map[string]string, error) {
+func installedFileMapWithExclusions(ctx context.Context, pkglist []*rpmdb.PackageInfo, excl fileExclusions) (map[string]string, error) {
 	const okFlags = rpmdb.RPMFILE_CONFIG |
 		rpmdb.RPMFILE_DOC |
 		rpmdb.RPMFILE_LICENSE |
@@ -397,7 +551,7 @@ func installedFileMapWithExclusions(ctx context.Context, pkglist []*rpmdb.Packag
 				continue
 			}
 			normalized := normalize(file.Path)
-			if pathIsExcluded(ctx, normalized) || directoryIsExcluded(ctx, normalized) || prefixAndSuffixIsExcluded(ctx, normalized) {
+			if excl.pathIsExcluded(ctx, normalized) || excl.directoryIsExcluded(ctx, normalized) || excl.prefixAndSuffixIsExcluded(ctx, normalized) {
 				// It is either an explicitly excluded path or directory. Skip it.
 				continue
 			}
@@ -478,89 +632,7 @@ func generateChangesFor(ctx context.Context, layer v1.Layer) ([]string, error) {
 	return keys, nil
 }
 
-// ExtractRPMDB copies /var/lib/rpm/* from the archive and derives a list of packages from
-// the rpm database.
+// extractRPMDB derives a list of packages from the rpm database in layer.
 func extractRPMDB(ctx context.Context, layer v1.Layer) ([]*rpmdb.PackageInfo, error) {
-	layerReader, err := layer.Uncompressed()
-	if err != nil {
-		return nil, fmt.Errorf("reading layer contents: %w", err)
-	}
-	defer layerReader.Close()
-
-	fs := afero.NewOsFs()
-	basepath, err := afero.TempDir(fs, "", "rpmdb")
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		_ = fs.RemoveAll(basepath)
-	}()
-
-	tarReader := tar.NewReader(layerReader)
-	for {
-		header, err := tarReader.Next()
-		if errors.Is(err, io.EOF) {
-			break
-		}
-		if err != nil {
-			return nil, fmt.Errorf("reading tar: %w", err)
-		}
-
-		// Some tools prepend everything with "./", so if we don't Clean the
-		// name, we may have duplicate entries, which angers tar-split.
-		header.Name = filepath.Clean(header.Name)
-		header.Format = tar.FormatPAX
-		rpmdirname := "var/lib/rpm"
-		basename := filepath.Base(header.Name)
-		dirname := filepath.Dir(header.Name)
-		tombstone := strings.HasPrefix(basename, whiteoutPrefix)
-
-		// Not a file or directory? Continue...
-		if header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeReg {
-			continue
-		}
-
-		// Tombstone? Ignore...
-		if tombstone {
-			continue
-		}
-
-		// Not in the RPM directory. Ignore...
-		if !strings.HasPrefix(filepath.Join(dirname, basename), rpmdirname) {
-			continue
-		}
-		// a dir or file with the correct var/lib/rpm prefix that has not been marked with a tombstone is valid.
-		if header.Typeflag == tar.TypeDir {
-			err := os.MkdirAll(filepath.Join(basepath, dirname, basename), header.FileInfo().Mode())
-			if err != nil {
-				return nil, err
-			}
-			continue
-		}
-
-		f, err := fs.OpenFile(filepath.Join(basepath, dirname, basename), os.O_RDWR|os.O_CREATE|os.O_TRUNC, header.FileInfo().Mode())
-		if err != nil {
-			return nil, err
-		}
-		err = func() error {
-			// closure here allows us to defer f.Close() in this iteration instead of
-			// waiting for the parent function to complete.
-			defer f.Close()
-			_, err := io.Copy(f, tarReader)
-			if err != nil {
-				return err
-			}
-			return nil
-		}()
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	packageList, err := rpm.GetPackageList(ctx, basepath)
-	if err != nil {
-		return nil, err
-	}
-
-	return packageList, nil
+	return rpm.LayerPackageList(ctx, layer)
 }
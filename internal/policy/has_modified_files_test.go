@@ -12,6 +12,8 @@ import (
 	. "github.com/onsi/gomega"
 	"github.com/opdev/knex/types"
 	"github.com/sirupsen/logrus"
+
+	"github.com/opdev/container-certification/internal/findings"
 )
 
 var _ = Describe("HasModifiedFiles", func() {
@@ -178,6 +180,17 @@ var _ = Describe("HasModifiedFiles", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(ok).To(BeFalse())
 			})
+			It("should report the file and the layer that modified it", func() {
+				_, err := hasModifiedFiles.validate(context.Background(), layers, pkgs, dist)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hasModifiedFiles.Report().Findings).To(ConsistOf(findings.Finding{
+					Kind:    findings.KindFile,
+					Subject: "this",
+					Layer:   "secondlayer",
+					Package: "foo-1.0-1.d9.fooarch",
+					Detail:  "file installed by a Red Hat package was modified outside of rpm",
+				}))
+			})
 		})
 		When("a package is updated", func() {
 			var pkgs map[string]packageFilesRef
@@ -329,7 +342,7 @@ var _ = Describe("HasModifiedFiles", func() {
 			}
 		})
 		It("should contain all files installed by the package according to its metadata", func() {
-			files, err := installedFileMapWithExclusions(context.TODO(), goodPkgList)
+			files, err := installedFileMapWithExclusions(context.TODO(), goodPkgList, defaultFileExclusions)
 			Expect(err).ToNot(HaveOccurred())
 
 			_, ok := files[path.Join(dirname, basename)]
@@ -339,9 +352,17 @@ var _ = Describe("HasModifiedFiles", func() {
 		It("should fail if the rpm is invalid", func() {
 			badPkgList := goodPkgList
 			badPkgList[0].DirNames = []string{dirname, "extradir"}
-			_, err := installedFileMapWithExclusions(context.TODO(), badPkgList)
+			_, err := installedFileMapWithExclusions(context.TODO(), badPkgList, defaultFileExclusions)
 			Expect(err).To(HaveOccurred())
 		})
+
+		It("should leave out files in configured excluded directories", func() {
+			check := NewHasModifiedFilesCheck(ModifiedFilesOptions{AdditionalExcludedDirectories: []string{"/" + dirname}})
+			files, err := installedFileMapWithExclusions(context.TODO(), goodPkgList, check.fileExclusions())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(files).To(BeEmpty())
+			Expect(check.fileExclusions().directories).To(ContainElement("etc"))
+		})
 	})
 
 	When("calling the top level Validate", func() {
@@ -0,0 +1,317 @@
+package policy
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/opdev/knex/log"
+
+	"github.com/opdev/container-certification/internal/findings"
+	"github.com/opdev/container-certification/internal/rpm"
+
+	"github.com/go-logr/logr"
+	rpmdb "github.com/knqyf263/go-rpmdb/pkg"
+)
+
+const (
+	// ModifiedFilesModeLayers compares the files changed by each layer with the
+	// rpm database of that layer.
+	ModifiedFilesModeLayers = "layers"
+	// ModifiedFilesModeVerify compares the files in the final image with the
+	// digests, sizes and modes recorded in its rpm database, like rpm -Va.
+	ModifiedFilesModeVerify = "verify"
+)
+
+// The hash algorithms an rpm database may record file digests with, as numbered
+// by OpenPGP (RFC 4880). Packages without a recorded algorithm use MD5.
+const (
+	pgpHashMD5    = 1
+	pgpHashSHA1   = 2
+	pgpHashSHA256 = 8
+	pgpHashSHA384 = 9
+	pgpHashSHA512 = 10
+	pgpHashSHA224 = 11
+)
+
+// Bits of the st_mode rpm records for each file.
+const (
+	modeTypeMask  = 0o170000
+	modeDirectory = 0o040000
+	modeRegular   = 0o100000
+	modeSymlink   = 0o120000
+	modePermMask  = 0o7777
+)
+
+// fileVerification is what the verification of one rpm-installed file found. Its
+// fields mirror the columns of rpm -V.
+type fileVerification struct {
+	missing bool
+	size    bool
+	mode    bool
+	digest  bool
+	// untested is set if the digest could not be computed because the
+	// algorithm is not supported.
+	untested bool
+}
+
+func (v fileVerification) failed() bool {
+	return v.missing || v.size || v.mode || v.digest
+}
+
+// attributes returns the attribute column rpm -V prints for v, e.g. "S.5......".
+func (v fileVerification) attributes() string {
+	if v.missing {
+		return "missing"
+	}
+	attrs := []byte(".........")
+	if v.size {
+		attrs[0] = 'S'
+	}
+	if v.mode {
+		attrs[1] = 'M'
+	}
+	switch {
+	case v.digest:
+		attrs[2] = '5'
+	case v.untested:
+		attrs[2] = '?'
+	}
+	return string(attrs)
+}
+
+// detail describes v for a finding.
+func (v fileVerification) detail(config bool) string {
+	var detail string
+	if v.missing {
+		detail = "file is missing from the image"
+	} else {
+		var differ []string
+		if v.size {
+			differ = append(differ, "size")
+		}
+		if v.mode {
+			differ = append(differ, "mode")
+		}
+		if v.digest {
+			differ = append(differ, "digest")
+		}
+		detail = fmt.Sprintf("%s: %s differ from the rpm database", v.attributes(), strings.Join(differ, ", "))
+	}
+	if config {
+		detail += " (configuration file)"
+	}
+	return detail
+}
+
+// verifyInstalledFiles checks the files installed by the packages in the rpm database
+// of the image at root against what the database recorded for them.
+func (p *HasModifiedFilesCheck) verifyInstalledFiles(ctx context.Context, root string) (bool, error) {
+	pkgList, err := rpm.GetPackageList(ctx, root)
+	if errors.Is(err, os.ErrNotExist) {
+		logr.FromContextOrDiscard(ctx).V(log.DBG).Info("no rpm database found, so there are no rpm-installed files to verify")
+		pkgList = nil
+	} else if err != nil {
+		return false, fmt.Errorf("could not read the rpm database: %v", err)
+	}
+
+	return p.verifyPackages(ctx, root, pkgList)
+}
+
+// verifyPackages checks the files installed by pkgList, relative to root.
+func (p *HasModifiedFilesCheck) verifyPackages(ctx context.Context, root string, pkgList []*rpmdb.PackageInfo) (bool, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+	excl := p.fileExclusions()
+
+	var verified int
+	found := []findings.Finding{}
+	for _, pkg := range pkgList {
+		files, err := pkg.InstalledFiles()
+		if err != nil {
+			return false, fmt.Errorf("could not list the files of package %s: %v", pkg.Name, err)
+		}
+
+		nvra := fmt.Sprintf("%s-%s-%s.%s", pkg.Name, pkg.Version, pkg.Release, pkg.Arch)
+		newHash := fileDigestHash(int32(pkg.DigestAlgorithm))
+		var mismatches int
+		for _, file := range files {
+			flags := int32(file.Flags)
+			normalized := normalize(file.Path)
+			switch {
+			case flags&rpmdb.RPMFILE_GHOST != 0:
+				// Ghost files are owned by the package but not shipped in it.
+				continue
+			case flags&rpmdb.RPMFILE_CONFIG != 0 && !p.verifyConfigFiles:
+				continue
+			case flags&(rpmdb.RPMFILE_DOC|rpmdb.RPMFILE_LICENSE|rpmdb.RPMFILE_README) != 0 && !p.verifyDocFiles:
+				continue
+			case excl.pathIsExcluded(ctx, normalized) || excl.directoryIsExcluded(ctx, normalized) || excl.prefixAndSuffixIsExcluded(ctx, normalized):
+				continue
+			}
+
+			result, err := verifyFile(filepath.Join(root, normalized), file, newHash)
+			if err != nil {
+				return false, fmt.Errorf("could not verify %s from package %s: %v", normalized, nvra, err)
+			}
+			verified++
+
+			// Files flagged as missingok, and documentation, which is left out
+			// of images installed with nodocs, may be absent.
+			if result.missing && flags&(rpmdb.RPMFILE_MISSINGOK|rpmdb.RPMFILE_DOC|rpmdb.RPMFILE_LICENSE|rpmdb.RPMFILE_README) != 0 {
+				continue
+			}
+			if !result.failed() {
+				continue
+			}
+
+			logger.V(log.DBG).Info("rpm-installed file does not match the rpm database", "file", normalized, "package", nvra, "attributes", result.attributes())
+			mismatches++
+			found = append(found, findings.Finding{
+				Kind:    findings.KindFile,
+				Subject: normalized,
+				Package: nvra,
+				Detail:  result.detail(flags&rpmdb.RPMFILE_CONFIG != 0),
+			})
+		}
+		if mismatches > 0 {
+			logger.Info(fmt.Sprintf("%d files installed by package %s do not match the rpm database", mismatches, nvra))
+		}
+	}
+
+	// Group the findings by package.
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Package != found[j].Package {
+			return found[i].Package < found[j].Package
+		}
+		return found[i].Subject < found[j].Subject
+	})
+
+	p.report = findings.Report{
+		Inspected: fmt.Sprintf("%d files installed by %d rpm packages, verified against the rpm database", verified, len(pkgList)),
+		Findings:  found,
+	}
+	if len(found) > 0 {
+		p.report.Remediation = "Do not modify files installed by rpm. Install a different version of the package with rpm or dnf instead"
+	}
+
+	return len(found) == 0, nil
+}
+
+// verifyFile compares the file at path with what the rpm database recorded for it.
+// newHash returns the hash to compare the file's digest with, or nil if the
+// algorithm is not supported.
+func verifyFile(path string, file rpmdb.FileInfo, newHash func() hash.Hash) (fileVerification, error) {
+	var result fileVerification
+
+	fi, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		result.missing = true
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+
+	if file.Mode != 0 {
+		mode, want := unixMode(fi.Mode()), file.Mode
+		switch {
+		case mode&modeTypeMask == 0:
+			// Devices, pipes and sockets are compared by permissions only.
+			want &= modePermMask
+		case mode&modeTypeMask == modeDirectory:
+			// Directories are extracted with owner access, so the owner
+			// permissions of the image are not known.
+			mode |= 0o700
+			want |= 0o700
+		}
+		result.mode = mode != want
+	}
+
+	// rpm records a digest and size only for regular files.
+	if !fi.Mode().IsRegular() || (file.Mode != 0 && file.Mode&modeTypeMask != modeRegular) {
+		return result, nil
+	}
+
+	if file.Size >= 0 && fi.Size() != int64(file.Size) {
+		result.size = true
+	}
+
+	if file.Digest == "" {
+		return result, nil
+	}
+	if newHash == nil {
+		result.untested = true
+		return result, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return result, err
+	}
+	defer f.Close()
+
+	h := newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return result, err
+	}
+	result.digest = !strings.EqualFold(hex.EncodeToString(h.Sum(nil)), file.Digest)
+
+	return result, nil
+}
+
+// fileDigestHash returns the hash for an rpm file digest algorithm, or nil if it is
+// not supported.
+func fileDigestHash(algorithm int32) func() hash.Hash {
+	switch algorithm {
+	case 0, pgpHashMD5:
+		return md5.New
+	case pgpHashSHA1:
+		return sha1.New
+	case pgpHashSHA224:
+		return sha256.New224
+	case pgpHashSHA256:
+		return sha256.New
+	case pgpHashSHA384:
+		return sha512.New384
+	case pgpHashSHA512:
+		return sha512.New
+	default:
+		return nil
+	}
+}
+
+// unixMode converts mode to the st_mode value rpm records. The file type bits are
+// only set for directories, symlinks and regular files.
+func unixMode(mode fs.FileMode) uint16 {
+	m := uint16(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		m |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		m |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		m |= 0o1000
+	}
+	switch {
+	case mode.IsDir():
+		m |= modeDirectory
+	case mode&fs.ModeSymlink != 0:
+		m |= modeSymlink
+	case mode.IsRegular():
+		m |= modeRegular
+	}
+	return m
+}
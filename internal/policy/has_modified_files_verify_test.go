@@ -0,0 +1,173 @@
+package policy
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	rpmdb "github.com/knqyf263/go-rpmdb/pkg"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
+)
+
+var _ = Describe("HasModifiedFiles in verify mode", func() {
+	const (
+		original = "as shipped\n"
+		regular  = 0o100755
+	)
+
+	var (
+		root string
+		pkg  *rpmdb.PackageInfo
+	)
+
+	digest := func(content string) string {
+		sum := md5.Sum([]byte(content))
+		return hex.EncodeToString(sum[:])
+	}
+
+	writeFile := func(name, content string, mode os.FileMode) {
+		path := filepath.Join(root, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), mode)).To(Succeed())
+		Expect(os.Chmod(path, mode)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+		pkg = &rpmdb.PackageInfo{
+			Name:    "tool",
+			Version: "1.0",
+			Release: "1.el9",
+			Arch:    "x86_64",
+			DirNames: []string{
+				"/usr/bin",
+				"/opt/tool",
+				"/usr/share/doc/tool",
+			},
+			BaseNames:   []string{"tool", "tool.conf", "README", "gone"},
+			DirIndexes:  []int32{0, 1, 2, 0},
+			FileDigests: []string{digest(original), digest(original), digest(original), digest(original)},
+			FileSizes:   []int32{int32(len(original)), int32(len(original)), int32(len(original)), int32(len(original))},
+			FileModes:   []uint16{regular, 0o100644, 0o100644, regular},
+			FileFlags:   []int32{0, rpmdb.RPMFILE_CONFIG, rpmdb.RPMFILE_DOC, rpmdb.RPMFILE_MISSINGOK},
+		}
+		writeFile("usr/bin/tool", original, 0o755)
+		writeFile("opt/tool/tool.conf", original, 0o644)
+	})
+
+	It("should pass when the files match the rpm database", func() {
+		check := NewHasModifiedFilesCheck(ModifiedFilesOptions{Mode: ModifiedFilesModeVerify})
+		passed, err := check.verifyPackages(context.TODO(), root, []*rpmdb.PackageInfo{pkg})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(passed).To(BeTrue())
+		Expect(check.Report().Inspected).To(HavePrefix("2 files installed by 1 rpm packages"))
+	})
+
+	It("should report each modified file with its package", func() {
+		writeFile("usr/bin/tool", "patched!!!\n", 0o755|os.ModeSetuid)
+		writeFile("opt/tool/tool.conf", "edited\n", 0o644)
+
+		check := NewHasModifiedFilesCheck(ModifiedFilesOptions{Mode: ModifiedFilesModeVerify})
+		passed, err := check.verifyPackages(context.TODO(), root, []*rpmdb.PackageInfo{pkg})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(passed).To(BeFalse())
+		Expect(check.Report().Findings).To(ConsistOf(findings.Finding{
+			Kind:    findings.KindFile,
+			Subject: "usr/bin/tool",
+			Package: "tool-1.0-1.el9.x86_64",
+			Detail:  ".M5......: mode, digest differ from the rpm database",
+		}))
+	})
+
+	It("should report modified configuration files and missing documentation only when asked to", func() {
+		writeFile("opt/tool/tool.conf", "edited\n", 0o644)
+		writeFile("usr/share/doc/tool/README", "rewritten\n", 0o644)
+		pkg.FileFlags[3] = 0
+
+		check := NewHasModifiedFilesCheck(ModifiedFilesOptions{
+			Mode:              ModifiedFilesModeVerify,
+			VerifyConfigFiles: true,
+			VerifyDocFiles:    true,
+		})
+		passed, err := check.verifyPackages(context.TODO(), root, []*rpmdb.PackageInfo{pkg})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(passed).To(BeFalse())
+
+		details := map[string]string{}
+		for _, f := range check.Report().Findings {
+			details[f.Subject] = f.Detail
+		}
+		Expect(details).To(Equal(map[string]string{
+			"opt/tool/tool.conf":        "S.5......: size, digest differ from the rpm database (configuration file)",
+			"usr/share/doc/tool/README": "S.5......: size, digest differ from the rpm database",
+			"usr/bin/gone":              "file is missing from the image",
+		}))
+	})
+
+	It("should verify files in the directories that the layers mode excludes by default", func() {
+		pkg.DirNames[1] = "/etc/tool"
+		writeFile("etc/tool/tool.conf", "edited\n", 0o644)
+
+		check := NewHasModifiedFilesCheck(ModifiedFilesOptions{Mode: ModifiedFilesModeVerify})
+		passed, err := check.verifyPackages(context.TODO(), root, []*rpmdb.PackageInfo{pkg})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(passed).To(BeTrue(), "configuration files are skipped by their rpm file flags")
+
+		pkg.FileFlags[1] = 0
+		passed, err = check.verifyPackages(context.TODO(), root, []*rpmdb.PackageInfo{pkg})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(passed).To(BeFalse())
+		Expect(check.Report().Findings).To(ConsistOf(findings.Finding{
+			Kind:    findings.KindFile,
+			Subject: "etc/tool/tool.conf",
+			Package: "tool-1.0-1.el9.x86_64",
+			Detail:  "S.5......: size, digest differ from the rpm database",
+		}))
+	})
+
+	It("should skip files in excluded directories", func() {
+		writeFile("usr/bin/tool", "patched\n", 0o755)
+
+		check := NewHasModifiedFilesCheck(ModifiedFilesOptions{
+			Mode:                          ModifiedFilesModeVerify,
+			AdditionalExcludedDirectories: []string{"/usr/bin"},
+		})
+		passed, err := check.verifyPackages(context.TODO(), root, []*rpmdb.PackageInfo{pkg})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(passed).To(BeTrue())
+	})
+
+	It("should pass an image without an rpm database", func() {
+		check := NewHasModifiedFilesCheck(ModifiedFilesOptions{Mode: ModifiedFilesModeVerify})
+		passed, err := check.Validate(context.TODO(), types.ImageReference{ImageFSPath: root})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(passed).To(BeTrue())
+	})
+
+	It("should mark digests it cannot compute as untested", func() {
+		writeFile("usr/bin/tool", original, 0o755)
+		file := rpmdb.FileInfo{Path: "/usr/bin/tool", Mode: regular, Size: int32(len(original)), Digest: digest(original)}
+		result, err := verifyFile(filepath.Join(root, "usr/bin/tool"), file, fileDigestHash(3))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.failed()).To(BeFalse())
+		Expect(result.attributes()).To(Equal("..?......"))
+	})
+
+	It("should not compare the owner permissions of directories, which are extracted with owner access", func() {
+		dir := rpmdb.FileInfo{Path: "/usr/bin", Mode: modeDirectory | 0o555}
+		result, err := verifyFile(filepath.Join(root, "usr/bin"), dir, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.failed()).To(BeFalse())
+
+		dir.Mode = modeDirectory | 0o700
+		result, err = verifyFile(filepath.Join(root, "usr/bin"), dir, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.mode).To(BeTrue())
+	})
+})
@@ -0,0 +1,664 @@
+package policy
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/opdev/knex/log"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
+
+	"github.com/go-logr/logr"
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+const (
+	// defaultMaxSecretScanBytes is the size above which files are not scanned.
+	defaultMaxSecretScanBytes = 10 * 1024 * 1024
+	// binarySniffBytes is how much of a file is read to decide whether it is binary.
+	binarySniffBytes = 8000
+	// maxSecretLineBytes is the longest line that is scanned at once. Longer lines,
+	// such as minified scripts, are scanned in chunks of this size that overlap by
+	// secretChunkOverlapBytes, so that a secret across two chunks is still found.
+	maxSecretLineBytes      = 1024 * 1024
+	secretChunkOverlapBytes = 4 * 1024
+)
+
+var (
+	_ types.Check       = &HasNoEmbeddedSecretsCheck{}
+	_ findings.Reporter = &HasNoEmbeddedSecretsCheck{}
+)
+
+// SecretRule describes a kind of secret. A rule with Paths only applies to files
+// whose path matches one of them; a rule without Paths also applies to the Env and
+// labels of the image config.
+type SecretRule struct {
+	ID          string `yaml:"id"`
+	Description string `yaml:"description"`
+	// Pattern is a regular expression matched against each line.
+	Pattern string `yaml:"pattern"`
+	// Paths are globs of the files the rule applies to, relative to the image
+	// root. "**" matches any number of directories.
+	Paths []string `yaml:"paths"`
+	// MinEntropy is the minimum Shannon entropy, in bits per character, of the
+	// first capture group of Pattern (or of the whole match) for it to count as
+	// a secret. It filters out placeholders such as "changeme".
+	MinEntropy float64 `yaml:"minEntropy"`
+}
+
+// SecretAllow exempts matches from being reported.
+type SecretAllow struct {
+	// Path is a glob of the files to exempt, or "env:NAME" or "label:NAME"
+	// for a variable or label in the image config. NAME may be a glob too.
+	Path string `yaml:"path"`
+	// Rule limits the exemption to the rule with this ID. If empty, all rules
+	// are exempted.
+	Rule string `yaml:"rule"`
+}
+
+// EmbeddedSecretsOptions configures a HasNoEmbeddedSecretsCheck.
+type EmbeddedSecretsOptions struct {
+	// Rules replaces the default rules.
+	Rules []SecretRule `yaml:"rules"`
+	// AdditionalRules are applied in addition to Rules.
+	AdditionalRules []SecretRule `yaml:"additionalRules"`
+	// Allowlist replaces the default allowlist.
+	Allowlist []SecretAllow `yaml:"allowlist"`
+	// AdditionalAllowlist is allowed in addition to Allowlist.
+	AdditionalAllowlist []SecretAllow `yaml:"additionalAllowlist"`
+	// MaxFileSize is the size in bytes above which files are not scanned.
+	// Values below 1 use the default of 10MiB.
+	MaxFileSize int64 `yaml:"maxFileSize"`
+}
+
+// defaultSecretRules are the rules used unless the check is configured otherwise.
+var defaultSecretRules = []SecretRule{
+	{
+		ID:          "private-key",
+		Description: "private key",
+		Pattern:     `-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY(?: BLOCK)?-----`,
+	},
+	{
+		ID:          "aws-access-key-id",
+		Description: "AWS access key ID",
+		Pattern:     `\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`,
+	},
+	{
+		ID:          "aws-secret-access-key",
+		Description: "AWS secret access key",
+		Pattern:     `(?i)aws_?secret_?access_?key["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`,
+		MinEntropy:  3.5,
+	},
+	{
+		ID:          "gcp-service-account-key",
+		Description: "Google Cloud service account key",
+		Pattern:     `"private_key_id"\s*:\s*"[a-f0-9]{40}"`,
+	},
+	{
+		ID:          "github-token",
+		Description: "GitHub token",
+		Pattern:     `\b(gh[pousr]_[A-Za-z0-9]{36,})\b`,
+	},
+	{
+		ID:          "slack-token",
+		Description: "Slack token",
+		Pattern:     `\b(xox[abprs]-[0-9A-Za-z-]{10,})\b`,
+	},
+	{
+		ID:          "registry-auth",
+		Description: "container registry credentials",
+		Pattern:     `"auth"\s*:\s*"([A-Za-z0-9+/=]{8,})"`,
+		Paths:       []string{"**/.docker/config.json", "**/.dockercfg", "**/containers/auth.json"},
+	},
+	{
+		ID:          "npm-token",
+		Description: "npm registry token",
+		Pattern:     `(?:_authToken|_auth|_password)\s*=\s*(\S+)`,
+		Paths:       []string{"**/.npmrc"},
+	},
+	{
+		ID:          "pypi-password",
+		Description: "PyPI password",
+		Pattern:     `(?i)^\s*password\s*[=:]\s*(\S+)`,
+		Paths:       []string{"**/.pypirc"},
+	},
+	{
+		ID:          "git-credentials",
+		Description: "git credentials",
+		Pattern:     `https?://[^:@/\s]+:([^@/\s]+)@`,
+		Paths:       []string{"**/.git-credentials"},
+	},
+	{
+		ID:          "generic-secret",
+		Description: "high-entropy secret assigned to a password, secret, token or key",
+		Pattern:     `(?i)(?:password|passwd|secret|token|api_?key|access_?key)[a-z0-9_]*["']?\s*[=:]\s*["']?([^\s"',;]{16,})`,
+		MinEntropy:  4.0,
+	},
+}
+
+// defaultSecretAllowlist exempts documentation and test fixtures, which commonly
+// contain example keys.
+var defaultSecretAllowlist = []SecretAllow{
+	{Path: "usr/share/doc/**"},
+	{Path: "usr/share/licenses/**"},
+	{Path: "usr/share/man/**"},
+	{Path: "**/test/**"},
+	{Path: "**/tests/**"},
+	{Path: "**/testdata/**"},
+}
+
+// compiledSecretRule is a SecretRule ready to be matched.
+type compiledSecretRule struct {
+	SecretRule
+	pattern *regexp.Regexp
+	paths   []*regexp.Regexp
+}
+
+// compiledSecretAllow is a SecretAllow ready to be matched.
+type compiledSecretAllow struct {
+	rule string
+	path *regexp.Regexp
+}
+
+// secretScanner holds the compiled rules and allowlist of a check.
+type secretScanner struct {
+	rules       []compiledSecretRule
+	allowlist   []compiledSecretAllow
+	maxFileSize int64
+}
+
+// defaultSecretScanner is used by checks that were not configured.
+var defaultSecretScanner = &secretScanner{
+	rules:       mustCompileSecretRules(defaultSecretRules),
+	allowlist:   mustCompileSecretAllowlist(defaultSecretAllowlist),
+	maxFileSize: defaultMaxSecretScanBytes,
+}
+
+// mustCompileSecretRules compiles rules that are known to be valid, like
+// regexp.MustCompile.
+func mustCompileSecretRules(rules []SecretRule) []compiledSecretRule {
+	compiled := make([]compiledSecretRule, 0, len(rules))
+	for _, rule := range rules {
+		c := compiledSecretRule{SecretRule: rule, pattern: regexp.MustCompile(rule.Pattern)}
+		for _, p := range rule.Paths {
+			c.paths = append(c.paths, regexp.MustCompile(globPattern(p)))
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled
+}
+
+// mustCompileSecretAllowlist compiles an allowlist that is known to be valid, like
+// regexp.MustCompile.
+func mustCompileSecretAllowlist(allowlist []SecretAllow) []compiledSecretAllow {
+	compiled := make([]compiledSecretAllow, 0, len(allowlist))
+	for _, allow := range allowlist {
+		compiled = append(compiled, compiledSecretAllow{rule: allow.Rule, path: regexp.MustCompile(globPattern(allow.Path))})
+	}
+	return compiled
+}
+
+func newSecretScanner(rules []SecretRule, allowlist []SecretAllow, maxFileSize int64) (*secretScanner, error) {
+	if maxFileSize < 1 {
+		maxFileSize = defaultMaxSecretScanBytes
+	}
+	s := &secretScanner{maxFileSize: maxFileSize}
+
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("secret rule with pattern %q has no id", rule.Pattern)
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("secret rule %s is defined more than once", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for secret rule %s: %w", rule.ID, err)
+		}
+		compiled := compiledSecretRule{SecretRule: rule, pattern: pattern}
+		for _, p := range rule.Paths {
+			re, err := globRegexp(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path for secret rule %s: %w", rule.ID, err)
+			}
+			compiled.paths = append(compiled.paths, re)
+		}
+		s.rules = append(s.rules, compiled)
+	}
+
+	for _, allow := range allowlist {
+		re, err := globRegexp(allow.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret allowlist path: %w", err)
+		}
+		s.allowlist = append(s.allowlist, compiledSecretAllow{rule: allow.Rule, path: re})
+	}
+
+	return s, nil
+}
+
+// NewHasNoEmbeddedSecretsCheck returns a HasNoEmbeddedSecretsCheck configured with
+// opts. It returns an error if a rule or allowlist entry is invalid.
+func NewHasNoEmbeddedSecretsCheck(opts EmbeddedSecretsOptions) (*HasNoEmbeddedSecretsCheck, error) {
+	scanner, err := newSecretScanner(
+		configuredList(opts.Rules, opts.AdditionalRules, defaultSecretRules),
+		configuredList(opts.Allowlist, opts.AdditionalAllowlist, defaultSecretAllowlist),
+		opts.MaxFileSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &HasNoEmbeddedSecretsCheck{scanner: scanner}, nil
+}
+
+// HasNoEmbeddedSecretsCheck evaluates that the image filesystem, and the Env and
+// labels of the image config, contain no private keys, credentials or tokens. Findings
+// name the file or variable, and the rule that matched, but never the secret itself.
+type HasNoEmbeddedSecretsCheck struct {
+	scanner *secretScanner
+	report  findings.Report
+}
+
+func (p *HasNoEmbeddedSecretsCheck) Validate(ctx context.Context, imgRef types.ImageReference) (bool, error) {
+	config, err := p.getConfigToValidate(imgRef.ImageInfo)
+	if err != nil {
+		return false, fmt.Errorf("could not get image config: %v", err)
+	}
+
+	return p.validate(ctx, imgRef.ImageFSPath, config, imgRef.ImageInfo)
+}
+
+func (p *HasNoEmbeddedSecretsCheck) getConfigToValidate(image cranev1.Image) (cranev1.Config, error) {
+	if image == nil {
+		return cranev1.Config{}, nil
+	}
+	configFile, err := image.ConfigFile()
+	if err != nil {
+		return cranev1.Config{}, err
+	}
+	return configFile.Config, nil
+}
+
+// validate scans the filesystem at fsPath and config for secrets. Files with
+// findings are attributed to the last layer of image that wrote them.
+func (p *HasNoEmbeddedSecretsCheck) validate(ctx context.Context, fsPath string, config cranev1.Config, image cranev1.Image) (bool, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+	scanner := p.secretScanner()
+
+	fileFindings, scanned, unreadable, err := scanner.scanFilesystem(ctx, fsPath)
+	if err != nil {
+		return false, err
+	}
+
+	if len(fileFindings) > 0 && image != nil {
+		layers, err := layersByPath(image)
+		if err != nil {
+			return false, fmt.Errorf("could not attribute secrets to layers: %v", err)
+		}
+		for i := range fileFindings {
+			fileFindings[i].Layer = layers[fileFindings[i].Subject]
+		}
+	}
+
+	p.report = findings.Report{
+		Inspected: fmt.Sprintf("%d files, %d environment variables and %d labels", scanned, len(config.Env), len(config.Labels)),
+		Findings:  append(fileFindings, scanner.scanConfig(config)...),
+	}
+	if len(unreadable) > 0 {
+		logger.Info(fmt.Sprintf("%d paths could not be read and were not scanned for secrets", len(unreadable)), "paths", unreadable)
+		p.report.Inspected += fmt.Sprintf(", skipping %d paths that could not be read: %s", len(unreadable), strings.Join(unreadable, ", "))
+	}
+	if len(p.report.Findings) > 0 {
+		logger.V(log.DBG).Info("embedded secrets found", "findingCount", len(p.report.Findings))
+		p.report.Remediation = p.Help().Suggestion
+	}
+
+	return len(p.report.Findings) == 0, nil
+}
+
+func (p *HasNoEmbeddedSecretsCheck) secretScanner() *secretScanner {
+	if p.scanner == nil {
+		return defaultSecretScanner
+	}
+	return p.scanner
+}
+
+// scanFilesystem scans the regular files below root, and returns the findings, the
+// number of files scanned, and the paths that could not be read and were skipped.
+func (s *secretScanner) scanFilesystem(ctx context.Context, root string) ([]findings.Finding, int, []string, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	found := []findings.Finding{}
+	scanned := 0
+	var unreadable []string
+	err := filepath.WalkDir(root, func(fullPath string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		rel, relErr := filepath.Rel(root, fullPath)
+		if relErr != nil {
+			return relErr
+		}
+		rel = filepath.ToSlash(rel)
+
+		if err != nil {
+			if d == nil || !errors.Is(err, fs.ErrPermission) {
+				return err
+			}
+			logger.V(log.DBG).Info("could not read path to scan for secrets", "path", rel, "reason", err)
+			unreadable = append(unreadable, rel)
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > s.maxFileSize {
+			logger.V(log.TRC).Info("file too large to scan for secrets", "file", rel, "size", info.Size())
+			return nil
+		}
+
+		fileFindings, err := s.scanFile(fullPath, rel)
+		if errors.Is(err, fs.ErrPermission) {
+			logger.V(log.DBG).Info("could not read file to scan for secrets", "file", rel, "reason", err)
+			unreadable = append(unreadable, rel)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		scanned++
+		found = append(found, fileFindings...)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("could not scan filesystem for secrets: %w", err)
+	}
+
+	return found, scanned, unreadable, nil
+}
+
+// scanFile reports the first line matched by each rule that applies to the file at rel.
+func (s *secretScanner) scanFile(fullPath, rel string) ([]findings.Finding, error) {
+	var rules []compiledSecretRule
+	for _, rule := range s.rules {
+		if rule.appliesToPath(rel) && !s.allowed(rel, rule.ID) {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReaderSize(f, binarySniffBytes)
+	if head, _ := r.Peek(binarySniffBytes); bytes.IndexByte(head, 0) >= 0 {
+		// Binary files are not scanned, as they rarely hold usable secrets and
+		// produce false positives.
+		return nil, nil
+	}
+
+	var found []findings.Finding
+	matched := map[string]bool{}
+	var chunks lineChunker
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 0, 64*1024), maxSecretLineBytes)
+	lines.Split(chunks.split)
+	for lineNumber := 1; lines.Scan(); {
+		// Only the first rule that matches a line is reported, so that specific
+		// rules take precedence over generic ones.
+		for _, rule := range rules {
+			if !rule.matches(lines.Text()) {
+				continue
+			}
+			if !matched[rule.ID] {
+				matched[rule.ID] = true
+				found = append(found, findings.Finding{
+					Kind:    findings.KindFile,
+					Subject: rel,
+					Rule:    rule.ID,
+					Detail:  fmt.Sprintf("%s on line %d", rule.Description, lineNumber),
+				})
+			}
+			break
+		}
+		if !chunks.partial {
+			lineNumber++
+		}
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
+
+// lineChunker splits a file into lines like bufio.ScanLines, except that a line
+// longer than maxSecretLineBytes is split into overlapping chunks.
+type lineChunker struct {
+	// partial is true if the last token is a chunk that does not end its line.
+	partial bool
+}
+
+func (c *lineChunker) split(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if advance > 0 || token != nil || err != nil || len(data) < maxSecretLineBytes {
+		c.partial = false
+		return advance, token, err
+	}
+	c.partial = true
+	return maxSecretLineBytes - secretChunkOverlapBytes, data[:maxSecretLineBytes], nil
+}
+
+// scanConfig reports the variables in Env and the labels that match a rule.
+func (s *secretScanner) scanConfig(config cranev1.Config) []findings.Finding {
+	found := []findings.Finding{}
+	for _, env := range config.Env {
+		name, _, _ := strings.Cut(env, "=")
+		found = append(found, s.scanConfigValue(findings.KindEnv, "env:"+name, name, env)...)
+	}
+
+	labels := make([]string, 0, len(config.Labels))
+	for name := range config.Labels {
+		labels = append(labels, name)
+	}
+	sort.Strings(labels)
+	for _, name := range labels {
+		found = append(found, s.scanConfigValue(findings.KindLabel, "label:"+name, name, name+"="+config.Labels[name])...)
+	}
+
+	return found
+}
+
+// scanConfigValue reports the first rule without Paths that matches value.
+func (s *secretScanner) scanConfigValue(kind findings.Kind, allowPath, name, value string) []findings.Finding {
+	for _, rule := range s.rules {
+		if len(rule.paths) > 0 || s.allowed(allowPath, rule.ID) || !rule.matches(value) {
+			continue
+		}
+		return []findings.Finding{{
+			Kind:    kind,
+			Subject: name,
+			Rule:    rule.ID,
+			Detail:  rule.Description,
+		}}
+	}
+	return nil
+}
+
+// allowed returns true if matches of rule in the file, variable or label at path
+// are exempted by the allowlist.
+func (s *secretScanner) allowed(path, rule string) bool {
+	for _, allow := range s.allowlist {
+		if (allow.rule == "" || allow.rule == rule) && allow.path.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r compiledSecretRule) appliesToPath(path string) bool {
+	if len(r.paths) == 0 {
+		return true
+	}
+	for _, re := range r.paths {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if s holds a secret according to the rule.
+func (r compiledSecretRule) matches(s string) bool {
+	for _, m := range r.pattern.FindAllStringSubmatch(s, -1) {
+		candidate := m[0]
+		if len(m) > 1 && m[1] != "" {
+			candidate = m[1]
+		}
+		if shannonEntropy(candidate) >= r.MinEntropy {
+			return true
+		}
+	}
+	return false
+}
+
+// shannonEntropy returns the entropy of s in bits per character.
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := map[rune]int{}
+	total := 0
+	for _, c := range s {
+		counts[c]++
+		total++
+	}
+	var entropy float64
+	for _, n := range counts {
+		p := float64(n) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// globRegexp compiles a path glob in which "*" matches within a path segment and
+// "**" matches across segments.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	return regexp.Compile(globPattern(glob))
+}
+
+// globPattern returns the regular expression globRegexp compiles for glob.
+func globPattern(glob string) string {
+	glob = strings.TrimPrefix(glob, "/")
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// layersByPath maps each path in image to the digest of the last layer that wrote it.
+func layersByPath(image cranev1.Image) (map[string]string, error) {
+	layers, err := image.Layers()
+	if err != nil {
+		return nil, err
+	}
+
+	byPath := map[string]string{}
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, err
+		}
+
+		rc, err := layer.Uncompressed()
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(rc)
+		for {
+			header, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				rc.Close()
+				return nil, fmt.Errorf("reading layer %s: %w", digest, err)
+			}
+			byPath[normalize(header.Name)] = digest.String()
+		}
+		rc.Close()
+	}
+
+	return byPath, nil
+}
+
+// Report returns the secrets found by the most recent validation.
+func (p *HasNoEmbeddedSecretsCheck) Report() findings.Report {
+	return p.report
+}
+
+func (p *HasNoEmbeddedSecretsCheck) Name() string {
+	return "HasNoEmbeddedSecrets"
+}
+
+func (p *HasNoEmbeddedSecretsCheck) Metadata() types.Metadata {
+	return types.Metadata{
+		Description:      "Checking that the image filesystem, environment and labels do not contain private keys, credentials or tokens.",
+		Level:            "best",
+		KnowledgeBaseURL: certDocumentationURL,
+		CheckURL:         certDocumentationURL,
+	}
+}
+
+func (p *HasNoEmbeddedSecretsCheck) Help() types.HelpText {
+	return types.HelpText{
+		Message:    "Check HasNoEmbeddedSecrets encountered an error. Please review the preflight.log file for more information.",
+		Suggestion: "Remove the reported secrets from the image and revoke them, as they may already have been exposed. Use build secrets or mount credentials at runtime instead of copying them into layers, environment variables or labels.",
+	}
+}
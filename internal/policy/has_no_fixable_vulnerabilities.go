@@ -0,0 +1,199 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/opdev/knex/log"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/advisory"
+	"github.com/opdev/container-certification/internal/findings"
+	"github.com/opdev/container-certification/internal/rpm"
+
+	"github.com/go-logr/logr"
+	rpmdb "github.com/knqyf263/go-rpmdb/pkg"
+)
+
+var (
+	_ types.Check       = &HasNoFixableVulnerabilitiesCheck{}
+	_ findings.Reporter = &HasNoFixableVulnerabilitiesCheck{}
+)
+
+// defaultVulnerabilitySeverities are the severities reported unless the check is
+// configured otherwise.
+var defaultVulnerabilitySeverities = []string{"Important", "Critical"}
+
+// VulnerabilitiesOptions configures a HasNoFixableVulnerabilitiesCheck.
+type VulnerabilitiesOptions struct {
+	// AdvisoryFiles are Red Hat OVAL, CSAF or VEX files, optionally compressed
+	// with bzip2, that describe the fixes to look for.
+	AdvisoryFiles []string `yaml:"advisoryFiles"`
+	// Severities replaces the default severities, Important and Critical, of
+	// the vulnerabilities that are reported.
+	Severities []string `yaml:"severities"`
+}
+
+// NewHasNoFixableVulnerabilitiesCheck returns a HasNoFixableVulnerabilitiesCheck
+// configured with opts. The advisory files are read when the check is first run.
+func NewHasNoFixableVulnerabilitiesCheck(opts VulnerabilitiesOptions) *HasNoFixableVulnerabilitiesCheck {
+	return &HasNoFixableVulnerabilitiesCheck{
+		advisoryFiles: opts.AdvisoryFiles,
+		severities:    configuredList(opts.Severities, nil, defaultVulnerabilitySeverities),
+	}
+}
+
+// HasNoFixableVulnerabilitiesCheck evaluates that no installed rpm package has a
+// vulnerability of a reported severity for which Red Hat has shipped a fix, according
+// to locally provided advisory files. It does not need network access.
+type HasNoFixableVulnerabilitiesCheck struct {
+	advisoryFiles []string
+	severities    []string
+
+	mu     sync.Mutex // guards db
+	db     *advisory.Database
+	report findings.Report
+}
+
+func (p *HasNoFixableVulnerabilitiesCheck) Validate(ctx context.Context, imgRef types.ImageReference) (bool, error) {
+	db, err := p.database(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	pkgList, err := rpm.GetPackageList(ctx, imgRef.ImageFSPath)
+	if err != nil {
+		return false, fmt.Errorf("could not get rpm list: %w", err)
+	}
+
+	return p.validate(ctx, db, pkgList)
+}
+
+// database loads the advisory files, the first time it is called.
+func (p *HasNoFixableVulnerabilitiesCheck) database(ctx context.Context) (*advisory.Database, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.db != nil {
+		return p.db, nil
+	}
+	if len(p.advisoryFiles) == 0 {
+		return nil, fmt.Errorf("no advisory files were provided, set the advisoryFiles param of %s or --advisory-file", p.Name())
+	}
+
+	db, err := advisory.Load(p.advisoryFiles...)
+	if err != nil {
+		return nil, err
+	}
+	logr.FromContextOrDiscard(ctx).V(log.DBG).Info("loaded advisories", "files", p.advisoryFiles, "fixes", db.Len())
+
+	p.db = db
+	return db, nil
+}
+
+func (p *HasNoFixableVulnerabilitiesCheck) validate(ctx context.Context, db *advisory.Database, pkgList []*rpmdb.PackageInfo) (bool, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	p.report = findings.Report{
+		Inspected: fmt.Sprintf("%d installed rpm packages against %d fixes from advisory files", len(pkgList), db.Len()),
+		Findings:  []findings.Finding{},
+	}
+
+	var vulnerable []string
+	for _, pkg := range pkgList {
+		installed := rpm.EVR{Epoch: pkg.EpochNum(), Version: pkg.Version, Release: pkg.Release}
+
+		var fixes []advisory.Fix
+		for _, fix := range db.Fixes(pkg.Name, pkg.Arch, installed) {
+			if p.isReported(fix.Severity) {
+				fixes = append(fixes, fix)
+			}
+		}
+		if len(fixes) == 0 {
+			continue
+		}
+
+		nvra := rpm.NVRA(pkg)
+		logger.V(log.DBG).Info("package has fixable vulnerabilities", "package", nvra, "count", len(fixes))
+		vulnerable = append(vulnerable, pkg.Name)
+		p.report.Findings = append(p.report.Findings, findings.Finding{
+			Kind:    findings.KindPackage,
+			Subject: nvra,
+			Detail:  describeFixes(fixes),
+		})
+	}
+
+	if len(vulnerable) > 0 {
+		p.report.Remediation = "Update the following packages, e.g. with dnf update, and rebuild the image: " + strings.Join(vulnerable, ", ")
+	}
+
+	return len(vulnerable) == 0, nil
+}
+
+// isReported returns true if vulnerabilities of severity are reported.
+func (p *HasNoFixableVulnerabilitiesCheck) isReported(severity string) bool {
+	for _, s := range p.reportedSeverities() {
+		if strings.EqualFold(s, severity) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *HasNoFixableVulnerabilitiesCheck) reportedSeverities() []string {
+	if p.severities == nil {
+		return defaultVulnerabilitySeverities
+	}
+	return p.severities
+}
+
+// describeFixes lists the vulnerabilities fixes address, and the version that fixes
+// all of them, e.g. "CVE-2022-3715 (Important, RHSA-2023:1234); fixed in 0:5.1.8-6.el9_1".
+func describeFixes(fixes []advisory.Fix) string {
+	sort.Slice(fixes, func(i, j int) bool {
+		return fixes[i].CVE < fixes[j].CVE
+	})
+
+	latest := fixes[0].Fixed
+	cves := make([]string, 0, len(fixes))
+	for _, fix := range fixes {
+		if rpm.CompareEVR(fix.Fixed, latest) > 0 {
+			latest = fix.Fixed
+		}
+		about := fix.Severity
+		if fix.Advisory != "" {
+			about += ", " + fix.Advisory
+		}
+		cves = append(cves, fmt.Sprintf("%s (%s)", fix.CVE, about))
+	}
+
+	return fmt.Sprintf("%s; fixed in %s", strings.Join(cves, ", "), latest)
+}
+
+// Report returns the vulnerable packages found by the most recent validation.
+func (p *HasNoFixableVulnerabilitiesCheck) Report() findings.Report {
+	return p.report
+}
+
+func (p *HasNoFixableVulnerabilitiesCheck) Name() string {
+	return "HasNoFixableVulnerabilities"
+}
+
+func (p *HasNoFixableVulnerabilitiesCheck) Metadata() types.Metadata {
+	return types.Metadata{
+		Description:      fmt.Sprintf("Checks that no installed package has a fixable vulnerability rated %s, according to the advisory files provided", strings.Join(p.reportedSeverities(), " or ")),
+		Level:            "best",
+		KnowledgeBaseURL: certDocumentationURL,
+		CheckURL:         certDocumentationURL,
+	}
+}
+
+func (p *HasNoFixableVulnerabilitiesCheck) Help() types.HelpText {
+	return types.HelpText{
+		Message:    "Check HasNoFixableVulnerabilities encountered an error. Please review the preflight.log file for more information.",
+		Suggestion: "Update the packages that have fixable vulnerabilities, e.g. with dnf update, and rebuild the image.",
+	}
+}
@@ -0,0 +1,106 @@
+package policy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	rpmdb "github.com/knqyf263/go-rpmdb/pkg"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/advisory"
+	"github.com/opdev/container-certification/internal/findings"
+)
+
+const vulnerabilityAdvisory = `{
+  "document": {
+    "csaf_version": "2.0",
+    "tracking": {"id": "RHSA-2023:0340"}
+  },
+  "product_tree": {
+    "relationships": [
+      {"full_product_name": {"product_id": "BaseOS-9.1.0.Z.MAIN:bash-0:5.1.8-6.el9_1.x86_64"}, "product_reference": "bash-0:5.1.8-6.el9_1.x86_64"},
+      {"full_product_name": {"product_id": "BaseOS-9.1.0.Z.MAIN:less-0:590-2.el9_1.x86_64"}, "product_reference": "less-0:590-2.el9_1.x86_64"}
+    ]
+  },
+  "vulnerabilities": [
+    {
+      "cve": "CVE-2022-3715",
+      "product_status": {"fixed": ["BaseOS-9.1.0.Z.MAIN:bash-0:5.1.8-6.el9_1.x86_64"]},
+      "threats": [{"category": "impact", "details": "Important"}]
+    },
+    {
+      "cve": "CVE-2022-46663",
+      "product_status": {"fixed": ["BaseOS-9.1.0.Z.MAIN:less-0:590-2.el9_1.x86_64"]},
+      "threats": [{"category": "impact", "details": "Low"}]
+    }
+  ]
+}`
+
+var _ = Describe("HasNoFixableVulnerabilities", func() {
+	var (
+		db      *advisory.Database
+		pkgList []*rpmdb.PackageInfo
+	)
+
+	BeforeEach(func() {
+		path := filepath.Join(GinkgoT().TempDir(), "rhsa-2023_0340.json")
+		Expect(os.WriteFile(path, []byte(vulnerabilityAdvisory), 0o644)).To(Succeed())
+
+		var err error
+		db, err = advisory.Load(path)
+		Expect(err).ToNot(HaveOccurred())
+
+		pkgList = []*rpmdb.PackageInfo{
+			{Name: "bash", Version: "5.1.8", Release: "5.el9", Arch: "x86_64"},
+			{Name: "less", Version: "590", Release: "1.el9_0", Arch: "x86_64"},
+		}
+	})
+
+	Context("When installed packages have fixable vulnerabilities", func() {
+		It("should report the packages with an Important or Critical vulnerability", func() {
+			check := NewHasNoFixableVulnerabilitiesCheck(VulnerabilitiesOptions{})
+			passed, err := check.validate(context.TODO(), db, pkgList)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(passed).To(BeFalse())
+			Expect(check.Report().Findings).To(ConsistOf(findings.Finding{
+				Kind:    findings.KindPackage,
+				Subject: "bash-5.1.8-5.el9.x86_64",
+				Detail:  "CVE-2022-3715 (Important, RHSA-2023:0340); fixed in 0:5.1.8-6.el9_1",
+			}))
+			Expect(check.Report().Remediation).To(ContainSubstring("bash"))
+		})
+		It("should report the configured severities", func() {
+			check := NewHasNoFixableVulnerabilitiesCheck(VulnerabilitiesOptions{Severities: []string{"low"}})
+			passed, err := check.validate(context.TODO(), db, pkgList)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(passed).To(BeFalse())
+			Expect(check.Report().Findings).To(HaveLen(1))
+			Expect(check.Report().Findings[0].Subject).To(Equal("less-590-1.el9_0.x86_64"))
+		})
+	})
+
+	Context("When installed packages are up to date", func() {
+		It("should pass", func() {
+			pkgList[0].Release = "6.el9_1"
+			check := NewHasNoFixableVulnerabilitiesCheck(VulnerabilitiesOptions{})
+			passed, err := check.validate(context.TODO(), db, pkgList)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(passed).To(BeTrue())
+			Expect(check.Report().Findings).To(BeEmpty())
+		})
+	})
+
+	Context("When no advisory files are provided", func() {
+		It("should return an error", func() {
+			check := NewHasNoFixableVulnerabilitiesCheck(VulnerabilitiesOptions{})
+			passed, err := check.Validate(context.TODO(), types.ImageReference{})
+			Expect(err).To(MatchError(ContainSubstring("no advisory files were provided")))
+			Expect(passed).To(BeFalse())
+		})
+	})
+
+	AssertMetaData(&HasNoFixableVulnerabilitiesCheck{})
+})
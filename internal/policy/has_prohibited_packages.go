@@ -8,16 +8,59 @@ import (
 	"github.com/opdev/knex/log"
 	"github.com/opdev/knex/types"
 
+	"github.com/opdev/container-certification/internal/findings"
 	"github.com/opdev/container-certification/internal/rpm"
 
 	"github.com/go-logr/logr"
+	rpmdb "github.com/knqyf263/go-rpmdb/pkg"
 )
 
-var _ types.Check = &HasNoProhibitedPackagesCheck{}
+var (
+	_ types.Check       = &HasNoProhibitedPackagesCheck{}
+	_ findings.Reporter = &HasNoProhibitedPackagesCheck{}
+)
+
+// ProhibitedPackagesOptions configures a HasNoProhibitedPackagesCheck.
+type ProhibitedPackagesOptions struct {
+	// Packages replaces the default list of prohibited package names.
+	Packages []string `yaml:"packages"`
+	// AdditionalPackages are prohibited in addition to Packages.
+	AdditionalPackages []string `yaml:"additionalPackages"`
+	// PackagePrefixes replaces the default list of prefixes. Any package whose
+	// name starts with one of them is prohibited.
+	PackagePrefixes []string `yaml:"packagePrefixes"`
+	// AdditionalPackagePrefixes are prohibited in addition to PackagePrefixes.
+	AdditionalPackagePrefixes []string `yaml:"additionalPackagePrefixes"`
+}
+
+// NewHasNoProhibitedPackagesCheck returns a HasNoProhibitedPackagesCheck configured with opts.
+func NewHasNoProhibitedPackagesCheck(opts ProhibitedPackagesOptions) *HasNoProhibitedPackagesCheck {
+	packages := make(map[string]struct{}, len(prohibitedPackageList)+len(opts.AdditionalPackages))
+	if opts.Packages == nil {
+		for name := range prohibitedPackageList {
+			packages[name] = struct{}{}
+		}
+	}
+	for _, name := range opts.Packages {
+		packages[name] = struct{}{}
+	}
+	for _, name := range opts.AdditionalPackages {
+		packages[name] = struct{}{}
+	}
+
+	return &HasNoProhibitedPackagesCheck{
+		packages: packages,
+		prefixes: configuredList(opts.PackagePrefixes, opts.AdditionalPackagePrefixes, prohibitedPackageGlobList),
+	}
+}
 
 // HasProhibitedPackages evaluates that the image does not contain prohibited packages,
 // which refers to packages that are not redistributable without an appropriate license.
-type HasNoProhibitedPackagesCheck struct{}
+type HasNoProhibitedPackagesCheck struct {
+	packages map[string]struct{}
+	prefixes []string
+	report   findings.Report
+}
 
 func (p *HasNoProhibitedPackagesCheck) Validate(ctx context.Context, imgRef types.ImageReference) (bool, error) {
 	pkgList, err := p.getDataToValidate(ctx, imgRef.ImageFSPath)
@@ -28,44 +71,72 @@ func (p *HasNoProhibitedPackagesCheck) Validate(ctx context.Context, imgRef type
 	return p.validate(ctx, pkgList)
 }
 
-func (p *HasNoProhibitedPackagesCheck) getDataToValidate(ctx context.Context, dir string) ([]string, error) {
+func (p *HasNoProhibitedPackagesCheck) getDataToValidate(ctx context.Context, dir string) ([]*rpmdb.PackageInfo, error) {
 	pkgList, err := rpm.GetPackageList(ctx, dir)
 	if err != nil {
 		return nil, fmt.Errorf("could not get rpm list: %w", err)
 	}
-	pkgs := make([]string, 0, len(pkgList))
-	for _, pkg := range pkgList {
-		pkgs = append(pkgs, pkg.Name)
-	}
-	return pkgs, nil
+	return pkgList, nil
 }
 
 //nolint:unparam // ctx is unused. Keep for future use.
-func (p *HasNoProhibitedPackagesCheck) validate(ctx context.Context, pkgList []string) (bool, error) {
+func (p *HasNoProhibitedPackagesCheck) validate(ctx context.Context, pkgList []*rpmdb.PackageInfo) (bool, error) {
 	logger := logr.FromContextOrDiscard(ctx)
 
+	p.report = findings.Report{
+		Inspected: fmt.Sprintf("%d installed rpm packages", len(pkgList)),
+		Findings:  []findings.Finding{},
+	}
+
 	var prohibitedPackages []string
 	for _, pkg := range pkgList {
-		_, ok := prohibitedPackageList[pkg]
-		if ok {
-			prohibitedPackages = append(prohibitedPackages, pkg)
+		if !p.isProhibitedPackage(pkg.Name) {
 			continue
 		}
-		for _, name := range prohibitedPackageGlobList {
-			if strings.HasPrefix(pkg, name) {
-				prohibitedPackages = append(prohibitedPackages, pkg)
-				continue
-			}
-		}
+
+		nvra := fmt.Sprintf("%s-%s-%s.%s", pkg.Name, pkg.Version, pkg.Release, pkg.Arch)
+		prohibitedPackages = append(prohibitedPackages, nvra)
+		p.report.Findings = append(p.report.Findings, findings.Finding{
+			Kind:    findings.KindPackage,
+			Subject: nvra,
+			Detail:  "package is not redistributable outside of UBI",
+		})
 	}
 
 	if len(prohibitedPackages) > 0 {
 		logger.V(log.DBG).Info("prohibited packages found", "packageCount", len(prohibitedPackages), "packageList", prohibitedPackages)
+		p.report.Remediation = "Remove the following packages from the image: " + strings.Join(prohibitedPackages, ", ")
 	}
 
 	return len(prohibitedPackages) == 0, nil
 }
 
+// isProhibitedPackage returns true if the package named name may not be redistributed.
+func (p *HasNoProhibitedPackagesCheck) isProhibitedPackage(name string) bool {
+	packages, prefixes := p.packages, p.prefixes
+	if packages == nil {
+		packages = prohibitedPackageList
+	}
+	if prefixes == nil {
+		prefixes = prohibitedPackageGlobList
+	}
+
+	if _, ok := packages[name]; ok {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Report returns the prohibited packages found by the most recent validation.
+func (p *HasNoProhibitedPackagesCheck) Report() findings.Report {
+	return p.report
+}
+
 func (p *HasNoProhibitedPackagesCheck) Name() string {
 	return "HasNoProhibitedPackages"
 }
@@ -3,23 +3,35 @@ package policy
 import (
 	"context"
 
+	rpmdb "github.com/knqyf263/go-rpmdb/pkg"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+
+	"github.com/opdev/container-certification/internal/findings"
 )
 
+// packagesNamed returns installed package records for names.
+func packagesNamed(names ...string) []*rpmdb.PackageInfo {
+	pkgs := make([]*rpmdb.PackageInfo, 0, len(names))
+	for _, name := range names {
+		pkgs = append(pkgs, &rpmdb.PackageInfo{Name: name, Version: "1.0", Release: "1.el9", Arch: "x86_64"})
+	}
+	return pkgs
+}
+
 var _ = Describe("HasNoProhibitedPackages", func() {
 	var (
 		hasNoProhibitedPackages HasNoProhibitedPackagesCheck
-		pkgList                 []string
+		pkgList                 []*rpmdb.PackageInfo
 	)
 
 	BeforeEach(func() {
-		pkgList = []string{
+		pkgList = packagesNamed(
 			"this",
 			"is",
 			"not",
 			"prohibited",
-		}
+		)
 	})
 
 	AssertMetaData(&hasNoProhibitedPackages)
@@ -33,20 +45,29 @@ var _ = Describe("HasNoProhibitedPackages", func() {
 			})
 		})
 		Context("When there was a prohibited packages found", func() {
-			var pkgs []string
+			var pkgs []*rpmdb.PackageInfo
 			BeforeEach(func() {
-				pkgs = append(pkgList, "grub")
+				pkgs = append(pkgList, packagesNamed("grub")...)
 			})
 			It("should not pass Validate", func() {
 				ok, err := hasNoProhibitedPackages.validate(context.TODO(), pkgs)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(ok).To(BeFalse())
 			})
+			It("should report the package NVRA", func() {
+				_, err := hasNoProhibitedPackages.validate(context.TODO(), pkgs)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(hasNoProhibitedPackages.Report().Findings).To(ConsistOf(findings.Finding{
+					Kind:    findings.KindPackage,
+					Subject: "grub-1.0-1.el9.x86_64",
+					Detail:  "package is not redistributable outside of UBI",
+				}))
+			})
 		})
 		Context("When there is a prohibited package in the glob list found", func() {
-			var pkgs []string
+			var pkgs []*rpmdb.PackageInfo
 			BeforeEach(func() {
-				pkgs = append(pkgList, "kpatch2121")
+				pkgs = append(pkgList, packagesNamed("kpatch2121")...)
 			})
 			It("should not pass Validate", func() {
 				ok, err := hasNoProhibitedPackages.validate(context.TODO(), pkgs)
@@ -54,5 +75,35 @@ var _ = Describe("HasNoProhibitedPackages", func() {
 				Expect(ok).To(BeFalse())
 			})
 		})
+		Context("When additional packages are prohibited", func() {
+			var check *HasNoProhibitedPackagesCheck
+			BeforeEach(func() {
+				check = NewHasNoProhibitedPackagesCheck(ProhibitedPackagesOptions{
+					AdditionalPackages:        []string{"not"},
+					AdditionalPackagePrefixes: []string{"prohib"},
+				})
+			})
+			It("should report them along with the default packages", func() {
+				ok, err := check.validate(context.TODO(), append(pkgList, packagesNamed("grub")...))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeFalse())
+				Expect(check.Report().Findings).To(HaveLen(3))
+			})
+		})
+		Context("When the prohibited packages are replaced", func() {
+			It("should no longer prohibit the default packages", func() {
+				check := NewHasNoProhibitedPackagesCheck(ProhibitedPackagesOptions{
+					Packages:        []string{"this"},
+					PackagePrefixes: []string{},
+				})
+				ok, err := check.validate(context.TODO(), packagesNamed("grub", "kpatch2121"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+
+				ok, err = check.validate(context.TODO(), pkgList)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeFalse())
+			})
+		})
 	})
 })
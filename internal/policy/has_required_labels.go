@@ -3,21 +3,45 @@ package policy
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/opdev/knex/types"
 
+	"github.com/opdev/container-certification/internal/findings"
+
 	"github.com/go-logr/logr"
 	cranev1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/opdev/knex/log"
 )
 
-var requiredLabels = []string{"name", "vendor", "version", "release", "summary", "description"}
+var defaultRequiredLabels = []string{"name", "vendor", "version", "release", "summary", "description"}
+
+var (
+	_ types.Check       = &HasRequiredLabelsCheck{}
+	_ findings.Reporter = &HasRequiredLabelsCheck{}
+)
+
+// RequiredLabelsOptions configures a HasRequiredLabelsCheck.
+type RequiredLabelsOptions struct {
+	// Labels replaces the default list of required labels.
+	Labels []string `yaml:"labels"`
+	// AdditionalLabels are required in addition to Labels.
+	AdditionalLabels []string `yaml:"additionalLabels"`
+}
 
-var _ types.Check = &HasRequiredLabelsCheck{}
+// NewHasRequiredLabelsCheck returns a HasRequiredLabelsCheck configured with opts.
+func NewHasRequiredLabelsCheck(opts RequiredLabelsOptions) *HasRequiredLabelsCheck {
+	return &HasRequiredLabelsCheck{
+		labels: configuredList(opts.Labels, opts.AdditionalLabels, defaultRequiredLabels),
+	}
+}
 
 // HasRequiredLabelsCheck evaluates the image manifest to ensure that the appropriate metadata
 // labels are present on the image asset as it exists in its current container registry.
-type HasRequiredLabelsCheck struct{}
+type HasRequiredLabelsCheck struct {
+	labels []string
+	report findings.Report
+}
 
 func (p *HasRequiredLabelsCheck) Validate(ctx context.Context, imgRef types.ImageReference) (bool, error) {
 	labels, err := p.getDataForValidate(imgRef.ImageInfo)
@@ -36,6 +60,7 @@ func (p *HasRequiredLabelsCheck) getDataForValidate(image cranev1.Image) (map[st
 func (p *HasRequiredLabelsCheck) validate(ctx context.Context, labels map[string]string) (bool, error) {
 	logger := logr.FromContextOrDiscard(ctx)
 
+	requiredLabels := p.requiredLabels()
 	missingLabels := []string{}
 	for _, label := range requiredLabels {
 		if labels[label] == "" {
@@ -43,21 +68,45 @@ func (p *HasRequiredLabelsCheck) validate(ctx context.Context, labels map[string
 		}
 	}
 
-	// TODO: We should be reporting this in the results, not in a log message
+	p.report = findings.Report{
+		Inspected: "image labels: " + strings.Join(requiredLabels, ", "),
+		Findings:  make([]findings.Finding, 0, len(missingLabels)),
+	}
 	if len(missingLabels) > 0 {
 		logger.V(log.DBG).Info("expected labels are missing", "missingLabels", missingLabels)
+		p.report.Remediation = "Add the following labels to your Dockerfile or Containerfile: " + strings.Join(missingLabels, ", ")
+	}
+	for _, label := range missingLabels {
+		p.report.Findings = append(p.report.Findings, findings.Finding{
+			Kind:    findings.KindLabel,
+			Subject: label,
+			Detail:  "required label is missing or empty",
+		})
 	}
 
 	return len(missingLabels) == 0, nil
 }
 
+// Report returns the labels found to be missing by the most recent validation.
+func (p *HasRequiredLabelsCheck) Report() findings.Report {
+	return p.report
+}
+
+// requiredLabels returns the configured labels, or the default labels if none were set.
+func (p *HasRequiredLabelsCheck) requiredLabels() []string {
+	if p.labels == nil {
+		return defaultRequiredLabels
+	}
+	return p.labels
+}
+
 func (p *HasRequiredLabelsCheck) Name() string {
 	return "HasRequiredLabel"
 }
 
 func (p *HasRequiredLabelsCheck) Metadata() types.Metadata {
 	return types.Metadata{
-		Description:      "Checking if the required labels (name, vendor, version, release, summary, description) are present in the container metadata.",
+		Description:      fmt.Sprintf("Checking if the required labels (%s) are present in the container metadata.", strings.Join(p.requiredLabels(), ", ")),
 		Level:            "good",
 		KnowledgeBaseURL: certDocumentationURL,
 		CheckURL:         certDocumentationURL,
@@ -67,6 +116,6 @@ func (p *HasRequiredLabelsCheck) Metadata() types.Metadata {
 func (p *HasRequiredLabelsCheck) Help() types.HelpText {
 	return types.HelpText{
 		Message:    "Check Check HasRequiredLabel encountered an error. Please review the preflight.log file for more information.",
-		Suggestion: "Add the following labels to your Dockerfile or Containerfile: name, vendor, version, release, summary, description",
+		Suggestion: "Add the following labels to your Dockerfile or Containerfile: " + strings.Join(p.requiredLabels(), ", "),
 	}
 }
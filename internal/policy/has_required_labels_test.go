@@ -8,6 +8,8 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
 )
 
 func getLabels(bad bool) map[string]string {
@@ -76,6 +78,40 @@ var _ = Describe("HasRequiredLabels", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(ok).To(BeFalse())
 			})
+			It("should report the missing label", func() {
+				_, err := hasRequiredLabelsCheck.Validate(context.TODO(), imageRef)
+				Expect(err).ToNot(HaveOccurred())
+				report := hasRequiredLabelsCheck.Report()
+				Expect(report.Findings).To(ConsistOf(findings.Finding{
+					Kind:    findings.KindLabel,
+					Subject: "description",
+					Detail:  "required label is missing or empty",
+				}))
+				Expect(report.Remediation).To(ContainSubstring("description"))
+			})
+		})
+		Context("When additional labels are required", func() {
+			It("should report the additional label if it is missing", func() {
+				check := NewHasRequiredLabelsCheck(RequiredLabelsOptions{AdditionalLabels: []string{"maintainer"}})
+				ok, err := check.Validate(context.TODO(), imageRef)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeFalse())
+				Expect(check.Report().Findings).To(HaveLen(1))
+				Expect(check.Report().Findings[0].Subject).To(Equal("maintainer"))
+				Expect(check.Help().Suggestion).To(HaveSuffix("description, maintainer"))
+			})
+		})
+		Context("When the required labels are replaced", func() {
+			It("should only require the configured labels", func() {
+				fakeImage := fakecranev1.FakeImage{
+					ConfigFileStub: getBadConfigFile,
+				}
+				imageRef.ImageInfo = &fakeImage
+				check := NewHasRequiredLabelsCheck(RequiredLabelsOptions{Labels: []string{"name", "vendor"}})
+				ok, err := check.Validate(context.TODO(), imageRef)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeTrue())
+			})
 		})
 	})
 
@@ -0,0 +1,153 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/opdev/knex/log"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
+	"github.com/opdev/container-certification/internal/rpm"
+
+	"github.com/go-logr/logr"
+	rpmdb "github.com/knqyf263/go-rpmdb/pkg"
+)
+
+var (
+	_ types.Check       = &HasTrustedPackageSignaturesCheck{}
+	_ findings.Reporter = &HasTrustedPackageSignaturesCheck{}
+)
+
+// redHatVendor is the vendor of packages built by Red Hat.
+const redHatVendor = "Red Hat, Inc."
+
+// redHatReleaseKeys are the IDs of the keys Red Hat signs released packages with:
+// release key 2 and auxiliary key 2.
+var redHatReleaseKeys = []string{
+	"199e2f91fd431d51",
+	"5054e4a45a6340b3",
+}
+
+// PackageSignaturesOptions configures a HasTrustedPackageSignaturesCheck.
+type PackageSignaturesOptions struct {
+	// TrustedKeys replaces the default list of trusted key IDs, the Red Hat release
+	// keys. Both long (16 hex digits) and short (8 hex digits) key IDs are accepted.
+	TrustedKeys []string `yaml:"trustedKeys"`
+	// AdditionalTrustedKeys are trusted in addition to TrustedKeys.
+	AdditionalTrustedKeys []string `yaml:"additionalTrustedKeys"`
+}
+
+// NewHasTrustedPackageSignaturesCheck returns a HasTrustedPackageSignaturesCheck
+// configured with opts.
+func NewHasTrustedPackageSignaturesCheck(opts PackageSignaturesOptions) *HasTrustedPackageSignaturesCheck {
+	return &HasTrustedPackageSignaturesCheck{
+		trustedKeys: configuredList(opts.TrustedKeys, opts.AdditionalTrustedKeys, redHatReleaseKeys),
+	}
+}
+
+// HasTrustedPackageSignaturesCheck evaluates that every installed package is signed,
+// and that packages claiming to be built by Red Hat are signed by a trusted key. This
+// catches Red Hat packages that were rebuilt or tampered with, which HasModifiedFiles
+// cannot tell from the originals.
+type HasTrustedPackageSignaturesCheck struct {
+	trustedKeys []string
+	report      findings.Report
+}
+
+func (p *HasTrustedPackageSignaturesCheck) Validate(ctx context.Context, imgRef types.ImageReference) (bool, error) {
+	pkgList, err := rpm.GetPackageList(ctx, imgRef.ImageFSPath)
+	if err != nil {
+		return false, fmt.Errorf("could not get rpm list: %w", err)
+	}
+
+	return p.validate(ctx, pkgList), nil
+}
+
+func (p *HasTrustedPackageSignaturesCheck) validate(ctx context.Context, pkgList []*rpmdb.PackageInfo) bool {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	p.report = findings.Report{
+		Inspected: fmt.Sprintf("signatures of %d installed rpm packages", len(pkgList)),
+		Findings:  []findings.Finding{},
+	}
+
+	var untrusted []string
+	for _, pkg := range pkgList {
+		// The public keys imported into the rpm database are listed as
+		// gpg-pubkey packages, which are never signed.
+		if pkg.Name == "gpg-pubkey" {
+			continue
+		}
+
+		var detail string
+		switch keyID := rpm.PGPKeyID(pkg.PGP); {
+		case keyID == "":
+			detail = "package is not signed"
+		case pkg.Vendor == redHatVendor && !p.isTrusted(keyID):
+			detail = fmt.Sprintf("package claims vendor %s but is signed with untrusted key %s", redHatVendor, keyID)
+		default:
+			continue
+		}
+
+		nvra := rpm.NVRA(pkg)
+		untrusted = append(untrusted, nvra)
+		p.report.Findings = append(p.report.Findings, findings.Finding{
+			Kind:    findings.KindPackage,
+			Subject: nvra,
+			Detail:  detail,
+		})
+	}
+
+	if len(untrusted) > 0 {
+		logger.V(log.DBG).Info("packages without a trusted signature found", "packageCount", len(untrusted), "packageList", untrusted)
+		p.report.Remediation = "Install signed packages from their original repositories in place of the following packages: " + strings.Join(untrusted, ", ")
+	}
+
+	return len(untrusted) == 0
+}
+
+// isTrusted returns true if keyID is one of the trusted keys. A trusted short key
+// ID matches the long key IDs that end with it.
+func (p *HasTrustedPackageSignaturesCheck) isTrusted(keyID string) bool {
+	trustedKeys := p.trustedKeys
+	if trustedKeys == nil {
+		trustedKeys = redHatReleaseKeys
+	}
+
+	keyID = strings.ToLower(keyID)
+	for _, trusted := range trustedKeys {
+		trusted = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(trusted), "0x"))
+		if trusted != "" && strings.HasSuffix(keyID, trusted) {
+			return true
+		}
+	}
+	return false
+}
+
+// Report returns the packages without a trusted signature found by the most
+// recent validation.
+func (p *HasTrustedPackageSignaturesCheck) Report() findings.Report {
+	return p.report
+}
+
+func (p *HasTrustedPackageSignaturesCheck) Name() string {
+	return "HasTrustedPackageSignatures"
+}
+
+func (p *HasTrustedPackageSignaturesCheck) Metadata() types.Metadata {
+	return types.Metadata{
+		Description:      "Checks that every installed package is signed, and that packages from Red Hat are signed with a trusted Red Hat key.",
+		Level:            "best",
+		KnowledgeBaseURL: certDocumentationURL,
+		CheckURL:         certDocumentationURL,
+	}
+}
+
+func (p *HasTrustedPackageSignaturesCheck) Help() types.HelpText {
+	return types.HelpText{
+		Message:    "Check HasTrustedPackageSignatures encountered an error. Please review the preflight.log file for more information.",
+		Suggestion: "Install packages from their original, signed repositories instead of rebuilding or modifying them.",
+	}
+}
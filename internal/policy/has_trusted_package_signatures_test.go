@@ -0,0 +1,85 @@
+package policy
+
+import (
+	"context"
+
+	rpmdb "github.com/knqyf263/go-rpmdb/pkg"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/opdev/container-certification/internal/findings"
+)
+
+var _ = Describe("HasTrustedPackageSignatures", func() {
+	const (
+		redHatSignature = "RSA/SHA256, Mon Aug 16 11:48:42 2021, Key ID 199e2f91fd431d51"
+		otherSignature  = "RSA/SHA256, Tue Jan 10 09:12:00 2023, Key ID 0123456789abcdef"
+	)
+
+	var (
+		hasTrustedPackageSignatures HasTrustedPackageSignaturesCheck
+		pkgList                     []*rpmdb.PackageInfo
+	)
+
+	BeforeEach(func() {
+		pkgList = []*rpmdb.PackageInfo{
+			{Name: "bash", Version: "5.1.8", Release: "6.el9_1", Arch: "x86_64", Vendor: redHatVendor, PGP: redHatSignature},
+			{Name: "nginx", Version: "1.24.0", Release: "1.el9", Arch: "x86_64", Vendor: "nginx inc.", PGP: otherSignature},
+			{Name: "gpg-pubkey", Version: "fd431d51", Release: "4ae0493b"},
+		}
+	})
+
+	AssertMetaData(&hasTrustedPackageSignatures)
+
+	Context("When every package has a trusted signature", func() {
+		It("should pass Validate", func() {
+			ok := hasTrustedPackageSignatures.validate(context.TODO(), pkgList)
+			Expect(ok).To(BeTrue())
+			Expect(hasTrustedPackageSignatures.Report().Findings).To(BeEmpty())
+		})
+	})
+
+	Context("When a package is not signed", func() {
+		It("should report the package", func() {
+			pkgList = append(pkgList, &rpmdb.PackageInfo{Name: "tool", Version: "1.0", Release: "1", Arch: "x86_64"})
+			ok := hasTrustedPackageSignatures.validate(context.TODO(), pkgList)
+			Expect(ok).To(BeFalse())
+			Expect(hasTrustedPackageSignatures.Report().Findings).To(ConsistOf(findings.Finding{
+				Kind:    findings.KindPackage,
+				Subject: "tool-1.0-1.x86_64",
+				Detail:  "package is not signed",
+			}))
+		})
+	})
+
+	Context("When a Red Hat package is signed with an unknown key", func() {
+		BeforeEach(func() {
+			pkgList[0].PGP = otherSignature
+		})
+		It("should report the package", func() {
+			ok := hasTrustedPackageSignatures.validate(context.TODO(), pkgList)
+			Expect(ok).To(BeFalse())
+			Expect(hasTrustedPackageSignatures.Report().Findings).To(ConsistOf(findings.Finding{
+				Kind:    findings.KindPackage,
+				Subject: "bash-5.1.8-6.el9_1.x86_64",
+				Detail:  "package claims vendor Red Hat, Inc. but is signed with untrusted key 0123456789abcdef",
+			}))
+			Expect(hasTrustedPackageSignatures.Report().Remediation).To(ContainSubstring("bash-5.1.8-6.el9_1.x86_64"))
+		})
+		It("should pass when the key is trusted by its short ID", func() {
+			check := NewHasTrustedPackageSignaturesCheck(PackageSignaturesOptions{AdditionalTrustedKeys: []string{"89ABCDEF"}})
+			ok := check.validate(context.TODO(), pkgList)
+			Expect(ok).To(BeTrue())
+		})
+	})
+
+	Context("When the trusted keys are replaced", func() {
+		It("should no longer trust the Red Hat release keys", func() {
+			check := NewHasTrustedPackageSignaturesCheck(PackageSignaturesOptions{TrustedKeys: []string{"0123456789abcdef"}})
+			ok := check.validate(context.TODO(), pkgList)
+			Expect(ok).To(BeFalse())
+			Expect(check.Report().Findings).To(HaveLen(1))
+			Expect(check.Report().Findings[0].Subject).To(Equal("bash-5.1.8-6.el9_1.x86_64"))
+		})
+	})
+})
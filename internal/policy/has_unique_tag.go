@@ -8,15 +8,19 @@ import (
 	"github.com/opdev/knex/types"
 
 	"github.com/opdev/container-certification/internal/authn"
+	"github.com/opdev/container-certification/internal/transport"
 
 	"github.com/google/go-containerregistry/pkg/crane"
 )
 
 var _ types.Check = &hasUniqueTagCheck{}
 
-func NewHasUniqueTagCheck(dockercfg string) *hasUniqueTagCheck {
+// NewHasUniqueTagCheck returns a check that lists tags using the credentials in
+// dockercfg, connecting to the registry as configured by registry.
+func NewHasUniqueTagCheck(dockercfg string, registry transport.Options) *hasUniqueTagCheck {
 	return &hasUniqueTagCheck{
 		dockercfg: dockercfg,
+		registry:  registry,
 	}
 }
 
@@ -25,6 +29,7 @@ func NewHasUniqueTagCheck(dockercfg string) *hasUniqueTagCheck {
 // represent the same image over time.
 type hasUniqueTagCheck struct {
 	dockercfg string
+	registry  transport.Options
 }
 
 func (p *hasUniqueTagCheck) Validate(ctx context.Context, imgRef types.ImageReference) (bool, error) {
@@ -60,6 +65,12 @@ func (p *hasUniqueTagCheck) getDataToValidate(ctx context.Context, image string)
 		crane.WithAuthFromKeychain(authn.PreflightKeychain(ctx, authn.WithDockerConfig(p.dockercfg))),
 	}
 
+	registryOptions, err := p.registry.CraneOptions()
+	if err != nil {
+		return nil, err
+	}
+	options = append(options, registryOptions...)
+
 	return crane.ListTags(image, options...)
 }
 
@@ -71,6 +82,18 @@ func (p *hasUniqueTagCheck) validate(tags []string) (bool, error) {
 	return len(tags) > 1 || len(tags) == 1 && strings.ToLower(tags[0]) != "latest", nil
 }
 
+// RequiresRegistry indicates that tags can only be listed from the image's registry,
+// so this check does not apply to images loaded from a local layout or archive.
+func (p *hasUniqueTagCheck) RequiresRegistry() bool {
+	return true
+}
+
+// RequiresSerialExecution prevents this check from running concurrently with
+// others, as it reconfigures the shared PreflightKeychain.
+func (p *hasUniqueTagCheck) RequiresSerialExecution() bool {
+	return true
+}
+
 func (p *hasUniqueTagCheck) Name() string {
 	return "HasUniqueTag"
 }
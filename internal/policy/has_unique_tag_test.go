@@ -17,10 +17,12 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/transport"
 )
 
 var _ = Describe("UniqueTag", func() {
-	hasUniqueTagCheck := *NewHasUniqueTagCheck("")
+	hasUniqueTagCheck := *NewHasUniqueTagCheck("", transport.Options{})
 	var src, dst, host string
 
 	BeforeEach(func() {
@@ -17,8 +17,22 @@ const (
 
 var _ types.Check = &MaxLayersCheck{}
 
+// MaxLayersOptions configures a MaxLayersCheck.
+type MaxLayersOptions struct {
+	// MaxLayers is the most layers an image may have. Values below 1 use the
+	// default of 40.
+	MaxLayers int `yaml:"maxLayers"`
+}
+
+// NewMaxLayersCheck returns a MaxLayersCheck configured with opts.
+func NewMaxLayersCheck(opts MaxLayersOptions) *MaxLayersCheck {
+	return &MaxLayersCheck{maxLayers: opts.MaxLayers}
+}
+
 // UnderLayerMaxCheck ensures that the image has less layers in its assembly than a predefined maximum.
-type MaxLayersCheck struct{}
+type MaxLayersCheck struct {
+	maxLayers int
+}
 
 func (p *MaxLayersCheck) Validate(ctx context.Context, imgRef types.ImageReference) (bool, error) {
 	layers, err := p.getDataToValidate(imgRef.ImageInfo)
@@ -34,8 +48,16 @@ func (p *MaxLayersCheck) getDataToValidate(image cranev1.Image) ([]cranev1.Layer
 }
 
 func (p *MaxLayersCheck) validate(ctx context.Context, layers []cranev1.Layer) (bool, error) {
-	logr.FromContextOrDiscard(ctx).V(log.DBG).Info("number of layers detected in image", "layerCount", len(layers))
-	return len(layers) <= acceptableLayerMax, nil
+	logr.FromContextOrDiscard(ctx).V(log.DBG).Info("number of layers detected in image", "layerCount", len(layers), "layerMax", p.layerMax())
+	return len(layers) <= p.layerMax(), nil
+}
+
+// layerMax returns the configured maximum, or acceptableLayerMax if none was set.
+func (p *MaxLayersCheck) layerMax() int {
+	if p.maxLayers < 1 {
+		return acceptableLayerMax
+	}
+	return p.maxLayers
 }
 
 func (p *MaxLayersCheck) Name() string {
@@ -44,7 +66,7 @@ func (p *MaxLayersCheck) Name() string {
 
 func (p *MaxLayersCheck) Metadata() types.Metadata {
 	return types.Metadata{
-		Description:      fmt.Sprintf("Checking if container has less than %d layers.  Too many layers within the container images can degrade container performance.", acceptableLayerMax),
+		Description:      fmt.Sprintf("Checking if container has less than %d layers.  Too many layers within the container images can degrade container performance.", p.layerMax()),
 		Level:            "better",
 		KnowledgeBaseURL: certDocumentationURL,
 		CheckURL:         certDocumentationURL,
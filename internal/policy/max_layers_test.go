@@ -60,6 +60,15 @@ var _ = Describe("LessThanMaxLayers", func() {
 				Expect(ok).To(BeFalse())
 			})
 		})
+		Context("When a lower maximum is configured", func() {
+			It("should fail an image over that maximum", func() {
+				check := NewMaxLayersCheck(MaxLayersOptions{MaxLayers: 4})
+				ok, err := check.Validate(context.TODO(), imgRef)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(ok).To(BeFalse())
+				Expect(check.Metadata().Description).To(ContainSubstring("less than 4 layers"))
+			})
+		})
 	})
 
 	AssertMetaData(&maxLayersCheck)
@@ -0,0 +1,184 @@
+package policy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// passwdEntry is a user from an image's /etc/passwd.
+type passwdEntry struct {
+	name string
+	uid  int
+	gid  int
+	home string
+}
+
+// groupEntry is a group from an image's /etc/group.
+type groupEntry struct {
+	name string
+	gid  int
+}
+
+// readPasswd returns the users in /etc/passwd of the image filesystem at root.
+func readPasswd(root string) ([]passwdEntry, error) {
+	var users []passwdEntry
+	err := readColonFile(filepath.Join(root, "etc", "passwd"), func(fields []string) {
+		// name:password:uid:gid:gecos:home:shell
+		if len(fields) < 6 {
+			return
+		}
+		uid, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return
+		}
+		gid, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return
+		}
+		users = append(users, passwdEntry{name: fields[0], uid: uid, gid: gid, home: fields[5]})
+	})
+	return users, err
+}
+
+// readGroup returns the groups in /etc/group of the image filesystem at root.
+func readGroup(root string) ([]groupEntry, error) {
+	var groups []groupEntry
+	err := readColonFile(filepath.Join(root, "etc", "group"), func(fields []string) {
+		// name:password:gid:members
+		if len(fields) < 3 {
+			return
+		}
+		gid, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return
+		}
+		groups = append(groups, groupEntry{name: fields[0], gid: gid})
+	})
+	return groups, err
+}
+
+// readColonFile calls entry with the fields of each entry of a colon-separated file
+// such as /etc/passwd, skipping comments and NIS compat entries.
+func readColonFile(path string, entry func(fields []string)) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			continue
+		}
+		entry(strings.Split(line, ":"))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read %s: %w", path, err)
+	}
+	return nil
+}
+
+// resolvedUser is the user and group a container runs as.
+type resolvedUser struct {
+	uid       int
+	gid       int
+	userName  string
+	groupName string
+}
+
+func (u resolvedUser) String() string {
+	s := "uid " + strconv.Itoa(u.uid)
+	if u.userName != "" {
+		s += " (" + u.userName + ")"
+	}
+	s += ", gid " + strconv.Itoa(u.gid)
+	if u.groupName != "" {
+		s += " (" + u.groupName + ")"
+	}
+	return s
+}
+
+// resolveUser resolves the USER of an image config, user[:group], the way container
+// runtimes do: names first, then numeric IDs, with the user's primary group, or gid 0,
+// if no group is given. A name that cannot be resolved is an error.
+func resolveUser(spec string, users []passwdEntry, groups []groupEntry) (resolvedUser, error) {
+	userPart, groupPart, hasGroup := strings.Cut(spec, ":")
+
+	var resolved resolvedUser
+	switch entry, ok := lookupUser(userPart, users); {
+	case userPart == "":
+		resolved.uid = 0
+	case ok:
+		resolved.uid, resolved.gid = entry.uid, entry.gid
+	default:
+		uid, err := strconv.Atoi(userPart)
+		if err != nil || uid < 0 {
+			return resolved, fmt.Errorf("user %s is not in /etc/passwd", userPart)
+		}
+		resolved.uid = uid
+		if entry, ok := lookupUID(uid, users); ok {
+			resolved.gid = entry.gid
+		}
+	}
+
+	if hasGroup {
+		if entry, ok := lookupGroup(groupPart, groups); ok {
+			resolved.gid = entry.gid
+		} else {
+			gid, err := strconv.Atoi(groupPart)
+			if err != nil || gid < 0 {
+				return resolved, fmt.Errorf("group %s is not in /etc/group", groupPart)
+			}
+			resolved.gid = gid
+		}
+	}
+
+	if entry, ok := lookupUID(resolved.uid, users); ok {
+		resolved.userName = entry.name
+	}
+	for _, g := range groups {
+		if g.gid == resolved.gid {
+			resolved.groupName = g.name
+			break
+		}
+	}
+	return resolved, nil
+}
+
+func lookupUser(name string, users []passwdEntry) (passwdEntry, bool) {
+	for _, u := range users {
+		if u.name == name {
+			return u, true
+		}
+	}
+	return passwdEntry{}, false
+}
+
+func lookupUID(uid int, users []passwdEntry) (passwdEntry, bool) {
+	for _, u := range users {
+		if u.uid == uid {
+			return u, true
+		}
+	}
+	return passwdEntry{}, false
+}
+
+func lookupGroup(name string, groups []groupEntry) (groupEntry, bool) {
+	for _, g := range groups {
+		if g.name == name {
+			return g, true
+		}
+	}
+	return groupEntry{}, false
+}
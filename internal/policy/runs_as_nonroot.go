@@ -6,15 +6,23 @@ import (
 
 	"github.com/opdev/knex/types"
 
+	"github.com/opdev/container-certification/internal/findings"
+
 	"github.com/go-logr/logr"
 	cranev1 "github.com/google/go-containerregistry/pkg/v1"
 )
 
-var _ types.Check = &RunAsNonRootCheck{}
+var (
+	_ types.Check       = &RunAsNonRootCheck{}
+	_ findings.Reporter = &RunAsNonRootCheck{}
+)
 
 // RunAsNonRootCheck evaluates the image to determine that the runtime UID is not 0,
-// which correlates to the root user.
-type RunAsNonRootCheck struct{}
+// which correlates to the root user. The USER of the image config is resolved
+// against the image's /etc/passwd and /etc/group.
+type RunAsNonRootCheck struct {
+	report findings.Report
+}
 
 func (p *RunAsNonRootCheck) Validate(ctx context.Context, imgRef types.ImageReference) (bool, error) {
 	user, err := p.getDataToValidate(imgRef.ImageInfo)
@@ -22,7 +30,7 @@ func (p *RunAsNonRootCheck) Validate(ctx context.Context, imgRef types.ImageRefe
 		return false, fmt.Errorf("could not get validation data: %v", err)
 	}
 
-	return p.validate(ctx, user)
+	return p.validate(ctx, imgRef.ImageFSPath, user)
 }
 
 func (p *RunAsNonRootCheck) getDataToValidate(image cranev1.Image) (string, error) {
@@ -33,25 +41,63 @@ func (p *RunAsNonRootCheck) getDataToValidate(image cranev1.Image) (string, erro
 	return configFile.Config.User, nil
 }
 
-func (p *RunAsNonRootCheck) validate(ctx context.Context, user string) (bool, error) {
+func (p *RunAsNonRootCheck) validate(ctx context.Context, root string, user string) (bool, error) {
 	logger := logr.FromContextOrDiscard(ctx)
 
+	p.report = findings.Report{
+		Inspected: "image config USER, resolved against /etc/passwd and /etc/group",
+		Findings:  []findings.Finding{},
+	}
+
 	if user == "" {
 		logger.Info("detected empty USER. Presumed to be running as root")
 		logger.Info("USER value must be provided and be a non-root value for this check to pass")
+		p.fail(user, "no USER is set, so the container runs as uid 0 (root)")
+		return false, nil
+	}
+
+	users, err := readPasswd(root)
+	if err != nil {
+		return false, err
+	}
+	groups, err := readGroup(root)
+	if err != nil {
+		return false, err
+	}
+
+	resolved, err := resolveUser(user, users, groups)
+	if err != nil {
+		logger.Info(fmt.Sprintf("USER %s could not be resolved: %v", user, err))
+		p.fail(user, fmt.Sprintf("could not be resolved, the container would not start: %v", err))
 		return false, nil
 	}
 
-	if user == "0" || user == "root" {
-		logger.Info("detected USER specified as root or UID 0")
+	if resolved.uid == 0 {
+		logger.Info(fmt.Sprintf("detected USER %s resolved to UID 0", user))
 		logger.Info("USER other than root is required for this check to pass")
+		p.fail(user, "resolved to "+resolved.String())
 		return false, nil
 	}
 
-	logger.Info(fmt.Sprintf("USER %s specified that is non-root", user))
+	logger.Info(fmt.Sprintf("USER %s specified that is non-root, resolved to %s", user, resolved))
 	return true, nil
 }
 
+// fail records a finding about the USER of the image.
+func (p *RunAsNonRootCheck) fail(user, detail string) {
+	p.report.Findings = append(p.report.Findings, findings.Finding{
+		Kind:    findings.KindUser,
+		Subject: user,
+		Detail:  detail,
+	})
+	p.report.Remediation = "Set USER to a non-root user, e.g. USER 1001, in the dockerfile or containerfile"
+}
+
+// Report returns why the USER of the image was rejected by the most recent validation.
+func (p *RunAsNonRootCheck) Report() findings.Report {
+	return p.report
+}
+
 func (p *RunAsNonRootCheck) Name() string {
 	return "RunAsNonRoot"
 }
@@ -2,12 +2,16 @@ package policy
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 
 	cranev1 "github.com/google/go-containerregistry/pkg/v1"
 	fakecranev1 "github.com/google/go-containerregistry/pkg/v1/fake"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
 )
 
 func userConfigFile(user string) (*cranev1.ConfigFile, error) {
@@ -98,5 +102,68 @@ var _ = Describe("RunAsNonRoot", func() {
 		})
 	})
 
+	Describe("Resolving the manifest user against the image", func() {
+		var root string
+
+		BeforeEach(func() {
+			root = GinkgoT().TempDir()
+			Expect(os.MkdirAll(filepath.Join(root, "etc"), 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, "etc", "passwd"), []byte(`root:x:0:0:root:/root:/bin/bash
+# a comment
+nobody:x:65534:65534:Kernel Overflow User:/:/sbin/nologin
+toor:x:0:0:another root:/root:/bin/bash
+default:x:1001:0:Default Application User:/opt/app-root/src:/sbin/nologin
+`), 0o644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, "etc", "group"), []byte(`root:x:0:
+nobody:x:65534:
+`), 0o644)).To(Succeed())
+		})
+
+		Context("When the user resolves to a non-zero UID", func() {
+			It("should pass validate", func() {
+				for _, user := range []string{"default", "nobody:0", "1001:root", "1000680000"} {
+					ok, err := runAsNonRoot.validate(context.TODO(), root, user)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(ok).To(BeTrue(), user)
+					Expect(runAsNonRoot.Report().Findings).To(BeEmpty())
+				}
+			})
+		})
+
+		Context("When the user resolves to UID 0", func() {
+			It("should report what the user resolved to", func() {
+				for user, detail := range map[string]string{
+					"0:0":         "resolved to uid 0 (root), gid 0 (root)",
+					"toor":        "resolved to uid 0 (root), gid 0 (root)",
+					"root:nobody": "resolved to uid 0 (root), gid 65534 (nobody)",
+				} {
+					ok, err := runAsNonRoot.validate(context.TODO(), root, user)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(ok).To(BeFalse(), user)
+					Expect(runAsNonRoot.Report().Findings).To(ConsistOf(findings.Finding{
+						Kind:    findings.KindUser,
+						Subject: user,
+						Detail:  detail,
+					}))
+				}
+			})
+		})
+
+		It("should not pass when the user is not in /etc/passwd", func() {
+			ok, err := runAsNonRoot.validate(context.TODO(), root, "appuser")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(runAsNonRoot.Report().Findings).To(HaveLen(1))
+			Expect(runAsNonRoot.Report().Findings[0].Detail).To(ContainSubstring("user appuser is not in /etc/passwd"))
+		})
+
+		It("should not pass when the group is not in /etc/group", func() {
+			ok, err := runAsNonRoot.validate(context.TODO(), root, "1001:app")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(runAsNonRoot.Report().Findings[0].Detail).To(ContainSubstring("group app is not in /etc/group"))
+		})
+	})
+
 	AssertMetaData(&runAsNonRoot)
 })
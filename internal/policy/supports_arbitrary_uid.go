@@ -0,0 +1,219 @@
+package policy
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/opdev/knex/log"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
+	"github.com/opdev/container-certification/internal/fsroot"
+
+	"github.com/go-logr/logr"
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+)
+
+var (
+	_ types.Check       = &SupportsArbitraryUIDCheck{}
+	_ findings.Reporter = &SupportsArbitraryUIDCheck{}
+)
+
+// SupportsArbitraryUIDCheck evaluates that the image can run as an arbitrary UID in
+// group 0, as OpenShift runs containers, by checking that the directories the
+// container is expected to write to are writable by group 0. Those are the VOLUMEs,
+// the WORKDIR, and the home directory of the USER. Ownership and permissions are read
+// from the image layers, since they are not kept when the image is extracted.
+type SupportsArbitraryUIDCheck struct {
+	report findings.Report
+}
+
+func (p *SupportsArbitraryUIDCheck) Validate(ctx context.Context, imgRef types.ImageReference) (bool, error) {
+	if imgRef.ImageInfo == nil {
+		return false, errors.New("image reference invalid")
+	}
+	configFile, err := imgRef.ImageInfo.ConfigFile()
+	if err != nil {
+		return false, fmt.Errorf("could not retrieve ConfigFile from Image: %w", err)
+	}
+
+	dirs, err := p.writableDirs(imgRef.ImageFSPath, configFile.Config)
+	if err != nil {
+		return false, err
+	}
+
+	fs := mutate.Extract(imgRef.ImageInfo)
+	defer fs.Close()
+	tree, err := readFSTree(fs)
+	if err != nil {
+		return false, fmt.Errorf("could not read the image filesystem: %w", err)
+	}
+
+	return p.validate(ctx, tree, dirs)
+}
+
+// writableDir is a directory the container is expected to write to.
+type writableDir struct {
+	path string
+	// source is what declares the directory, e.g. VOLUME.
+	source string
+}
+
+// writableDirs returns the directories declared by config, and the home directory
+// of its USER if it is listed in the /etc/passwd of the image filesystem at root.
+func (p *SupportsArbitraryUIDCheck) writableDirs(root string, config cranev1.Config) ([]writableDir, error) {
+	volumes := make([]string, 0, len(config.Volumes))
+	for volume := range config.Volumes {
+		volumes = append(volumes, volume)
+	}
+	sort.Strings(volumes)
+
+	dirs := make([]writableDir, 0, len(volumes)+2)
+	for _, volume := range volumes {
+		dirs = append(dirs, writableDir{path: volume, source: "VOLUME"})
+	}
+	if config.WorkingDir != "" {
+		dirs = append(dirs, writableDir{path: config.WorkingDir, source: "WORKDIR"})
+	}
+
+	users, err := readPasswd(root)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := readGroup(root)
+	if err != nil {
+		return nil, err
+	}
+	// RunAsNonRoot reports a USER that cannot be resolved, or is root.
+	if resolved, err := resolveUser(config.User, users, groups); err == nil && resolved.uid != 0 {
+		if user, ok := lookupUID(resolved.uid, users); ok && path.Clean(user.home) != "/" && user.home != "" {
+			dirs = append(dirs, writableDir{path: user.home, source: "home directory of USER " + config.User})
+		}
+	}
+
+	return dirs, nil
+}
+
+func (p *SupportsArbitraryUIDCheck) validate(ctx context.Context, tree fsTree, dirs []writableDir) (bool, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	p.report = findings.Report{
+		Inspected: fmt.Sprintf("ownership and permissions of %d VOLUME, WORKDIR and home directories in the image layers", len(dirs)),
+		Findings:  []findings.Finding{},
+	}
+
+	var offending []string
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		resolved, err := tree.resolve(dir.path)
+		if err != nil {
+			return false, fmt.Errorf("could not resolve %s %s: %w", dir.source, dir.path, err)
+		}
+		if seen[resolved] {
+			continue
+		}
+		seen[resolved] = true
+
+		entry, ok := tree[resolved]
+		if !ok {
+			// The container runtime creates the directory when it is missing.
+			logger.V(log.DBG).Info("directory is not in the image", "source", dir.source, "path", dir.path)
+			continue
+		}
+		if entry.Typeflag != tar.TypeDir {
+			continue
+		}
+		if writableByGroupZero(entry) {
+			continue
+		}
+
+		subject := dir.path
+		if resolved != path.Clean(dir.path) {
+			subject = fmt.Sprintf("%s -> %s", dir.path, resolved)
+		}
+		offending = append(offending, resolved)
+		p.report.Findings = append(p.report.Findings, findings.Finding{
+			Kind:    findings.KindFile,
+			Subject: subject,
+			Detail:  fmt.Sprintf("%s is owned by %d:%d with mode %04o, and is not writable by group 0", dir.source, entry.Uid, entry.Gid, entry.Mode&0o7777),
+		})
+	}
+
+	if len(offending) > 0 {
+		logger.V(log.DBG).Info("directories not writable by an arbitrary UID found", "directoryList", offending)
+		p.report.Remediation = "Make the following directories writable by group 0, e.g. with chgrp -R 0 <dir> && chmod -R g=u <dir>: " + strings.Join(offending, ", ")
+	}
+
+	return len(offending) == 0, nil
+}
+
+func (p *SupportsArbitraryUIDCheck) Report() findings.Report {
+	return p.report
+}
+
+func (p *SupportsArbitraryUIDCheck) Name() string {
+	return "SupportsArbitraryUID"
+}
+
+func (p *SupportsArbitraryUIDCheck) Metadata() types.Metadata {
+	return types.Metadata{
+		Description:      "Checking if the directories the container writes to are writable by group 0, because OpenShift runs containers as an arbitrary UID in group 0",
+		Level:            "best",
+		KnowledgeBaseURL: certDocumentationURL,
+		CheckURL:         certDocumentationURL,
+	}
+}
+
+func (p *SupportsArbitraryUIDCheck) Help() types.HelpText {
+	return types.HelpText{
+		Message:    "Check SupportsArbitraryUID encountered an error. Please review the preflight.log file for more information.",
+		Suggestion: "Make the VOLUMEs, the WORKDIR and the home directory of the USER owned by group 0 and group writable, e.g. with chgrp -R 0 <dir> && chmod -R g=u <dir>",
+	}
+}
+
+// fsTree holds the directories and symlinks of a filesystem, keyed by absolute path.
+type fsTree map[string]*tar.Header
+
+// readFSTree reads the directories and symlinks from a tar of a flattened image
+// filesystem, such as the one returned by mutate.Extract.
+func readFSTree(r io.Reader) (fsTree, error) {
+	tree := fsTree{}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return tree, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeSymlink {
+			continue
+		}
+		tree[path.Join("/", header.Name)] = header
+	}
+}
+
+// resolve returns p with every symlink in it resolved within the tree.
+func (t fsTree) resolve(p string) (string, error) {
+	return fsroot.Resolve(p, func(name string) (string, bool, error) {
+		header, ok := t[name]
+		if !ok || header.Typeflag != tar.TypeSymlink {
+			return "", false, nil
+		}
+		return header.Linkname, true, nil
+	})
+}
+
+// writableByGroupZero reports whether files can be created in the directory of
+// header by any UID in group 0, which needs both write and search permission.
+func writableByGroupZero(header *tar.Header) bool {
+	return header.Mode&0o003 == 0o003 || (header.Gid == 0 && header.Mode&0o030 == 0o030)
+}
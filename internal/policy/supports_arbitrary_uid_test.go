@@ -0,0 +1,145 @@
+package policy
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/opdev/knex/types"
+
+	"github.com/opdev/container-certification/internal/findings"
+)
+
+// imageWithHeaders returns an image with a single layer holding entries, configured
+// with config.
+func imageWithHeaders(config cranev1.Config, entries ...*tar.Header) cranev1.Image {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, header := range entries {
+		Expect(tw.WriteHeader(header)).To(Succeed())
+	}
+	Expect(tw.Close()).To(Succeed())
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	})
+	Expect(err).ToNot(HaveOccurred())
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	Expect(err).ToNot(HaveOccurred())
+	img, err = mutate.Config(img, config)
+	Expect(err).ToNot(HaveOccurred())
+	return img
+}
+
+func dirHeader(name string, uid, gid int, mode int64) *tar.Header {
+	return &tar.Header{Typeflag: tar.TypeDir, Name: name, Uid: uid, Gid: gid, Mode: mode}
+}
+
+var _ = Describe("SupportsArbitraryUID", func() {
+	var (
+		supportsArbitraryUID SupportsArbitraryUIDCheck
+		root                 string
+		config               cranev1.Config
+	)
+
+	BeforeEach(func() {
+		root = GinkgoT().TempDir()
+		Expect(os.MkdirAll(filepath.Join(root, "etc"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "etc", "passwd"), []byte(`root:x:0:0:root:/root:/bin/bash
+default:x:1001:0:Default Application User:/opt/app-root/src:/sbin/nologin
+`), 0o644)).To(Succeed())
+
+		config = cranev1.Config{
+			User:       "1001",
+			WorkingDir: "/opt/app-root/src",
+			Volumes:    map[string]struct{}{"/var/lib/data": {}},
+		}
+	})
+
+	AssertMetaData(&supportsArbitraryUID)
+
+	Context("When the directories are writable by group 0", func() {
+		It("should pass Validate", func() {
+			img := imageWithHeaders(config,
+				dirHeader("opt/app-root/src/", 1001, 0, 0o775),
+				dirHeader("var/lib/data/", 1001, 1001, 0o777),
+			)
+			ok, err := supportsArbitraryUID.Validate(context.TODO(), types.ImageReference{ImageFSPath: root, ImageInfo: img})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(supportsArbitraryUID.Report().Findings).To(BeEmpty())
+		})
+	})
+
+	Context("When a directory is owned by the build UID", func() {
+		It("should report the directory", func() {
+			img := imageWithHeaders(config,
+				dirHeader("opt/app-root/src/", 1001, 1001, 0o775),
+				dirHeader("var/lib/data/", 1001, 0, 0o755),
+			)
+			ok, err := supportsArbitraryUID.Validate(context.TODO(), types.ImageReference{ImageFSPath: root, ImageInfo: img})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(supportsArbitraryUID.Report().Findings).To(ConsistOf(
+				findings.Finding{
+					Kind:    findings.KindFile,
+					Subject: "/var/lib/data",
+					Detail:  "VOLUME is owned by 1001:0 with mode 0755, and is not writable by group 0",
+				},
+				findings.Finding{
+					Kind:    findings.KindFile,
+					Subject: "/opt/app-root/src",
+					Detail:  "WORKDIR is owned by 1001:1001 with mode 0775, and is not writable by group 0",
+				},
+			))
+			Expect(supportsArbitraryUID.Report().Remediation).To(ContainSubstring("/var/lib/data, /opt/app-root/src"))
+		})
+	})
+
+	Context("When the home directory of the user is not writable", func() {
+		It("should report the home directory", func() {
+			config.WorkingDir = "/"
+			config.Volumes = nil
+			img := imageWithHeaders(config, dirHeader("opt/app-root/src/", 0, 0, 0o755))
+			ok, err := supportsArbitraryUID.Validate(context.TODO(), types.ImageReference{ImageFSPath: root, ImageInfo: img})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(supportsArbitraryUID.Report().Findings).To(HaveLen(1))
+			Expect(supportsArbitraryUID.Report().Findings[0].Detail).To(HavePrefix("home directory of USER 1001"))
+		})
+	})
+
+	Context("When a directory is a symlink", func() {
+		It("should check the directory it points to", func() {
+			config.Volumes = map[string]struct{}{"/data": {}}
+			img := imageWithHeaders(config,
+				dirHeader("opt/app-root/src/", 1001, 0, 0o775),
+				&tar.Header{Typeflag: tar.TypeSymlink, Name: "data", Linkname: "var/lib/data"},
+				dirHeader("var/lib/data/", 1001, 1001, 0o700),
+			)
+			ok, err := supportsArbitraryUID.Validate(context.TODO(), types.ImageReference{ImageFSPath: root, ImageInfo: img})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeFalse())
+			Expect(supportsArbitraryUID.Report().Findings).To(HaveLen(1))
+			Expect(supportsArbitraryUID.Report().Findings[0].Subject).To(Equal("/data -> /var/lib/data"))
+		})
+	})
+
+	Context("When a directory is not in the image", func() {
+		It("should pass Validate", func() {
+			img := imageWithHeaders(config, dirHeader("opt/app-root/src/", 1001, 0, 0o770))
+			ok, err := supportsArbitraryUID.Validate(context.TODO(), types.ImageReference{ImageFSPath: root, ImageInfo: img})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ok).To(BeTrue())
+		})
+	})
+})
@@ -130,6 +130,15 @@ func WithArtifact(r io.Reader, filename string) CertificationInputOption {
 	}
 }
 
+// WithJournal records the progress of the submission in journal, so that a submission
+// that fails part way can be resumed from the first step that did not complete.
+func WithJournal(journal *Journal) CertificationInputOption {
+	return func(b *certificationInputBuilder) error {
+		b.Journal = journal
+		return nil
+	}
+}
+
 func readAndUnmarshal(r io.Reader, submission interface{}) error {
 	bytes, err := io.ReadAll(r)
 	if err != nil {
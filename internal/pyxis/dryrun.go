@@ -0,0 +1,81 @@
+package pyxis
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// DryRunImageID stands in for the ID that Pyxis assigns to the image, in planned
+// requests that refer to the image.
+const DryRunImageID = "<image _id assigned by pyxis>"
+
+// SubmissionRequest is a request that SubmitResults sends to Pyxis.
+type SubmissionRequest struct {
+	// Operation is what the request does, e.g. "create image".
+	Operation string
+	Method    string
+	// Path is the path of the request, relative to the API version, e.g. images.
+	Path string
+	// Body is sent as JSON.
+	Body any
+}
+
+// PlanSubmission returns the requests SubmitResults sends to submit certInput to the
+// project with projectID, in the order they are sent, without sending them. Requests
+// that refer to the image use DryRunImageID. If the image or its RPM manifest already
+// exist, SubmitResults reads them instead of creating them, and may update whether
+// the image is certified. certInput is not modified.
+func PlanSubmission(projectID string, certInput *CertificationInput) ([]SubmissionRequest, error) {
+	certProject := *certInput.CertProject
+	certImage := *certInput.CertImage
+	certImage.Repositories = append([]Repository(nil), certInput.CertImage.Repositories...)
+	if err := prepareSubmission(&certProject, &certImage); err != nil {
+		return nil, err
+	}
+
+	requests := []SubmissionRequest{
+		{
+			Operation: "update project",
+			Method:    http.MethodPatch,
+			Path:      fmt.Sprintf("projects/certification/id/%s", projectID),
+			Body:      projectPatch(&certProject),
+		},
+		{
+			Operation: "create image",
+			Method:    http.MethodPost,
+			Path:      "images",
+			Body:      &certImage,
+		},
+	}
+
+	rpmManifest := *certInput.RpmManifest
+	rpmManifest.ImageID = DryRunImageID
+	requests = append(requests, SubmissionRequest{
+		Operation: "create rpm manifest",
+		Method:    http.MethodPost,
+		Path:      fmt.Sprintf("images/id/%s/rpm-manifest", rpmManifest.ImageID),
+		Body:      &rpmManifest,
+	})
+
+	for _, artifact := range certInput.Artifacts {
+		artifact := artifact
+		artifact.ImageID = DryRunImageID
+		requests = append(requests, SubmissionRequest{
+			Operation: "create artifact",
+			Method:    http.MethodPost,
+			Path:      fmt.Sprintf("projects/certification/id/%s/artifacts", projectID),
+			Body:      &artifact,
+		})
+	}
+
+	testResults := *certInput.TestResults
+	testResults.ImageID = DryRunImageID
+	requests = append(requests, SubmissionRequest{
+		Operation: "create test results",
+		Method:    http.MethodPost,
+		Path:      fmt.Sprintf("projects/certification/id/%s/test-results", projectID),
+		Body:      &testResults,
+	})
+
+	return requests, nil
+}
@@ -0,0 +1,74 @@
+package pyxis
+
+import (
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pyxis PlanSubmission", func() {
+	var certInput CertificationInput
+
+	BeforeEach(func() {
+		certInput = CertificationInput{
+			CertProject: &CertProject{
+				ID:                  "my-awesome-project-id",
+				CertificationStatus: "Started",
+				Type:                "Containers",
+				Container:           Container{Type: "container", DockerConfigJSON: "{}"},
+			},
+			CertImage: &CertImage{
+				Repositories:      []Repository{{Registry: "index.docker.io", Repository: "my/repo"}},
+				DockerImageDigest: "sha256:deadb33f",
+				Certified:         true,
+			},
+			RpmManifest: &RPMManifest{RPMS: []RPM{{Name: "bash"}}},
+			TestResults: &TestResults{},
+			Artifacts:   []Artifact{{Filename: "preflight.log", Content: "bG9n"}},
+		}
+	})
+
+	Context("when a submission is planned", func() {
+		It("should return the requests SubmitResults sends, in order", func() {
+			requests, err := PlanSubmission("my-awesome-project-id", &certInput)
+			Expect(err).ToNot(HaveOccurred())
+
+			operations := make([]string, 0, len(requests))
+			for _, req := range requests {
+				operations = append(operations, req.Operation)
+			}
+			Expect(operations).To(Equal([]string{"update project", "create image", "create rpm manifest", "create artifact", "create test results"}))
+
+			Expect(requests[0].Method).To(Equal(http.MethodPatch))
+			Expect(requests[0].Path).To(Equal("projects/certification/id/my-awesome-project-id"))
+			project := requests[0].Body.(*CertProject)
+			Expect(project.CertificationStatus).To(Equal("In Progress"))
+			Expect(project.Type).To(BeEmpty())
+			Expect(project.Container.Type).To(BeEmpty())
+			Expect(project.Container.Registry).To(Equal("docker.io"))
+			Expect(project.Container.Repository).To(Equal("my/repo"))
+
+			Expect(requests[1].Body.(*CertImage).Repositories[0].Registry).To(Equal("docker.io"))
+			Expect(requests[2].Path).To(Equal("images/id/" + DryRunImageID + "/rpm-manifest"))
+			Expect(requests[3].Body.(*Artifact).ImageID).To(Equal(DryRunImageID))
+			Expect(requests[4].Body.(*TestResults).ImageID).To(Equal(DryRunImageID))
+		})
+		It("should not modify the input", func() {
+			_, err := PlanSubmission("my-awesome-project-id", &certInput)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(certInput.CertProject.CertificationStatus).To(Equal("Started"))
+			Expect(certInput.CertImage.Repositories[0].Registry).To(Equal("index.docker.io"))
+			Expect(certInput.RpmManifest.ImageID).To(BeEmpty())
+			Expect(certInput.Artifacts[0].ImageID).To(BeEmpty())
+		})
+	})
+
+	Context("when the certImage does not have repositories", func() {
+		It("should return an error", func() {
+			certInput.CertImage.Repositories = nil
+			_, err := PlanSubmission("my-awesome-project-id", &certInput)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
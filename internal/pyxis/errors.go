@@ -1,5 +1,97 @@
 package pyxis
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
 
+// ErrPyxis409StatusCode matches an *APIError for a conflict, which Pyxis returns
+// when the resource being created already exists.
 var ErrPyxis409StatusCode = errors.New("pyxis API returned a conflict")
+
+// These errors match an *APIError, with errors.Is, according to its status code.
+var (
+	// ErrUnauthorized matches a 401 or 403, usually an invalid or expired API
+	// token, or a token for another project.
+	ErrUnauthorized = errors.New("pyxis API rejected the API token")
+	// ErrNotFound matches a 404, e.g. for a project that does not exist.
+	ErrNotFound = errors.New("pyxis API could not find the resource")
+	// ErrInvalidRequest matches a 400 or 422, which Pyxis returns when a
+	// request fails validation.
+	ErrInvalidRequest = errors.New("pyxis API rejected the request as invalid")
+	// ErrRateLimited matches a 429.
+	ErrRateLimited = errors.New("pyxis API rate limited the request")
+	// ErrUnavailable matches a 5xx, when Pyxis is down or failing.
+	ErrUnavailable = errors.New("pyxis API is unavailable")
+)
+
+// APIError is a failed Pyxis API call. Use errors.Is with the errors above to tell
+// the common failures apart.
+type APIError struct {
+	// Operation is what the client was doing, e.g. "create image".
+	Operation string
+	// Path is the path of the request, e.g. /api/containers/v1/images.
+	Path string
+	// StatusCode is the HTTP status of the response, or the status of the error
+	// returned in the result of a GraphQL query.
+	StatusCode int
+	// Detail is the detail of the error Pyxis returned, or the response body if
+	// it is not a Pyxis error.
+	Detail string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("pyxis API could not %s: %s: status code: %d", e.Operation, e.Path, e.StatusCode)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+// Is reports whether target is the sentinel error for the status code of e.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrPyxis409StatusCode:
+		return e.StatusCode == http.StatusConflict
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrInvalidRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnavailable:
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// newAPIError returns the error for a response to req with statusCode and body.
+// Pyxis describes errors as {"detail": ..., "status": ..., "title": ...}.
+func newAPIError(operation string, req *http.Request, statusCode int, body []byte) *APIError {
+	var pyxisErr struct {
+		Detail string `json:"detail"`
+		Title  string `json:"title"`
+	}
+
+	detail := strings.TrimSpace(string(body))
+	if err := json.Unmarshal(body, &pyxisErr); err == nil {
+		switch {
+		case pyxisErr.Detail != "":
+			detail = pyxisErr.Detail
+		case pyxisErr.Title != "":
+			detail = pyxisErr.Title
+		}
+	}
+
+	return &APIError{
+		Operation:  operation,
+		Path:       req.URL.Path,
+		StatusCode: statusCode,
+		Detail:     detail,
+	}
+}
@@ -0,0 +1,102 @@
+package pyxis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pyxis API errors", func() {
+	ctx := context.Background()
+
+	var (
+		pyxisClient *pyxisClient
+		status      int
+		body        string
+	)
+
+	BeforeEach(func() {
+		handler := http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			if request.Body != nil {
+				defer request.Body.Close()
+			}
+			response.Header().Set("Content-Type", "application/json")
+			response.WriteHeader(status)
+			mustWrite(response, body)
+		})
+		pyxisClient = NewPyxisClient("my.pyxis.host/api", "my-spiffy-api-token", "my-awesome-project-id", &http.Client{Transport: localRoundTripper{handler: handler}})
+		pyxisClient.Retry = RetryPolicy{}
+	})
+
+	Context("when a REST call fails", func() {
+		It("should return an APIError with the Pyxis error detail", func() {
+			status = http.StatusNotFound
+			body = `{"detail":"Project my-awesome-project-id not found","status":404,"title":"Not Found","type":"about:blank"}`
+			_, err := pyxisClient.GetProject(ctx)
+
+			var apiErr *APIError
+			Expect(errors.As(err, &apiErr)).To(BeTrue())
+			Expect(*apiErr).To(Equal(APIError{
+				Operation:  "get project",
+				Path:       "/api/v1/projects/certification/id/my-awesome-project-id",
+				StatusCode: http.StatusNotFound,
+				Detail:     "Project my-awesome-project-id not found",
+			}))
+			Expect(err).To(MatchError(ErrNotFound))
+			Expect(err).ToNot(MatchError(ErrUnauthorized))
+		})
+		It("should keep a body that is not a Pyxis error", func() {
+			status = http.StatusBadGateway
+			body = "<html>Bad Gateway</html>\n"
+			_, err := pyxisClient.GetProject(ctx)
+			Expect(err).To(MatchError(ErrUnavailable))
+			Expect(err).To(MatchError(ContainSubstring("status code: 502: <html>Bad Gateway</html>")))
+		})
+		It("should match a conflict through a submission", func() {
+			status = http.StatusConflict
+			body = `{"detail":"Image already exists","status":409}`
+			_, err := pyxisClient.createImage(ctx, &CertImage{})
+			Expect(err).To(MatchError(ErrPyxis409StatusCode))
+		})
+	})
+
+	Context("when a GraphQL query fails", func() {
+		It("should return an APIError for an HTTP error", func() {
+			status = http.StatusUnauthorized
+			body = `{"detail":"Invalid API key","status":401}`
+			_, err := pyxisClient.FindImagesByDigest(ctx, []string{"sha256:deadb33f"})
+			Expect(err).To(MatchError(ErrUnauthorized))
+			Expect(err).To(MatchError(ContainSubstring("Invalid API key")))
+		})
+		It("should return an APIError for the error in the query result", func() {
+			status = http.StatusOK
+			body = `{"data":{"find_images":{"error":{"status":400,"detail":"Invalid filter"},"total":0,"page":0,"data":null}}}`
+			_, err := pyxisClient.FindImagesByDigest(ctx, []string{"sha256:deadb33f"})
+
+			var apiErr *APIError
+			Expect(errors.As(err, &apiErr)).To(BeTrue())
+			Expect(apiErr.Operation).To(Equal("find images"))
+			Expect(apiErr.Path).To(Equal("/api/graphql/"))
+			Expect(apiErr.Detail).To(Equal("Invalid filter"))
+			Expect(err).To(MatchError(ErrInvalidRequest))
+		})
+	})
+
+	It("should match each sentinel by status code", func() {
+		for statusCode, sentinel := range map[int]error{
+			http.StatusBadRequest:          ErrInvalidRequest,
+			http.StatusUnauthorized:        ErrUnauthorized,
+			http.StatusForbidden:           ErrUnauthorized,
+			http.StatusNotFound:            ErrNotFound,
+			http.StatusConflict:            ErrPyxis409StatusCode,
+			http.StatusUnprocessableEntity: ErrInvalidRequest,
+			http.StatusTooManyRequests:     ErrRateLimited,
+			http.StatusServiceUnavailable:  ErrUnavailable,
+		} {
+			Expect(errors.Is(&APIError{StatusCode: statusCode}, sentinel)).To(BeTrue(), "status %d", statusCode)
+		}
+	})
+})
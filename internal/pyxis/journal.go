@@ -0,0 +1,202 @@
+package pyxis
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// The steps of a submission, as recorded in a Journal. They are named after the
+// operations of the requests that complete them.
+const (
+	stepUpdateProject     = "update project"
+	stepCreateImage       = "create image"
+	stepCreateRPMManifest = "create rpm manifest"
+	stepCreateArtifact    = "create artifact"
+	stepCreateTestResults = "create test results"
+)
+
+// Journal records the steps of a submission that completed, with the IDs Pyxis
+// returned for them, so that a submission that failed part way can be resumed
+// without repeating them. It is saved to its file after each step.
+type Journal struct {
+	path string
+
+	// ProjectID, DockerImageDigest and Architecture identify the submission. The
+	// steps of a submission of another image or project are discarded.
+	ProjectID         string        `json:"project_id"`
+	DockerImageDigest string        `json:"docker_image_digest"`
+	Architecture      string        `json:"architecture"`
+	Steps             []JournalStep `json:"steps"`
+}
+
+// JournalStep is a completed step of a submission.
+type JournalStep struct {
+	Step string `json:"step"`
+	// ID is the ID of the resource the step created or updated.
+	ID string `json:"id,omitempty"`
+	// Filename identifies the artifact created by a create artifact step.
+	Filename string `json:"filename,omitempty"`
+	// SHA256 is the hash of the content the step submitted, for the steps whose
+	// content can differ between attempts. A step is repeated if it changed.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// OpenJournal reads the journal at path, or returns an empty journal that is saved
+// to path if there is none.
+func OpenJournal(path string) (*Journal, error) {
+	j := &Journal{path: path}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read submission journal: %w", err)
+	}
+	if err := json.Unmarshal(b, j); err != nil {
+		return nil, fmt.Errorf("could not parse submission journal %s: %w", path, err)
+	}
+	return j, nil
+}
+
+// begin prepares j for the submission of certImage to the project with projectID,
+// discarding the steps of a submission of another image or project. It returns true
+// if steps of a previous attempt were kept.
+func (j *Journal) begin(projectID string, certImage *CertImage) (bool, error) {
+	if j.ProjectID == projectID && j.DockerImageDigest == certImage.DockerImageDigest && j.Architecture == certImage.Architecture {
+		return len(j.Steps) > 0, nil
+	}
+
+	j.ProjectID = projectID
+	j.DockerImageDigest = certImage.DockerImageDigest
+	j.Architecture = certImage.Architecture
+	j.Steps = nil
+	return false, j.save()
+}
+
+// completed returns the recorded step, if it completed with content that hashes
+// to sha256. Steps recorded with other content are not returned.
+func (j *Journal) completed(step string, sha256 string) (JournalStep, bool) {
+	for _, s := range j.Steps {
+		if s.Step == step && s.SHA256 == sha256 {
+			return s, true
+		}
+	}
+	return JournalStep{}, false
+}
+
+// completedArtifact returns the recorded step that created artifact, if any.
+func (j *Journal) completedArtifact(artifact *Artifact) (JournalStep, bool) {
+	hash := artifactHash(artifact)
+	for _, s := range j.Steps {
+		if s.Step == stepCreateArtifact && s.Filename == artifact.Filename && s.SHA256 == hash {
+			return s, true
+		}
+	}
+	return JournalStep{}, false
+}
+
+// record adds a completed step and saves the journal.
+func (j *Journal) record(step JournalStep) error {
+	j.Steps = append(j.Steps, step)
+	return j.save()
+}
+
+// recordArtifact records that artifact was created with id, and saves the journal.
+func (j *Journal) recordArtifact(artifact *Artifact, id string) error {
+	return j.record(JournalStep{Step: stepCreateArtifact, ID: id, Filename: artifact.Filename, SHA256: artifactHash(artifact)})
+}
+
+// save writes the journal to its file, replacing it atomically so that a failure
+// while writing does not lose the steps already recorded. A journal without a file
+// is not saved.
+func (j *Journal) save() error {
+	if j.path == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(j, "", "    ")
+	if err != nil {
+		return fmt.Errorf("could not marshal submission journal: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*")
+	if err != nil {
+		return fmt.Errorf("could not save submission journal: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not save submission journal: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not save submission journal: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), j.path); err != nil {
+		return fmt.Errorf("could not save submission journal: %w", err)
+	}
+	return nil
+}
+
+// volatileFields are set anew by each run of the checks, such as the time the
+// image was seen and how long each check took. They are left out of contentHash.
+var volatileFields = map[string]bool{
+	"added_date":   true,
+	"push_date":    true,
+	"elapsed_time": true,
+}
+
+// contentHash returns the SHA-256 of v as JSON, without its volatileFields, so
+// that a retried submission of the same results hashes the same.
+func contentHash(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("could not marshal submission content: %w", err)
+	}
+	var content any
+	if err := json.Unmarshal(b, &content); err != nil {
+		return "", fmt.Errorf("could not unmarshal submission content: %w", err)
+	}
+	b, err = json.Marshal(withoutVolatileFields(content))
+	if err != nil {
+		return "", fmt.Errorf("could not marshal submission content: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// withoutVolatileFields removes volatileFields from the objects in content, a
+// decoded JSON value.
+func withoutVolatileFields(content any) any {
+	switch v := content.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if volatileFields[key] {
+				delete(v, key)
+				continue
+			}
+			v[key] = withoutVolatileFields(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = withoutVolatileFields(value)
+		}
+	}
+	return content
+}
+
+// artifactHash returns the SHA-256 of the content of artifact.
+func artifactHash(artifact *Artifact) string {
+	content, err := base64.StdEncoding.DecodeString(artifact.Content)
+	if err != nil {
+		content = []byte(artifact.Content)
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
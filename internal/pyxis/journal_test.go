@@ -0,0 +1,204 @@
+package pyxis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+
+	"github.com/opdev/container-certification/internal/pyxismock"
+)
+
+var _ = Describe("Pyxis Submit with a journal", func() {
+	ctx := context.Background()
+
+	var (
+		mock          *pyxismock.Server
+		pyxisClient   *pyxisClient
+		journalPath   string
+		failArtifacts bool
+		requests      int
+	)
+
+	newCertInput := func() *CertificationInput {
+		journal, err := OpenJournal(journalPath)
+		Expect(err).ToNot(HaveOccurred())
+		log := Artifact{Filename: "preflight.log", Content: "bG9n", CertProject: "my-awesome-project-id"}
+		return &CertificationInput{
+			CertProject: &CertProject{ID: "my-awesome-project-id", CertificationStatus: "Started"},
+			CertImage: &CertImage{
+				Repositories:      []Repository{{Registry: "my.registry", Repository: "my/repo"}},
+				DockerImageDigest: "sha256:deadb33f",
+				Architecture:      "amd64",
+			},
+			RpmManifest: &RPMManifest{},
+			TestResults: &TestResults{},
+			// The same artifact twice, which is only created once.
+			Artifacts: []Artifact{log, log},
+			Journal:   journal,
+		}
+	}
+
+	BeforeEach(func() {
+		mock = pyxismock.New(pyxismock.Fixtures{
+			Projects: []pyxismock.Document{{"_id": "my-awesome-project-id", "certification_status": "Started"}},
+		})
+		failArtifacts = false
+		requests = 0
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if failArtifacts && strings.HasSuffix(r.URL.Path, "/artifacts") {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			mock.ServeHTTP(w, r)
+		})
+		pyxisClient = NewPyxisClient("my.pyxis.host/api", "my-spiffy-api-token", "my-awesome-project-id",
+			&http.Client{Transport: localRoundTripper{handler: handler}})
+		pyxisClient.Retry = RetryPolicy{}
+		journalPath = filepath.Join(GinkgoT().TempDir(), "submission-journal.json")
+	})
+
+	Context("when a submission fails part way", func() {
+		It("should resume from the first step that did not complete", func() {
+			failArtifacts = true
+			_, err := pyxisClient.SubmitResults(ctx, newCertInput())
+			Expect(errors.Is(err, ErrUnavailable)).To(BeTrue())
+
+			journal, err := OpenJournal(journalPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(journal.Steps).To(HaveLen(3))
+			Expect(journal.Steps[1].Step).To(Equal("create image"))
+			imageID := journal.Steps[1].ID
+			Expect(imageID).ToNot(BeEmpty())
+
+			failArtifacts = false
+			requests = 0
+			certResults, err := pyxisClient.SubmitResults(ctx, newCertInput())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(certResults.CertImage.ID).To(Equal(imageID))
+			// Only the artifact and the test results are created.
+			Expect(requests).To(Equal(2))
+
+			Expect(mock.Images()).To(HaveLen(1))
+			Expect(mock.Artifacts()).To(HaveLen(1))
+			Expect(mock.TestResults()).To(HaveLen(1))
+
+			journal, err = OpenJournal(journalPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(journal.Steps).To(HaveLen(5))
+			Expect(journal.Steps[3].Filename).To(Equal("preflight.log"))
+			// The SHA-256 of the decoded content, "log".
+			Expect(journal.Steps[3].SHA256).To(Equal("836ff184e7b41b1e13cb5fd89fa1de98dbbab99e9d2918913ff43b86a5c7c213"))
+		})
+	})
+
+	Context("when a submission is retried by another run of the checks", func() {
+		It("should not create the image again for a different time or log", func() {
+			failArtifacts = true
+			certInput := newCertInput()
+			certInput.CertImage.Repositories[0].PushDate = "2026-10-16T08:00:00Z"
+			certInput.CertImage.Repositories[0].Tags = []Tag{{Name: "latest", AddedDate: "2026-10-16T08:00:00Z"}}
+			_, err := pyxisClient.SubmitResults(ctx, certInput)
+			Expect(errors.Is(err, ErrUnavailable)).To(BeTrue())
+
+			failArtifacts = false
+			requests = 0
+			certInput = newCertInput()
+			certInput.CertImage.Repositories[0].PushDate = "2026-10-16T09:30:00Z"
+			certInput.CertImage.Repositories[0].Tags = []Tag{{Name: "latest", AddedDate: "2026-10-16T09:30:00Z"}}
+			for i := range certInput.Artifacts {
+				// "log of the second run"
+				certInput.Artifacts[i].Content = "bG9nIG9mIHRoZSBzZWNvbmQgcnVu"
+			}
+			_, err = pyxisClient.SubmitResults(ctx, certInput)
+			Expect(err).ToNot(HaveOccurred())
+			// Only the artifact and the test results are created.
+			Expect(requests).To(Equal(2))
+			Expect(mock.Images()).To(HaveLen(1))
+			Expect(mock.Artifacts()).To(HaveLen(1))
+		})
+
+		It("should not create the test results again for different check timings", func() {
+			withTimings := func(elapsed string) *CertificationInput {
+				certInput := newCertInput()
+				Expect(json.Unmarshal([]byte(`{"passed": true, "results": {"passed": [{"name": "HasLicense", "elapsed_time": `+elapsed+`}]}}`), certInput.TestResults)).To(Succeed())
+				return certInput
+			}
+			_, err := pyxisClient.SubmitResults(ctx, withTimings("12"))
+			Expect(err).ToNot(HaveOccurred())
+
+			requests = 0
+			_, err = pyxisClient.SubmitResults(ctx, withTimings("34"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(BeZero())
+			Expect(mock.TestResults()).To(HaveLen(1))
+		})
+	})
+
+	Context("when a submission completed", func() {
+		It("should not send anything when it is run again", func() {
+			first, err := pyxisClient.SubmitResults(ctx, newCertInput())
+			Expect(err).ToNot(HaveOccurred())
+
+			requests = 0
+			second, err := pyxisClient.SubmitResults(ctx, newCertInput())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(requests).To(BeZero())
+			Expect(second.CertImage.ID).To(Equal(first.CertImage.ID))
+		})
+	})
+
+	Context("when the image is submitted again with other results", func() {
+		It("should submit the new results and correct whether the image is certified", func() {
+			first, err := pyxisClient.SubmitResults(ctx, newCertInput())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mock.Images()[0]["certified"]).To(BeFalse())
+
+			certInput := newCertInput()
+			certInput.CertImage.Certified = true
+			certInput.TestResults.Passed = true
+			second, err := pyxisClient.SubmitResults(ctx, certInput)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(second.CertImage.ID).To(Equal(first.CertImage.ID))
+			Expect(second.TestResults.ID).ToNot(Equal(first.TestResults.ID))
+
+			Expect(mock.Images()).To(HaveLen(1))
+			Expect(mock.Images()[0]["certified"]).To(BeTrue())
+			Expect(mock.TestResults()).To(HaveLen(2))
+			Expect(mock.TestResults()[1]["passed"]).To(BeTrue())
+		})
+	})
+
+	Context("when the journal is for another image", func() {
+		It("should start over", func() {
+			_, err := pyxisClient.SubmitResults(ctx, newCertInput())
+			Expect(err).ToNot(HaveOccurred())
+
+			certInput := newCertInput()
+			certInput.CertImage.DockerImageDigest = "sha256:c0ffee"
+			_, err = pyxisClient.SubmitResults(ctx, certInput)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mock.Images()).To(HaveLen(2))
+
+			journal, err := OpenJournal(journalPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(journal.DockerImageDigest).To(Equal("sha256:c0ffee"))
+			Expect(journal.Steps).To(HaveLen(5))
+		})
+	})
+
+	Context("when the journal cannot be parsed", func() {
+		It("should return an error", func() {
+			Expect(os.WriteFile(journalPath, []byte("{"), 0o644)).To(Succeed())
+			_, err := OpenJournal(journalPath)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -3,7 +3,6 @@ package pyxis
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"time"
 
 	cranev1 "github.com/google/go-containerregistry/pkg/v1"
@@ -48,15 +47,14 @@ func (p *pyxisClient) CertifiedImagesContainingLayers(ctx context.Context, uncom
 	}
 
 	// make our query
-	httpClient, ok := p.Client.(*http.Client)
-	if !ok {
-		return nil, fmt.Errorf("client could not be used as http.Client")
-	}
-	client := graphql.NewClient(p.getPyxisGraphqlURL(), httpClient)
+	client := p.graphqlClient("find images containing layers")
 
 	err := client.Query(ctx, &query, variables)
 	if err != nil {
-		return nil, fmt.Errorf("error while executing layers query: %v", err)
+		return nil, fmt.Errorf("error while executing layers query: %w", err)
+	}
+	if err := p.graphqlError("find images containing layers", query.FindImages.Error.Status, query.FindImages.Error.Detail); err != nil {
+		return nil, fmt.Errorf("error while executing layers query: %w", err)
 	}
 
 	images := make([]CertImage, 0, len(query.FindImages.ContainerImage))
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/shurcooL/graphql"
@@ -27,14 +29,25 @@ type pyxisClient struct {
 	ProjectID string
 	Client    HTTPClient
 	PyxisHost string
+	// Retry configures how failed requests are retried.
+	Retry RetryPolicy
 }
 
 func (p *pyxisClient) getPyxisURL(path string) string {
-	return fmt.Sprintf("https://%s/%s/%s", p.PyxisHost, apiVersion, path)
+	return fmt.Sprintf("%s/%s/%s", p.baseURL(), apiVersion, path)
 }
 
 func (p *pyxisClient) getPyxisGraphqlURL() string {
-	return fmt.Sprintf("https://%s/graphql/", p.PyxisHost)
+	return fmt.Sprintf("%s/graphql/", p.baseURL())
+}
+
+// baseURL returns the URL of PyxisHost. Pyxis is reached over HTTPS, unless
+// PyxisHost explicitly starts with http://, as for a local pyxis-mock server.
+func (p *pyxisClient) baseURL() string {
+	if strings.HasPrefix(p.PyxisHost, "https://") || strings.HasPrefix(p.PyxisHost, "http://") {
+		return p.PyxisHost
+	}
+	return "https://" + p.PyxisHost
 }
 
 func NewPyxisClient(pyxisHost string, apiToken string, projectID string, httpClient HTTPClient) *pyxisClient {
@@ -43,9 +56,59 @@ func NewPyxisClient(pyxisHost string, apiToken string, projectID string, httpCli
 		ProjectID: projectID,
 		Client:    httpClient,
 		PyxisHost: pyxisHost,
+		Retry:     DefaultRetryPolicy(),
 	}
 }
 
+// graphqlClient returns a GraphQL client that sends queries through the client of p,
+// retrying them according to its retry policy. A query that fails with an HTTP error
+// returns an *APIError for operation.
+func (p *pyxisClient) graphqlClient(operation string) *graphql.Client {
+	return graphql.NewClient(p.getPyxisGraphqlURL(), &http.Client{Transport: graphqlTransport{client: p, operation: operation}})
+}
+
+// graphqlError returns the error for the error field of a GraphQL query result, or nil
+// if the query succeeded.
+func (p *pyxisClient) graphqlError(operation string, status graphql.Int, detail graphql.String) error {
+	if status == 0 && detail == "" {
+		return nil
+	}
+	var path string
+	if u, err := url.Parse(p.getPyxisGraphqlURL()); err == nil {
+		path = u.Path
+	}
+	return &APIError{
+		Operation:  operation,
+		Path:       path,
+		StatusCode: int(status),
+		Detail:     string(detail),
+	}
+}
+
+// graphqlTransport sends the requests of an http.Client through the client of
+// pyxisClient. Queries are idempotent, so they are retried. A failed response
+// becomes an *APIError, which the GraphQL client returns as is.
+type graphqlTransport struct {
+	client    *pyxisClient
+	operation string
+}
+
+func (t graphqlTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.client.do(req, true)
+	if err != nil {
+		return nil, err
+	}
+	if ok := checkStatus(resp.StatusCode); !ok {
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("could not read body: %w", err)
+		}
+		return nil, newAPIError(t.operation, req, resp.StatusCode, body)
+	}
+	return resp, nil
+}
+
 func (p *pyxisClient) createImage(ctx context.Context, certImage *CertImage) (*CertImage, error) {
 	logger := logr.FromContextOrDiscard(ctx)
 	b, err := json.Marshal(certImage)
@@ -59,7 +122,7 @@ func (p *pyxisClient) createImage(ctx context.Context, certImage *CertImage) (*C
 
 	logger.V(log.TRC).Info("pyxis URL", "url", req.URL)
 
-	resp, err := p.Client.Do(req)
+	resp, err := p.do(req, false)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create image in pyxis: %w", err)
 	}
@@ -71,15 +134,8 @@ func (p *pyxisClient) createImage(ctx context.Context, certImage *CertImage) (*C
 		return nil, fmt.Errorf("could not read body: %w", err)
 	}
 
-	if resp.StatusCode == http.StatusConflict {
-		return nil, ErrPyxis409StatusCode
-	}
-
 	if ok := checkStatus(resp.StatusCode); !ok {
-		return nil, fmt.Errorf(
-			"status code: %d: body: %s",
-			resp.StatusCode,
-			string(body))
+		return nil, newAPIError("create image", req, resp.StatusCode, body)
 	}
 
 	var newCertImage CertImage
@@ -100,7 +156,7 @@ func (p *pyxisClient) getImage(ctx context.Context, dockerImageDigest string) (*
 
 	logger.V(log.TRC).Info("pyxis URL", "url", req.URL)
 
-	resp, err := p.Client.Do(req)
+	resp, err := p.do(req, true)
 	if err != nil {
 		return nil, fmt.Errorf("could not get image from pyxis: %w", err)
 	}
@@ -113,10 +169,7 @@ func (p *pyxisClient) getImage(ctx context.Context, dockerImageDigest string) (*
 	}
 
 	if ok := checkStatus(resp.StatusCode); !ok {
-		return nil, fmt.Errorf(
-			"status code: %d: body: %s",
-			resp.StatusCode,
-			string(body))
+		return nil, newAPIError("get image", req, resp.StatusCode, body)
 	}
 
 	// using an inline struct since this api's response is in a different format
@@ -149,7 +202,7 @@ func (p *pyxisClient) updateImage(ctx context.Context, certImage *CertImage) (*C
 		return nil, err
 	}
 
-	resp, err := p.Client.Do(req)
+	resp, err := p.do(req, true)
 	if err != nil {
 		return nil, fmt.Errorf("cannot update image in pyxis: %w", err)
 	}
@@ -162,10 +215,7 @@ func (p *pyxisClient) updateImage(ctx context.Context, certImage *CertImage) (*C
 	}
 
 	if ok := checkStatus(resp.StatusCode); !ok {
-		return nil, fmt.Errorf(
-			"status code: %d: body: %s",
-			resp.StatusCode,
-			string(body))
+		return nil, newAPIError("update image", req, resp.StatusCode, body)
 	}
 
 	var updatedCertImage CertImage
@@ -212,15 +262,14 @@ func (p *pyxisClient) FindImagesByDigest(ctx context.Context, digests []string)
 	}
 
 	// make our query
-	httpClient, ok := p.Client.(*http.Client)
-	if !ok {
-		return nil, fmt.Errorf("client could not be used as http.Client")
-	}
-	client := graphql.NewClient(p.getPyxisGraphqlURL(), httpClient)
+	client := p.graphqlClient("find images")
 
 	err := client.Query(ctx, &query, variables)
 	if err != nil {
-		return nil, fmt.Errorf("error while executing find_images query: %v", err)
+		return nil, fmt.Errorf("error while executing find_images query: %w", err)
+	}
+	if err := p.graphqlError("find images", query.FindImages.Error.Status, query.FindImages.Error.Detail); err != nil {
+		return nil, fmt.Errorf("error while executing find_images query: %w", err)
 	}
 
 	images := make([]CertImage, len(query.FindImages.ContainerImage))
@@ -249,7 +298,7 @@ func (p *pyxisClient) createRPMManifest(ctx context.Context, rpmManifest *RPMMan
 
 	logger.V(log.TRC).Info("pyxis URL", "url", req.URL)
 
-	resp, err := p.Client.Do(req)
+	resp, err := p.do(req, false)
 	if err != nil {
 		return nil, fmt.Errorf("could not create rpm manifest in pyxis: %w", err)
 	}
@@ -261,15 +310,8 @@ func (p *pyxisClient) createRPMManifest(ctx context.Context, rpmManifest *RPMMan
 		return nil, fmt.Errorf("could not read body: %w", err)
 	}
 
-	if resp.StatusCode == 409 {
-		return nil, ErrPyxis409StatusCode
-	}
-
 	if ok := checkStatus(resp.StatusCode); !ok {
-		return nil, fmt.Errorf(
-			"status code: %d: body: %s",
-			resp.StatusCode,
-			string(body))
+		return nil, newAPIError("create rpm manifest", req, resp.StatusCode, body)
 	}
 
 	var newRPMManifest RPMManifest
@@ -290,7 +332,7 @@ func (p *pyxisClient) getRPMManifest(ctx context.Context, imageID string) (*RPMM
 
 	logger.V(log.TRC).Info("pyxis URL", "url", req.URL)
 
-	resp, err := p.Client.Do(req)
+	resp, err := p.do(req, true)
 	if err != nil {
 		return nil, fmt.Errorf("could not get rpm manifest from pyxis: %w", err)
 	}
@@ -303,10 +345,7 @@ func (p *pyxisClient) getRPMManifest(ctx context.Context, imageID string) (*RPMM
 	}
 
 	if ok := checkStatus(resp.StatusCode); !ok {
-		return nil, fmt.Errorf(
-			"status code: %d: body: %s",
-			resp.StatusCode,
-			string(body))
+		return nil, newAPIError("get rpm manifest", req, resp.StatusCode, body)
 	}
 
 	var newRPMManifest RPMManifest
@@ -327,7 +366,7 @@ func (p *pyxisClient) GetProject(ctx context.Context) (*CertProject, error) {
 
 	logger.V(log.TRC).Info("pyxis URL", "url", req.URL)
 
-	resp, err := p.Client.Do(req)
+	resp, err := p.do(req, true)
 	if err != nil {
 		return nil, fmt.Errorf("could not get project from pyxis: %v", err)
 	}
@@ -340,10 +379,7 @@ func (p *pyxisClient) GetProject(ctx context.Context) (*CertProject, error) {
 	}
 
 	if ok := checkStatus(resp.StatusCode); !ok {
-		return nil, fmt.Errorf(
-			"status code: %d: body: %s",
-			resp.StatusCode,
-			string(body))
+		return nil, newAPIError("get project", req, resp.StatusCode, body)
 	}
 
 	var certProject CertProject
@@ -354,9 +390,8 @@ func (p *pyxisClient) GetProject(ctx context.Context) (*CertProject, error) {
 	return &certProject, nil
 }
 
-func (p *pyxisClient) updateProject(ctx context.Context, certProject *CertProject) (*CertProject, error) {
-	logger := logr.FromContextOrDiscard(ctx)
-
+// projectPatch returns the patch sent to update certProject.
+func projectPatch(certProject *CertProject) *CertProject {
 	// We cannot send the project type or container type
 	// to pyxis in a Patch. Copy the CertProject and strip type
 	// values to have omitempty skip the key in the JSON patch.
@@ -369,8 +404,13 @@ func (p *pyxisClient) updateProject(ctx context.Context, certProject *CertProjec
 		// Do not copy the Type.
 	}
 	patchCertProject.Container.Type = "" // Truncate this value, too.
+	return patchCertProject
+}
+
+func (p *pyxisClient) updateProject(ctx context.Context, certProject *CertProject) (*CertProject, error) {
+	logger := logr.FromContextOrDiscard(ctx)
 
-	b, err := json.Marshal(patchCertProject)
+	b, err := json.Marshal(projectPatch(certProject))
 	if err != nil {
 		return nil, fmt.Errorf("could not marshal certProject: %w", err)
 	}
@@ -381,7 +421,7 @@ func (p *pyxisClient) updateProject(ctx context.Context, certProject *CertProjec
 
 	logger.V(log.TRC).Info("pyxis URL", "url", req.URL)
 
-	resp, err := p.Client.Do(req)
+	resp, err := p.do(req, true)
 	if err != nil {
 		return nil, fmt.Errorf("could not update project in pyxis: %w", err)
 	}
@@ -394,10 +434,7 @@ func (p *pyxisClient) updateProject(ctx context.Context, certProject *CertProjec
 	}
 
 	if ok := checkStatus(resp.StatusCode); !ok {
-		return nil, fmt.Errorf(
-			"status code: %d: body: %s",
-			resp.StatusCode,
-			string(body))
+		return nil, newAPIError("update project", req, resp.StatusCode, body)
 	}
 
 	var newCertProject CertProject
@@ -418,7 +455,7 @@ func (p *pyxisClient) createTestResults(ctx context.Context, testResults *TestRe
 		return nil, fmt.Errorf("could not create new request: %w", err)
 	}
 
-	resp, err := p.Client.Do(req)
+	resp, err := p.do(req, false)
 	if err != nil {
 		return nil, fmt.Errorf("could not create test results in pyxis: %w", err)
 	}
@@ -431,10 +468,7 @@ func (p *pyxisClient) createTestResults(ctx context.Context, testResults *TestRe
 	}
 
 	if ok := checkStatus(resp.StatusCode); !ok {
-		return nil, fmt.Errorf(
-			"status code: %d: body: %s",
-			resp.StatusCode,
-			string(body))
+		return nil, newAPIError("create test results", req, resp.StatusCode, body)
 	}
 
 	newTestResults := TestResults{}
@@ -459,7 +493,7 @@ func (p *pyxisClient) createArtifact(ctx context.Context, artifact *Artifact) (*
 
 	logger.V(log.TRC).Info("pyxis URL", "url", req.URL)
 
-	resp, err := p.Client.Do(req)
+	resp, err := p.do(req, false)
 	if err != nil {
 		return nil, fmt.Errorf("could not create artifact in pyxis: %w", err)
 	}
@@ -472,10 +506,7 @@ func (p *pyxisClient) createArtifact(ctx context.Context, artifact *Artifact) (*
 	}
 
 	if ok := checkStatus(resp.StatusCode); !ok {
-		return nil, fmt.Errorf(
-			"status code: %d: body: %s",
-			resp.StatusCode,
-			string(body))
+		return nil, newAPIError("create artifact", req, resp.StatusCode, body)
 	}
 
 	var newArtifact Artifact
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr"
 	. "github.com/onsi/ginkgo/v2/dsl/core"
@@ -22,6 +23,11 @@ func TestPyxis(t *testing.T) {
 	RunSpecs(t, "Pyxis Engine Suite")
 }
 
+var _ = BeforeSuite(func() {
+	// Retries do not wait, so that failing requests do not slow the tests down.
+	sleep = func(context.Context, time.Duration) error { return nil }
+})
+
 type localRoundTripper struct {
 	handler http.Handler
 }
@@ -38,4 +38,22 @@ var _ = Describe("Pyxis", func() {
 			})
 		})
 	})
+
+	Context("URLs", func() {
+		It("should use HTTPS for a remote host", func() {
+			client := NewPyxisClient("catalog.redhat.com/api/containers", "", "", nil)
+			Expect(client.getPyxisURL("images")).To(Equal("https://catalog.redhat.com/api/containers/v1/images"))
+			Expect(client.getPyxisGraphqlURL()).To(Equal("https://catalog.redhat.com/api/containers/graphql/"))
+		})
+		It("should use HTTPS for a loopback host without a scheme", func() {
+			for _, host := range []string{"localhost:8080", "127.0.0.1:8080", "[::1]:8080", "localhost"} {
+				client := NewPyxisClient(host, "", "", nil)
+				Expect(client.getPyxisURL("images")).To(Equal("https://" + host + "/v1/images"))
+			}
+		})
+		It("should use the scheme of the host if it has one", func() {
+			client := NewPyxisClient("http://pyxis.example.com:8080", "", "", nil)
+			Expect(client.getPyxisGraphqlURL()).To(Equal("http://pyxis.example.com:8080/graphql/"))
+		})
+	})
 })
@@ -0,0 +1,167 @@
+package pyxis
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/opdev/knex/log"
+
+	"github.com/opdev/container-certification/internal/defaults"
+)
+
+// RetryPolicy configures how requests to Pyxis are retried when Pyxis is
+// unavailable, rate limits the client, or the connection fails. The zero value
+// does not retry.
+type RetryPolicy struct {
+	// MaxRetries is the number of times a request is retried after the first
+	// attempt.
+	MaxRetries int
+	// InitialBackoff is the wait before the first retry. It doubles for each
+	// following retry, with jitter, up to MaxBackoff.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries, and defaults to 30 seconds. A
+	// Retry-After sent by Pyxis is honored even if it is longer.
+	MaxBackoff time.Duration
+	// RetryNonIdempotent also retries requests that create resources, which
+	// may create duplicates if Pyxis received the failed request.
+	RetryNonIdempotent bool
+}
+
+// DefaultRetryPolicy returns the policy used by clients from NewPyxisClient.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:     defaults.DefaultPyxisRetries,
+		InitialBackoff: defaults.DefaultPyxisRetryBackoff,
+		MaxBackoff:     defaults.DefaultPyxisRetryMaxBackoff,
+	}
+}
+
+// backoff returns the wait before retry number retry, counting from 0, with
+// jitter so that clients that failed together do not retry together.
+func (r RetryPolicy) backoff(retry int) time.Duration {
+	maxBackoff := r.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaults.DefaultPyxisRetryMaxBackoff
+	}
+
+	wait := r.InitialBackoff
+	for i := 0; i < retry && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+	// Wait between half and all of the backoff.
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1)) //nolint:gosec // jitter does not need a secure source
+}
+
+// retryableStatus returns true for the status codes of transient failures.
+func retryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the wait a 429 or 503 response asks for in its Retry-After
+// header, given either in seconds or as an HTTP date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// sleep waits for d, or until ctx is done. Tests replace it to avoid waiting.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// do sends req, retrying it according to the retry policy of the client. Requests
+// that are not idempotent are only retried if the policy allows it. The body of
+// req must be replayable, as it is for requests from newRequest.
+func (p *pyxisClient) do(req *http.Request, idempotent bool) (*http.Response, error) {
+	ctx := req.Context()
+	logger := logr.FromContextOrDiscard(ctx)
+
+	retries := p.Retry.MaxRetries
+	if !idempotent && !p.Retry.RetryNonIdempotent {
+		retries = 0
+	}
+	if req.Body != nil && req.GetBody == nil {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("could not replay request body: %w", err)
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		resp, err := p.Client.Do(req)
+		if attempt >= retries || ctx.Err() != nil || (err == nil && !retryableStatus(resp.StatusCode)) {
+			return resp, err
+		}
+
+		wait := p.Retry.backoff(attempt)
+		var reason string
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			if after, ok := retryAfter(resp); ok {
+				wait = after
+				reason += ", Retry-After " + after.String()
+			}
+			// Drain the body so the connection can be reused.
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		logger.V(log.TRC).Info("retrying pyxis request", "method", req.Method, "url", req.URL,
+			"attempt", attempt+1, "maxRetries", retries, "reason", reason, "wait", wait.String())
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
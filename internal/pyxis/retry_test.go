@@ -0,0 +1,133 @@
+package pyxis
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2/dsl/core"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pyxis retries", func() {
+	ctx := context.Background()
+
+	var (
+		pyxisClient *pyxisClient
+		// statuses are returned in order, then 200 for the following requests.
+		statuses []int
+		headers  http.Header
+		bodies   []string
+		waits    []time.Duration
+	)
+
+	BeforeEach(func() {
+		statuses, bodies, waits = nil, nil, nil
+		headers = http.Header{}
+		handler := http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			if request.Body != nil {
+				defer request.Body.Close()
+				body, _ := io.ReadAll(request.Body)
+				bodies = append(bodies, string(body))
+			}
+			response.Header().Set("Content-Type", "application/json")
+			if len(statuses) > 0 {
+				status := statuses[0]
+				statuses = statuses[1:]
+				for key, values := range headers {
+					response.Header()[key] = values
+				}
+				response.WriteHeader(status)
+				return
+			}
+			mustWrite(response, `{"_id":"deadb33f","data":{"find_images":{"error":null,"total":1,"page":0,"data":[{"_id":"deadb33f","certified":true}]}}}`)
+		})
+
+		pyxisClient = NewPyxisClient("my.pyxis.host/api", "my-spiffy-api-token", "my-awesome-project-id", &http.Client{Transport: localRoundTripper{handler: handler}})
+
+		sleep = func(_ context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return nil
+		}
+		DeferCleanup(func() {
+			sleep = func(context.Context, time.Duration) error { return nil }
+		})
+	})
+
+	Context("when Pyxis is temporarily unavailable", func() {
+		It("should retry an idempotent request with backoff", func() {
+			statuses = []int{http.StatusBadGateway, http.StatusInternalServerError}
+			project, err := pyxisClient.GetProject(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(project.ID).To(Equal("deadb33f"))
+			Expect(waits).To(HaveLen(2))
+			Expect(waits[0]).To(BeNumerically("~", 750*time.Millisecond, 250*time.Millisecond))
+			Expect(waits[1]).To(BeNumerically("~", 1500*time.Millisecond, 500*time.Millisecond))
+		})
+		It("should honor Retry-After", func() {
+			statuses = []int{http.StatusTooManyRequests}
+			headers.Set("Retry-After", "7")
+			_, err := pyxisClient.GetProject(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(waits).To(Equal([]time.Duration{7 * time.Second}))
+		})
+		It("should give up after the configured number of retries", func() {
+			statuses = []int{503, 503, 503, 503, 503}
+			_, err := pyxisClient.GetProject(ctx)
+			Expect(err).To(MatchError(ContainSubstring("status code: 503")))
+			Expect(waits).To(HaveLen(DefaultRetryPolicy().MaxRetries))
+		})
+		It("should retry GraphQL queries", func() {
+			statuses = []int{http.StatusServiceUnavailable}
+			images, err := pyxisClient.FindImagesByDigest(ctx, []string{"sha256:deadb33f"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(images).To(HaveLen(1))
+			Expect(waits).To(HaveLen(1))
+			Expect(bodies[0]).To(Equal(bodies[1]))
+		})
+	})
+
+	Context("when a request that creates a resource fails", func() {
+		It("should not retry it by default", func() {
+			statuses = []int{http.StatusServiceUnavailable}
+			_, err := pyxisClient.createArtifact(ctx, &Artifact{Filename: "preflight.log"})
+			Expect(err).To(HaveOccurred())
+			Expect(waits).To(BeEmpty())
+		})
+		It("should retry it, replaying the body, when the policy allows it", func() {
+			pyxisClient.Retry.RetryNonIdempotent = true
+			statuses = []int{http.StatusServiceUnavailable}
+			_, err := pyxisClient.createArtifact(ctx, &Artifact{Filename: "preflight.log"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bodies).To(HaveLen(2))
+			Expect(bodies[1]).To(Equal(bodies[0]))
+			Expect(bodies[1]).To(ContainSubstring("preflight.log"))
+		})
+	})
+
+	Context("when Pyxis rejects a request", func() {
+		It("should not retry it", func() {
+			statuses = []int{http.StatusUnauthorized}
+			_, err := pyxisClient.GetProject(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(waits).To(BeEmpty())
+		})
+	})
+
+	Context("when retries are disabled", func() {
+		It("should send each request once", func() {
+			pyxisClient.Retry = RetryPolicy{}
+			statuses = []int{http.StatusServiceUnavailable}
+			_, err := pyxisClient.GetProject(ctx)
+			Expect(err).To(HaveOccurred())
+			Expect(waits).To(BeEmpty())
+		})
+	})
+
+	It("should cap the backoff", func() {
+		policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 4 * time.Second}
+		Expect(policy.backoff(10)).To(BeNumerically("<=", 4*time.Second))
+		Expect(policy.backoff(10)).To(BeNumerically(">=", 2*time.Second))
+	})
+})
@@ -5,47 +5,53 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/go-logr/logr"
 	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/opdev/knex/log"
 )
 
 var defaultRegistryAlias = "docker.io"
 
 // SubmitResults takes certInput and sends requests to Pyxis to create or update entries
-// based on certInput.
+// based on certInput. If certInput has a Journal, each completed step is recorded in it,
+// and the steps it records from a previous attempt to submit the same image to the same
+// project are skipped, unless what they submit changed. Artifacts are recorded by filename
+// and content, so an artifact is only created once.
 func (p *pyxisClient) SubmitResults(ctx context.Context, certInput *CertificationInput) (*CertificationResults, error) {
+	logger := logr.FromContextOrDiscard(ctx)
 	var err error
 
 	certProject := certInput.CertProject
 	certImage := certInput.CertImage
 
-	// Submission effectively starts the certification process, so switch
-	// the status to reflect this if needed. This only needs to be done for net new projects.
-	// Existing projects that are in "In Progress" can stay "In Progress" until they moved to "Published" which is triggered
-	// once an image in a project is moved to "Published" status. The status on the project would stay in "Published" status,
-	// unless the partner decides to un-publish all of their images. At that point backed systems/processes would move
-	// the project back to "In Process" and there would still be nothing that preflight need to update on the project.
-	if certProject.CertificationStatus == "Started" {
-		certProject.CertificationStatus = "In Progress"
+	if err := prepareSubmission(certProject, certImage); err != nil {
+		return nil, err
 	}
 
-	// You must have an existing repository.
-	if len(certImage.Repositories) == 0 {
-		return nil, fmt.Errorf("certImage has not been properly populated")
+	journal := certInput.Journal
+	if journal == nil {
+		journal = &Journal{}
+	}
+	resuming, err := journal.begin(p.ProjectID, certImage)
+	if err != nil {
+		return nil, err
+	}
+	if resuming {
+		logger.Info("resuming submission from journal", "journal", journal.path, "completedSteps", len(journal.Steps))
 	}
-
-	// Always set the project's metadata to match the image that we're certifying. These values will always be sent
-	// to pyxis which has the validation rules on if the values can be updated, and will throw an exception if they
-	// are not allowed to be updated, ie if the images/projects are already published.
-	// Also normalizing index.docker.io to docker.io for the certProject
-	certProject.Container.Registry = normalizeDockerRegistry(certImage.Repositories[0].Registry)
-	certProject.Container.Repository = certImage.Repositories[0].Repository
 
 	// always update the project no matter the status to ensure the dockerconfig preflight used to pull the image
 	// is the dockerfile that resides on the project and other backend processes ie clair use the same file
 	// Note: users no longer have the ability to update their project's dockerconfig in connect
-	certProject, err = p.updateProject(ctx, certProject)
-	if err != nil {
-		return nil, fmt.Errorf("could not update project: %v", err)
+	if _, ok := journal.completed(stepUpdateProject, ""); !ok {
+		certProject, err = p.updateProject(ctx, certProject)
+		if err != nil {
+			return nil, fmt.Errorf("could not update project: %w", err)
+		}
+		if err := journal.record(JournalStep{Step: stepUpdateProject, ID: certProject.ID}); err != nil {
+			return nil, err
+		}
 	}
 
 	// store the original digest so that we can pull the image later
@@ -55,45 +61,42 @@ func (p *pyxisClient) SubmitResults(ctx context.Context, certInput *Certificatio
 	// store the certification status for this execution, in case a previous execution failed and we need to patch the image
 	certified := certInput.CertImage.Certified
 
-	// normalizing index.docker.io to docker.io for the certImage
-	certImage.Repositories[0].Registry = normalizeDockerRegistry(certImage.Repositories[0].Registry)
-
-	// Create the image, or get it if it already exists.
-	certImage, err = p.createImage(ctx, certImage)
+	// The image is created again if it changed, such as whether it is certified.
+	imageHash, err := contentHash(certImage)
 	if err != nil {
-		if !errors.Is(err, ErrPyxis409StatusCode) {
-			return nil, fmt.Errorf("could not create image: %v", err)
-		}
-		certImage, err = p.getImage(ctx, originalImageDigest)
+		return nil, err
+	}
+	if step, ok := journal.completed(stepCreateImage, imageHash); ok {
+		logger.V(log.DBG).Info("image already submitted", "imageID", step.ID)
+		submittedImage := *certImage
+		submittedImage.ID = step.ID
+		certImage = &submittedImage
+	} else {
+		certImage, err = p.createOrGetImage(ctx, certImage, originalImageDigest, certified)
 		if err != nil {
-			return nil, fmt.Errorf("could not get image: %v", err)
+			return nil, err
 		}
-
-		// checking to see if the original value is certified and the previous value is not certified,
-		// this would indicate that a partner is running preflight again, and during the first run there was a timeout/error
-		// in a check that interacts with pyxis and we need to correct the certified value for the image
-		if certified && !certImage.Certified {
-			// change the certified value to `true`
-			certImage.Certified = certified
-
-			certImage, err = p.updateImage(ctx, certImage)
-			if err != nil {
-				return nil, fmt.Errorf("could not update image: %v", err)
-			}
+		if err := journal.record(JournalStep{Step: stepCreateImage, ID: certImage.ID, SHA256: imageHash}); err != nil {
+			return nil, err
 		}
 	}
 
 	// Create the RPM manifest, or get it if it already exists.
 	rpmManifest := certInput.RpmManifest
 	rpmManifest.ImageID = certImage.ID
-	_, err = p.createRPMManifest(ctx, rpmManifest)
-	if err != nil {
-		if !errors.Is(err, ErrPyxis409StatusCode) {
-			return nil, fmt.Errorf("could not create rpm manifest: %v", err)
-		}
-		_, err = p.getRPMManifest(ctx, rpmManifest.ImageID)
+	if _, ok := journal.completed(stepCreateRPMManifest, ""); !ok {
+		manifest, err := p.createRPMManifest(ctx, rpmManifest)
 		if err != nil {
-			return nil, fmt.Errorf("could not get rpm manifest: %v", err)
+			if !errors.Is(err, ErrPyxis409StatusCode) {
+				return nil, fmt.Errorf("could not create rpm manifest: %w", err)
+			}
+			manifest, err = p.getRPMManifest(ctx, rpmManifest.ImageID)
+			if err != nil {
+				return nil, fmt.Errorf("could not get rpm manifest: %w", err)
+			}
+		}
+		if err := journal.record(JournalStep{Step: stepCreateRPMManifest, ID: manifest.ID}); err != nil {
+			return nil, err
 		}
 	}
 
@@ -101,17 +104,43 @@ func (p *pyxisClient) SubmitResults(ctx context.Context, certInput *Certificatio
 	artifacts := certInput.Artifacts
 	for _, artifact := range artifacts {
 		artifact.ImageID = certImage.ID
-		if _, err := p.createArtifact(ctx, &artifact); err != nil {
-			return nil, fmt.Errorf("could not create artifact: %s: %v", artifact.Filename, err)
+		if step, ok := journal.completedArtifact(&artifact); ok {
+			logger.V(log.DBG).Info("artifact already submitted", "filename", artifact.Filename, "artifactID", step.ID)
+			continue
+		}
+		created, err := p.createArtifact(ctx, &artifact)
+		if err != nil {
+			return nil, fmt.Errorf("could not create artifact: %s: %w", artifact.Filename, err)
+		}
+		if err := journal.recordArtifact(&artifact, created.ID); err != nil {
+			return nil, err
 		}
 	}
 
 	// Create the test results.
 	testResults := certInput.TestResults
 	testResults.ImageID = certImage.ID
-	testResults, err = p.createTestResults(ctx, testResults)
+	// The test results are created again if the outcome of a check changed.
+	resultsHash, err := contentHash(struct {
+		Passed  bool `json:"passed"`
+		Results any  `json:"results"`
+	}{testResults.Passed, testResults.Results})
 	if err != nil {
-		return nil, fmt.Errorf("could not create test results: %v", err)
+		return nil, err
+	}
+	if step, ok := journal.completed(stepCreateTestResults, resultsHash); ok {
+		logger.V(log.DBG).Info("test results already submitted", "testResultsID", step.ID)
+		submittedResults := *testResults
+		submittedResults.ID = step.ID
+		testResults = &submittedResults
+	} else {
+		testResults, err = p.createTestResults(ctx, testResults)
+		if err != nil {
+			return nil, fmt.Errorf("could not create test results: %w", err)
+		}
+		if err := journal.record(JournalStep{Step: stepCreateTestResults, ID: testResults.ID, SHA256: resultsHash}); err != nil {
+			return nil, err
+		}
 	}
 
 	// Return the results with up-to-date information.
@@ -122,6 +151,68 @@ func (p *pyxisClient) SubmitResults(ctx context.Context, certInput *Certificatio
 	}, nil
 }
 
+// createOrGetImage creates certImage, or gets it if it already exists, correcting
+// whether it is certified if a previous submission failed to.
+func (p *pyxisClient) createOrGetImage(ctx context.Context, certImage *CertImage, originalImageDigest string, certified bool) (*CertImage, error) {
+	// Create the image, or get it if it already exists.
+	createdImage, err := p.createImage(ctx, certImage)
+	if err == nil {
+		return createdImage, nil
+	}
+	if !errors.Is(err, ErrPyxis409StatusCode) {
+		return nil, fmt.Errorf("could not create image: %w", err)
+	}
+	existingImage, err := p.getImage(ctx, originalImageDigest)
+	if err != nil {
+		return nil, fmt.Errorf("could not get image: %w", err)
+	}
+
+	// checking to see if the original value is certified and the previous value is not certified,
+	// this would indicate that a partner is running preflight again, and during the first run there was a timeout/error
+	// in a check that interacts with pyxis and we need to correct the certified value for the image
+	if certified && !existingImage.Certified {
+		// change the certified value to `true`
+		existingImage.Certified = certified
+
+		existingImage, err = p.updateImage(ctx, existingImage)
+		if err != nil {
+			return nil, fmt.Errorf("could not update image: %w", err)
+		}
+	}
+	return existingImage, nil
+}
+
+// prepareSubmission updates certProject and certImage with the values that are
+// submitted for them, which are derived from each other.
+func prepareSubmission(certProject *CertProject, certImage *CertImage) error {
+	// Submission effectively starts the certification process, so switch
+	// the status to reflect this if needed. This only needs to be done for net new projects.
+	// Existing projects that are in "In Progress" can stay "In Progress" until they moved to "Published" which is triggered
+	// once an image in a project is moved to "Published" status. The status on the project would stay in "Published" status,
+	// unless the partner decides to un-publish all of their images. At that point backed systems/processes would move
+	// the project back to "In Process" and there would still be nothing that preflight need to update on the project.
+	if certProject.CertificationStatus == "Started" {
+		certProject.CertificationStatus = "In Progress"
+	}
+
+	// You must have an existing repository.
+	if len(certImage.Repositories) == 0 {
+		return fmt.Errorf("certImage has not been properly populated")
+	}
+
+	// Always set the project's metadata to match the image that we're certifying. These values will always be sent
+	// to pyxis which has the validation rules on if the values can be updated, and will throw an exception if they
+	// are not allowed to be updated, ie if the images/projects are already published.
+	// Also normalizing index.docker.io to docker.io for the certProject
+	certProject.Container.Registry = normalizeDockerRegistry(certImage.Repositories[0].Registry)
+	certProject.Container.Repository = certImage.Repositories[0].Repository
+
+	// normalizing index.docker.io to docker.io for the certImage
+	certImage.Repositories[0].Registry = normalizeDockerRegistry(certImage.Repositories[0].Registry)
+
+	return nil
+}
+
 // normalizeDockerRegistry sets registry to the value we get from certImage from crane and then normalizes
 // index.docker.io to docker.io so project/image info shows properly in the Red Hat Catalog and other backend systems (Clair)
 func normalizeDockerRegistry(registry string) string {
@@ -12,6 +12,8 @@ type CertificationInput struct {
 	TestResults *TestResults
 	RpmManifest *RPMManifest
 	Artifacts   []Artifact
+	// Journal records the progress of the submission, so that it can be resumed.
+	Journal *Journal
 }
 
 type CertificationResults struct {
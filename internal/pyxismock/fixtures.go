@@ -0,0 +1,40 @@
+package pyxismock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Fixtures are the resources a Server starts with. Projects must exist before
+// results can be submitted to them, and images are found by the find_images query,
+// e.g. the certified UBI images that BasedOnUbi looks for. Resources are written
+// the way Pyxis returns them, e.g.
+//
+//	{
+//	  "api_keys": ["my-api-key"],
+//	  "projects": [{"_id": "my-project", "certification_status": "Started", "container": {"type": "container"}}],
+//	  "images": [{"_id": "ubi", "certified": true, "uncompressed_top_layer_id": "sha256:...",
+//	              "repositories": [{"registry": "registry.access.redhat.com"}]}]
+//	}
+type Fixtures struct {
+	// APIKeys are the API keys accepted by the REST endpoints. If empty, any key is
+	// accepted.
+	APIKeys      []string   `json:"api_keys,omitempty"`
+	Projects     []Document `json:"projects,omitempty"`
+	Images       []Document `json:"images,omitempty"`
+	RPMManifests []Document `json:"rpm_manifests,omitempty"`
+}
+
+// LoadFixtures reads Fixtures from the JSON file at path.
+func LoadFixtures(path string) (Fixtures, error) {
+	var fixtures Fixtures
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fixtures, fmt.Errorf("could not read fixtures: %w", err)
+	}
+	if err := json.Unmarshal(b, &fixtures); err != nil {
+		return fixtures, fmt.Errorf("could not parse fixtures %s: %w", path, err)
+	}
+	return fixtures, nil
+}
@@ -0,0 +1,491 @@
+package pyxismock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// defaultPageSize is the page size of find_images when the query does not set one,
+// as in Pyxis.
+const defaultPageSize = 50
+
+// serveGraphQL answers GraphQL queries for find_images. Only queries are supported,
+// without fragments or directives, which is all the pyxis client sends. The response
+// has the fields selected by the query and no others, as the client rejects fields it
+// did not ask for.
+func (s *Server) serveGraphQL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "The method is not allowed for the requested URL.")
+		return
+	}
+
+	var request struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "The request body is not a GraphQL request.")
+		return
+	}
+
+	selections, err := parseQuery(request.Query, request.Variables)
+	if err != nil {
+		writeGraphQLError(w, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := Document{}
+	for _, f := range selections {
+		switch f.name {
+		case "find_images":
+			data[f.key] = project(s.findImages(f.args), f.selections)
+		default:
+			writeGraphQLError(w, fmt.Errorf("cannot query field %q on type \"Query\"", f.name))
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, Document{"data": data})
+}
+
+// writeGraphQLError writes err as GraphQL reports errors, in a successful response.
+func writeGraphQLError(w http.ResponseWriter, err error) {
+	writeJSON(w, http.StatusOK, Document{"errors": []Document{{"message": err.Error()}}})
+}
+
+// findImages returns the page of images matching the filter argument, the way
+// find_images returns them.
+func (s *Server) findImages(args map[string]any) Document {
+	filter, _ := args["filter"].(map[string]any)
+	matched := []any{}
+	for _, image := range s.images {
+		if filter == nil || matchFilter(image, filter) {
+			matched = append(matched, image)
+		}
+	}
+
+	pageSize := intArg(args["page_size"], defaultPageSize)
+	page := intArg(args["page"], 0)
+	data := []any{}
+	if start := page * pageSize; pageSize > 0 && start >= 0 && start < len(matched) {
+		end := start + pageSize
+		if end > len(matched) {
+			end = len(matched)
+		}
+		data = matched[start:end]
+	}
+
+	return Document{"data": data, "error": nil, "page": page, "page_size": pageSize, "total": len(matched)}
+}
+
+func intArg(arg any, def int) int {
+	if n, ok := arg.(float64); ok {
+		return int(n)
+	}
+	return def
+}
+
+// matchFilter reports whether doc matches a find_images filter, e.g.
+// {and: [{docker_image_digest: {in: [...]}}, {repositories: {registry: {eq: ...}}}]}.
+// The and, or, eq, ne, in and nin operators are supported. A filter on a field that
+// is a list matches if any element of the list matches.
+func matchFilter(doc Document, filter map[string]any) bool {
+	for key, condition := range filter {
+		switch key {
+		case "and":
+			filters, _ := condition.([]any)
+			for _, f := range filters {
+				if f, _ := f.(map[string]any); !matchFilter(doc, f) {
+					return false
+				}
+			}
+		case "or":
+			filters, _ := condition.([]any)
+			matched := false
+			for _, f := range filters {
+				if f, _ := f.(map[string]any); matchFilter(doc, f) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false
+			}
+		default:
+			if !matchField(doc[key], condition) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchField(value any, condition any) bool {
+	operators, ok := condition.(map[string]any)
+	if !ok {
+		return false
+	}
+	if values, ok := value.([]any); ok {
+		for _, v := range values {
+			if matchField(v, condition) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for op, arg := range operators {
+		var matched bool
+		switch op {
+		case "eq":
+			matched = equal(value, arg)
+		case "ne":
+			matched = !equal(value, arg)
+		case "in", "nin":
+			list, _ := arg.([]any)
+			for _, v := range list {
+				if equal(value, v) {
+					matched = true
+					break
+				}
+			}
+			if op == "nin" {
+				matched = !matched
+			}
+		default:
+			// A filter on a field of a nested object.
+			nested, ok := value.(map[string]any)
+			matched = ok && matchField(nested[op], arg)
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func equal(a, b any) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// project returns the fields of value selected by selections.
+func project(value any, selections []field) any {
+	if len(selections) == 0 {
+		return value
+	}
+	switch v := value.(type) {
+	case []any:
+		projected := make([]any, 0, len(v))
+		for _, elem := range v {
+			projected = append(projected, project(elem, selections))
+		}
+		return projected
+	case map[string]any:
+		projected := Document{}
+		for _, f := range selections {
+			projected[f.key] = project(v[f.name], f.selections)
+		}
+		return projected
+	}
+	return value
+}
+
+// field is a field selected by a GraphQL query.
+type field struct {
+	// key is the alias of the field, or its name.
+	key        string
+	name       string
+	args       map[string]any
+	selections []field
+}
+
+// parseQuery parses a GraphQL query, returning the fields it selects on the Query
+// type, with variables substituted in their arguments.
+func parseQuery(query string, variables map[string]any) ([]field, error) {
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{tokens: tokens, variables: variables}
+
+	if t := p.peek(); t.kind == tokenName {
+		if t.text != "query" {
+			return nil, fmt.Errorf("unsupported operation %s, only queries are supported", t.text)
+		}
+		p.next()
+		if p.peek().kind == tokenName {
+			p.next()
+		}
+		if p.peekPunct("(") {
+			// Variable definitions are not needed, as variables are not validated.
+			if err := p.skipBalanced("(", ")"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q after the query", t.text)
+	}
+	return selections, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenName
+	tokenPunct
+	tokenString
+	tokenNumber
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// lex splits a GraphQL document into tokens. Commas are insignificant in GraphQL,
+// and are skipped like whitespace.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.IndexByte("{}()[]:$!=@", c) >= 0:
+			tokens = append(tokens, token{kind: tokenPunct, text: string(c)})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			var s string
+			if err := json.Unmarshal([]byte(src[i:end+1]), &s); err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %w", i, err)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: s})
+			i = end + 1
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(src) && strings.IndexByte("0123456789.eE+-", src[end]) >= 0 {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[i:end]})
+			i = end
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			end := i + 1
+			for end < len(src) && (src[end] == '_' || (src[end] >= 'a' && src[end] <= 'z') ||
+				(src[end] >= 'A' && src[end] <= 'Z') || (src[end] >= '0' && src[end] <= '9')) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenName, text: src[i:end]})
+			i = end
+		default:
+			return nil, fmt.Errorf("unsupported character %q at offset %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens    []token
+	pos       int
+	variables map[string]any
+}
+
+func (p *parser) peek() token {
+	if p.pos >= len(p.tokens) {
+		return token{kind: tokenEOF}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) peekPunct(punct string) bool {
+	t := p.peek()
+	return t.kind == tokenPunct && t.text == punct
+}
+
+func (p *parser) expectPunct(punct string) error {
+	if t := p.next(); t.kind != tokenPunct || t.text != punct {
+		return fmt.Errorf("expected %q, got %q", punct, t.text)
+	}
+	return nil
+}
+
+func (p *parser) expectName() (string, error) {
+	t := p.next()
+	if t.kind != tokenName {
+		return "", fmt.Errorf("expected a name, got %q", t.text)
+	}
+	return t.text, nil
+}
+
+// skipBalanced skips the tokens from open to its matching close.
+func (p *parser) skipBalanced(open, closing string) error {
+	depth := 0
+	for {
+		t := p.next()
+		switch {
+		case t.kind == tokenEOF:
+			return fmt.Errorf("expected %q", closing)
+		case t.kind == tokenPunct && t.text == open:
+			depth++
+		case t.kind == tokenPunct && t.text == closing:
+			if depth--; depth == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+func (p *parser) parseSelectionSet() ([]field, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	var selections []field
+	for !p.peekPunct("}") {
+		f, err := p.parseField()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, f)
+	}
+	p.next()
+	return selections, nil
+}
+
+func (p *parser) parseField() (field, error) {
+	var f field
+	name, err := p.expectName()
+	if err != nil {
+		return f, err
+	}
+	f.key, f.name = name, name
+	if p.peekPunct(":") {
+		p.next()
+		if f.name, err = p.expectName(); err != nil {
+			return f, err
+		}
+	}
+
+	if p.peekPunct("(") {
+		p.next()
+		f.args = map[string]any{}
+		for !p.peekPunct(")") {
+			arg, err := p.expectName()
+			if err != nil {
+				return f, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return f, err
+			}
+			if f.args[arg], err = p.parseValue(); err != nil {
+				return f, err
+			}
+		}
+		p.next()
+	}
+
+	if p.peekPunct("{") {
+		if f.selections, err = p.parseSelectionSet(); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// parseValue parses an input value, returning it as encoding/json would decode it.
+func (p *parser) parseValue() (any, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return t.text, nil
+	case tokenNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return n, nil
+	case tokenName:
+		switch t.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		// An enum value.
+		return t.text, nil
+	case tokenPunct:
+		switch t.text {
+		case "$":
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			return p.variables[name], nil
+		case "[":
+			list := []any{}
+			for !p.peekPunct("]") {
+				if p.peek().kind == tokenEOF {
+					return nil, fmt.Errorf("expected %q", "]")
+				}
+				v, err := p.parseValue()
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			p.next()
+			return list, nil
+		case "{":
+			object := map[string]any{}
+			for !p.peekPunct("}") {
+				name, err := p.expectName()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expectPunct(":"); err != nil {
+					return nil, err
+				}
+				if object[name], err = p.parseValue(); err != nil {
+					return nil, err
+				}
+			}
+			p.next()
+			return object, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
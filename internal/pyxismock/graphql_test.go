@@ -0,0 +1,82 @@
+package pyxismock
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GraphQL", func() {
+	Context("When parsing a query", func() {
+		It("should substitute variables in the arguments", func() {
+			selections, err := parseQuery(`query($digests:[String!]!){find_images(filter: {docker_image_digest:{in:$digests}}, page_size: 10){data{_id,certified},total}}`,
+				map[string]any{"digests": []any{"sha256:1234"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(selections).To(HaveLen(1))
+			Expect(selections[0].name).To(Equal("find_images"))
+			Expect(selections[0].args).To(Equal(map[string]any{
+				"filter":    map[string]any{"docker_image_digest": map[string]any{"in": []any{"sha256:1234"}}},
+				"page_size": float64(10),
+			}))
+			Expect(selections[0].selections).To(HaveLen(2))
+			Expect(selections[0].selections[0].selections).To(HaveLen(2))
+		})
+		It("should reject mutations", func() {
+			_, err := parseQuery(`mutation{create_image(input: {}){_id}}`, nil)
+			Expect(err).To(MatchError(ContainSubstring("only queries are supported")))
+		})
+		It("should reject an incomplete query", func() {
+			_, err := parseQuery(`{find_images{data{_id}`, nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("When matching a filter", func() {
+		image := Document{
+			"docker_image_digest":       "sha256:1234",
+			"uncompressed_top_layer_id": "sha256:abcd",
+			"repositories": []any{
+				map[string]any{"registry": "quay.io"},
+				map[string]any{"registry": "registry.access.redhat.com"},
+			},
+		}
+
+		It("should match any element of a list", func() {
+			Expect(matchFilter(image, map[string]any{
+				"and": []any{
+					map[string]any{"repositories": map[string]any{"registry": map[string]any{"in": []any{"registry.access.redhat.com"}}}},
+					map[string]any{"uncompressed_top_layer_id": map[string]any{"eq": "sha256:abcd"}},
+				},
+			})).To(BeTrue())
+		})
+		It("should not match when a condition fails", func() {
+			Expect(matchFilter(image, map[string]any{
+				"and": []any{
+					map[string]any{"repositories": map[string]any{"registry": map[string]any{"in": []any{"docker.io"}}}},
+					map[string]any{"uncompressed_top_layer_id": map[string]any{"eq": "sha256:abcd"}},
+				},
+			})).To(BeFalse())
+			Expect(matchFilter(image, map[string]any{"docker_image_digest": map[string]any{"nin": []any{"sha256:1234"}}})).To(BeFalse())
+		})
+		It("should match when any alternative matches", func() {
+			Expect(matchFilter(image, map[string]any{
+				"or": []any{
+					map[string]any{"docker_image_digest": map[string]any{"eq": "sha256:5678"}},
+					map[string]any{"docker_image_digest": map[string]any{"ne": "sha256:5678"}},
+				},
+			})).To(BeTrue())
+		})
+	})
+
+	Context("When projecting a result", func() {
+		It("should only keep the selected fields", func() {
+			selections, err := parseQuery(`{find_images{data{_id,grades:freshness_grades{grade}}}}`, nil)
+			Expect(err).ToNot(HaveOccurred())
+			result := Document{"data": []any{
+				map[string]any{"_id": "1234", "certified": true, "freshness_grades": []any{map[string]any{"grade": "A", "start_date": "2022-05-03"}}},
+			}, "total": 1}
+			Expect(project(result, selections[0].selections)).To(Equal(Document{"data": []any{
+				Document{"_id": "1234", "grades": []any{Document{"grade": "A"}}},
+			}}))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package pyxismock
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestPyxisMock(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Pyxis Mock Suite")
+}
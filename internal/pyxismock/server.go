@@ -0,0 +1,440 @@
+// Package pyxismock is a stateful, in-memory stand-in for the parts of the Pyxis
+// API used by the pyxis client: the certification projects, images, RPM manifests,
+// test results and artifacts REST endpoints, and the find_images GraphQL query. It
+// lets a submission, or the checks that query Pyxis, run with no network, e.g.
+// against a server started by pyxis-mock serve and --pyxis-host localhost:8080.
+package pyxismock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Document is a Pyxis resource, as it is sent and received in JSON.
+type Document = map[string]any
+
+// Server implements the Pyxis API over an in-memory store. The API is served under
+// any path prefix, so it works with whichever path the pyxis host names, e.g.
+// /api/containers/v1/images and /v1/images are the same endpoint.
+type Server struct {
+	mu sync.Mutex
+
+	apiKeys      map[string]bool
+	projects     map[string]Document
+	images       []Document
+	rpmManifests map[string]Document // by image ID
+	testResults  []Document
+	artifacts    []Document
+	lastID       int
+}
+
+// New returns a Server seeded with fixtures.
+func New(fixtures Fixtures) *Server {
+	s := &Server{
+		apiKeys:      map[string]bool{},
+		projects:     map[string]Document{},
+		rpmManifests: map[string]Document{},
+	}
+	s.Seed(fixtures)
+	return s
+}
+
+// Seed adds the API keys and resources of fixtures to the store. Resources are
+// copied, and those without an _id are given one.
+func (s *Server) Seed(fixtures Fixtures) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range fixtures.APIKeys {
+		s.apiKeys[key] = true
+	}
+	for _, project := range fixtures.Projects {
+		project = s.withID(clone(project))
+		s.projects[project["_id"].(string)] = project
+	}
+	for _, image := range fixtures.Images {
+		s.images = append(s.images, s.withID(clone(image)))
+	}
+	for _, manifest := range fixtures.RPMManifests {
+		manifest = s.withID(clone(manifest))
+		imageID, _ := manifest["image_id"].(string)
+		s.rpmManifests[imageID] = manifest
+	}
+}
+
+// Project returns a copy of the project with id.
+func (s *Server) Project(id string) (Document, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	project, ok := s.projects[id]
+	if !ok {
+		return nil, false
+	}
+	return clone(project), true
+}
+
+// Images returns a copy of the images, in the order they were created.
+func (s *Server) Images() []Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return cloneAll(s.images)
+}
+
+// RPMManifest returns a copy of the RPM manifest of the image with imageID.
+func (s *Server) RPMManifest(imageID string) (Document, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	manifest, ok := s.rpmManifests[imageID]
+	if !ok {
+		return nil, false
+	}
+	return clone(manifest), true
+}
+
+// TestResults returns a copy of the test results, in the order they were created.
+func (s *Server) TestResults() []Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return cloneAll(s.testResults)
+}
+
+// Artifacts returns a copy of the artifacts, in the order they were created.
+func (s *Server) Artifacts() []Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return cloneAll(s.artifacts)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Body != nil {
+		defer r.Body.Close()
+	}
+
+	if strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/graphql") {
+		s.serveGraphQL(w, r)
+		return
+	}
+
+	_, path, ok := strings.Cut(r.URL.Path, "/v1/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "The requested URL was not found on the server.")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.apiKeys) > 0 && !s.apiKeys[r.Header.Get("X-API-KEY")] {
+		writeError(w, http.StatusUnauthorized, "Invalid API key.")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch {
+	case match(parts, "images"):
+		s.route(w, r, map[string]func(){
+			http.MethodPost: func() { s.createImage(w, r) },
+		})
+	case match(parts, "images", "id", "*"):
+		s.route(w, r, map[string]func(){
+			http.MethodGet:   func() { s.getImage(w, parts[2]) },
+			http.MethodPatch: func() { s.updateImage(w, r, parts[2]) },
+		})
+	case match(parts, "images", "id", "*", "rpm-manifest"):
+		s.route(w, r, map[string]func(){
+			http.MethodGet:  func() { s.getRPMManifest(w, parts[2]) },
+			http.MethodPost: func() { s.createRPMManifest(w, r, parts[2]) },
+		})
+	case match(parts, "projects", "certification", "id", "*"):
+		s.route(w, r, map[string]func(){
+			http.MethodGet:   func() { s.getProject(w, parts[3]) },
+			http.MethodPatch: func() { s.updateProject(w, r, parts[3]) },
+		})
+	case match(parts, "projects", "certification", "id", "*", "images"):
+		s.route(w, r, map[string]func(){
+			http.MethodGet: func() { s.listProjectImages(w, r, parts[3]) },
+		})
+	case match(parts, "projects", "certification", "id", "*", "test-results"):
+		s.route(w, r, map[string]func(){
+			http.MethodPost: func() { s.createProjectResource(w, r, parts[3], &s.testResults) },
+		})
+	case match(parts, "projects", "certification", "id", "*", "artifacts"):
+		s.route(w, r, map[string]func(){
+			http.MethodPost: func() { s.createProjectResource(w, r, parts[3], &s.artifacts) },
+		})
+	default:
+		writeError(w, http.StatusNotFound, "The requested URL was not found on the server.")
+	}
+}
+
+// match reports whether the path parts match pattern, where * matches any part.
+func match(parts []string, pattern ...string) bool {
+	if len(parts) != len(pattern) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// route calls the handler for the method of r.
+func (s *Server) route(w http.ResponseWriter, r *http.Request, handlers map[string]func()) {
+	handler, ok := handlers[r.Method]
+	if !ok {
+		writeError(w, http.StatusMethodNotAllowed, "The method is not allowed for the requested URL.")
+		return
+	}
+	handler()
+}
+
+func (s *Server) createImage(w http.ResponseWriter, r *http.Request) {
+	image, ok := readDocument(w, r)
+	if !ok {
+		return
+	}
+	for _, existing := range s.images {
+		if existing["docker_image_digest"] == image["docker_image_digest"] && existing["architecture"] == image["architecture"] {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Image with docker_image_digest %v and architecture %v already exists.", image["docker_image_digest"], image["architecture"]))
+			return
+		}
+	}
+
+	image = s.withID(image)
+	s.images = append(s.images, image)
+	writeJSON(w, http.StatusCreated, image)
+}
+
+func (s *Server) getImage(w http.ResponseWriter, id string) {
+	image := s.findImage(id)
+	if image == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Image %s not found.", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, image)
+}
+
+func (s *Server) updateImage(w http.ResponseWriter, r *http.Request, id string) {
+	image := s.findImage(id)
+	if image == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Image %s not found.", id))
+		return
+	}
+	patch, ok := readDocument(w, r)
+	if !ok {
+		return
+	}
+	merge(image, patch)
+	image["_id"] = id
+	writeJSON(w, http.StatusOK, image)
+}
+
+func (s *Server) findImage(id string) Document {
+	for _, image := range s.images {
+		if image["_id"] == id {
+			return image
+		}
+	}
+	return nil
+}
+
+// listProjectImages lists the images matching the filter query parameter, a list of
+// field==value conditions separated by semicolons. Images record their project in
+// cert_project, and images without one are listed for every project.
+func (s *Server) listProjectImages(w http.ResponseWriter, r *http.Request, projectID string) {
+	var conditions [][2]string
+	if filter := r.URL.Query().Get("filter"); filter != "" {
+		for _, condition := range strings.Split(filter, ";") {
+			field, value, ok := strings.Cut(condition, "==")
+			if !ok {
+				writeError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported filter %q.", condition))
+				return
+			}
+			conditions = append(conditions, [2]string{field, value})
+		}
+	}
+
+	images := []Document{}
+	for _, image := range s.images {
+		if project, ok := image["cert_project"]; ok && project != projectID {
+			continue
+		}
+		matched := true
+		for _, condition := range conditions {
+			if fmt.Sprint(lookup(image, condition[0])) != condition[1] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			images = append(images, image)
+		}
+	}
+	writeJSON(w, http.StatusOK, Document{"data": images, "page": 0, "page_size": len(images), "total": len(images)})
+}
+
+func (s *Server) createRPMManifest(w http.ResponseWriter, r *http.Request, imageID string) {
+	if s.findImage(imageID) == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Image %s not found.", imageID))
+		return
+	}
+	if _, ok := s.rpmManifests[imageID]; ok {
+		writeError(w, http.StatusConflict, fmt.Sprintf("RPM manifest for image %s already exists.", imageID))
+		return
+	}
+	manifest, ok := readDocument(w, r)
+	if !ok {
+		return
+	}
+
+	manifest = s.withID(manifest)
+	manifest["image_id"] = imageID
+	s.rpmManifests[imageID] = manifest
+	writeJSON(w, http.StatusCreated, manifest)
+}
+
+func (s *Server) getRPMManifest(w http.ResponseWriter, imageID string) {
+	manifest, ok := s.rpmManifests[imageID]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("RPM manifest for image %s not found.", imageID))
+		return
+	}
+	writeJSON(w, http.StatusOK, manifest)
+}
+
+func (s *Server) getProject(w http.ResponseWriter, id string) {
+	project, ok := s.projects[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Certification project %s not found.", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, project)
+}
+
+func (s *Server) updateProject(w http.ResponseWriter, r *http.Request, id string) {
+	project, ok := s.projects[id]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Certification project %s not found.", id))
+		return
+	}
+	patch, ok := readDocument(w, r)
+	if !ok {
+		return
+	}
+	merge(project, patch)
+	project["_id"] = id
+	writeJSON(w, http.StatusOK, project)
+}
+
+// createProjectResource creates a resource of the project with projectID, such as
+// test results, and appends it to resources.
+func (s *Server) createProjectResource(w http.ResponseWriter, r *http.Request, projectID string, resources *[]Document) {
+	if _, ok := s.projects[projectID]; !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("Certification project %s not found.", projectID))
+		return
+	}
+	resource, ok := readDocument(w, r)
+	if !ok {
+		return
+	}
+
+	resource = s.withID(resource)
+	resource["cert_project"] = projectID
+	*resources = append(*resources, resource)
+	writeJSON(w, http.StatusCreated, resource)
+}
+
+// withID returns doc, with a new _id if it has none. IDs look like the ObjectIDs
+// Pyxis uses, and are sequential so that tests can predict them.
+func (s *Server) withID(doc Document) Document {
+	if doc == nil {
+		doc = Document{}
+	}
+	if id, _ := doc["_id"].(string); id != "" {
+		return doc
+	}
+	s.lastID++
+	doc["_id"] = fmt.Sprintf("%024x", s.lastID)
+	return doc
+}
+
+// readDocument decodes the body of r, writing a 400 if it is not a JSON object.
+func readDocument(w http.ResponseWriter, r *http.Request) (Document, bool) {
+	var doc Document
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &doc)
+	}
+	if err != nil || doc == nil {
+		writeError(w, http.StatusBadRequest, "The request body is not a JSON object.")
+		return nil, false
+	}
+	return doc, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error the way Pyxis describes them.
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, Document{"detail": detail, "status": status, "title": http.StatusText(status)})
+}
+
+// merge sets the fields of patch in doc, merging nested objects the way a PATCH
+// to Pyxis does.
+func merge(doc, patch Document) {
+	for key, value := range patch {
+		if nested, ok := value.(map[string]any); ok {
+			if existing, ok := doc[key].(map[string]any); ok {
+				merge(existing, nested)
+				continue
+			}
+		}
+		doc[key] = value
+	}
+}
+
+// lookup returns the value of the dotted path field in doc, or nil.
+func lookup(doc Document, field string) any {
+	var value any = doc
+	for _, key := range strings.Split(field, ".") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// clone returns a deep copy of doc, so that callers cannot modify the store.
+func clone(doc Document) Document {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		panic(err)
+	}
+	var c Document
+	if err := json.Unmarshal(b, &c); err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func cloneAll(docs []Document) []Document {
+	cloned := make([]Document, 0, len(docs))
+	for _, doc := range docs {
+		cloned = append(cloned, clone(doc))
+	}
+	return cloned
+}
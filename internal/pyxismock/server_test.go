@@ -0,0 +1,157 @@
+package pyxismock
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/opdev/container-certification/internal/pyxis"
+)
+
+const ubiTopLayer = "sha256:4b3a4e1b6e27ac3d4d5e0ee5e9a5f5f4c6a0f3a9c5d8f8f1e2b6a0f3c2d1e0f9"
+
+var _ = Describe("Server", func() {
+	var (
+		mock   *Server
+		server *httptest.Server
+		host   string
+		client interface {
+			GetProject(context.Context) (*pyxis.CertProject, error)
+			SubmitResults(context.Context, *pyxis.CertificationInput) (*pyxis.CertificationResults, error)
+			FindImagesByDigest(context.Context, []string) ([]pyxis.CertImage, error)
+			CertifiedImagesContainingLayers(context.Context, []cranev1.Hash) ([]pyxis.CertImage, error)
+		}
+	)
+
+	BeforeEach(func() {
+		mock = New(Fixtures{
+			APIKeys: []string{"my-api-key"},
+			Projects: []Document{{
+				"_id":                  "my-project",
+				"certification_status": "Started",
+				"name":                 "My Project",
+				"project_status":       "active",
+				"type":                 "Containers",
+				"container":            map[string]any{"type": "container", "docker_config_json": "{}"},
+			}},
+			Images: []Document{{
+				"_id":                       "ubi",
+				"certified":                 true,
+				"docker_image_digest":       "sha256:ubi",
+				"uncompressed_top_layer_id": ubiTopLayer,
+				"repositories":              []any{map[string]any{"registry": "registry.access.redhat.com", "repository": "ubi9/ubi"}},
+				"freshness_grades":          []any{map[string]any{"grade": "A", "start_date": "2023-05-03T08:52:00+00:00", "end_date": nil}},
+			}},
+		})
+		server = httptest.NewServer(mock)
+		DeferCleanup(server.Close)
+		host = server.URL
+		client = pyxis.NewPyxisClient(host, "my-api-key", "my-project", server.Client())
+	})
+
+	certificationInput := func(project *pyxis.CertProject) *pyxis.CertificationInput {
+		return &pyxis.CertificationInput{
+			CertProject: project,
+			CertImage: &pyxis.CertImage{
+				Certified:         true,
+				DockerImageDigest: "sha256:1234",
+				Architecture:      "amd64",
+				Repositories:      []pyxis.Repository{{Registry: "quay.io", Repository: "my/repo"}},
+			},
+			TestResults: &pyxis.TestResults{},
+			RpmManifest: &pyxis.RPMManifest{RPMS: []pyxis.RPM{{Name: "bash"}}},
+			Artifacts:   []pyxis.Artifact{{Filename: "preflight.log", Content: "bG9n", ContentType: "text/plain"}},
+		}
+	}
+
+	Context("When submitting results", func() {
+		It("should create the image, RPM manifest, artifacts and test results", func() {
+			project, err := client.GetProject(context.TODO())
+			Expect(err).ToNot(HaveOccurred())
+
+			results, err := client.SubmitResults(context.TODO(), certificationInput(project))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results.CertImage.ID).ToNot(BeEmpty())
+			Expect(results.CertProject.CertificationStatus).To(Equal("In Progress"))
+
+			stored, ok := mock.Project("my-project")
+			Expect(ok).To(BeTrue())
+			Expect(stored["certification_status"]).To(Equal("In Progress"))
+			Expect(stored["container"]).To(HaveKeyWithValue("repository", "my/repo"))
+			Expect(stored["container"]).To(HaveKeyWithValue("type", "container"))
+
+			Expect(mock.Images()).To(HaveLen(2))
+			_, ok = mock.RPMManifest(results.CertImage.ID)
+			Expect(ok).To(BeTrue())
+			Expect(mock.Artifacts()).To(ConsistOf(And(
+				HaveKeyWithValue("filename", "preflight.log"),
+				HaveKeyWithValue("image_id", results.CertImage.ID),
+				HaveKeyWithValue("cert_project", "my-project"),
+			)))
+			Expect(mock.TestResults()).To(ConsistOf(HaveKeyWithValue("image_id", results.CertImage.ID)))
+		})
+
+		It("should reuse the image when submitting again", func() {
+			project, err := client.GetProject(context.TODO())
+			Expect(err).ToNot(HaveOccurred())
+
+			first, err := client.SubmitResults(context.TODO(), certificationInput(project))
+			Expect(err).ToNot(HaveOccurred())
+			second, err := client.SubmitResults(context.TODO(), certificationInput(project))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(second.CertImage.ID).To(Equal(first.CertImage.ID))
+			Expect(mock.Images()).To(HaveLen(2))
+			Expect(mock.TestResults()).To(HaveLen(2))
+		})
+	})
+
+	Context("When the API key is not accepted", func() {
+		It("should return an unauthorized error", func() {
+			client = pyxis.NewPyxisClient(host, "my-bad-api-key", "my-project", server.Client())
+			_, err := client.GetProject(context.TODO())
+			Expect(errors.Is(err, pyxis.ErrUnauthorized)).To(BeTrue())
+		})
+	})
+
+	Context("When the project does not exist", func() {
+		It("should return a not found error", func() {
+			client = pyxis.NewPyxisClient(host, "my-api-key", "my-missing-project", server.Client())
+			_, err := client.GetProject(context.TODO())
+			Expect(errors.Is(err, pyxis.ErrNotFound)).To(BeTrue())
+		})
+	})
+
+	Context("When finding images", func() {
+		It("should find images by digest", func() {
+			images, err := client.FindImagesByDigest(context.TODO(), []string{"sha256:ubi", "sha256:unknown"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(images).To(ConsistOf(pyxis.CertImage{ID: "ubi", Certified: true, DockerImageDigest: "sha256:ubi"}))
+		})
+
+		It("should find the certified images containing a layer", func() {
+			layer, err := cranev1.NewHash(ubiTopLayer)
+			Expect(err).ToNot(HaveOccurred())
+
+			images, err := client.CertifiedImagesContainingLayers(context.TODO(), []cranev1.Hash{layer})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(images).To(HaveLen(1))
+			Expect(images[0].ID).To(Equal("ubi"))
+			Expect(images[0].FreshnessGrades).To(HaveLen(1))
+		})
+	})
+
+	Context("When the path is not part of the API", func() {
+		It("should return a not found error", func() {
+			resp, err := server.Client().Get(server.URL + "/v2/images")
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+	})
+})
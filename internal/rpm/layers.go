@@ -0,0 +1,155 @@
+package rpm
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/go-logr/logr"
+	cranev1 "github.com/google/go-containerregistry/pkg/v1"
+	rpmdb "github.com/knqyf263/go-rpmdb/pkg"
+	"github.com/opdev/knex/log"
+)
+
+const whiteoutPrefix = ".wh."
+
+var pgpKeyIDPattern = regexp.MustCompile(".*, Key ID (.*)")
+
+// PGPKeyID returns the ID of the key a package was signed with, given the PGP
+// field of the package. It returns an empty string for unsigned packages.
+func PGPKeyID(pgp string) string {
+	matches := pgpKeyIDPattern.FindStringSubmatch(pgp)
+	if matches == nil {
+		return ""
+	}
+	return matches[1]
+}
+
+// NVRA returns the name-version-release.arch of pkg.
+func NVRA(pkg *rpmdb.PackageInfo) string {
+	return fmt.Sprintf("%s-%s-%s.%s", pkg.Name, pkg.Version, pkg.Release, pkg.Arch)
+}
+
+// LayerPackageList copies /var/lib/rpm/* from the layer and derives a list of
+// packages from the rpm database. If the layer does not contain an rpm database,
+// this returns an error of type os.ErrNotExists.
+func LayerPackageList(ctx context.Context, layer cranev1.Layer) ([]*rpmdb.PackageInfo, error) {
+	layerReader, err := layer.Uncompressed()
+	if err != nil {
+		return nil, fmt.Errorf("reading layer contents: %w", err)
+	}
+	defer layerReader.Close()
+
+	basepath, err := os.MkdirTemp("", "rpmdb")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = os.RemoveAll(basepath)
+	}()
+
+	tarReader := tar.NewReader(layerReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading tar: %w", err)
+		}
+
+		// Some tools prepend everything with "./", so if we don't Clean the
+		// name, we may have duplicate entries, which angers tar-split.
+		header.Name = filepath.Clean(header.Name)
+		header.Format = tar.FormatPAX
+		rpmdirname := "var/lib/rpm"
+		basename := filepath.Base(header.Name)
+		dirname := filepath.Dir(header.Name)
+		tombstone := strings.HasPrefix(basename, whiteoutPrefix)
+
+		// Not a file or directory? Continue...
+		if header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Tombstone? Ignore...
+		if tombstone {
+			continue
+		}
+
+		// Not in the RPM directory. Ignore...
+		if !strings.HasPrefix(filepath.Join(dirname, basename), rpmdirname) {
+			continue
+		}
+		// a dir or file with the correct var/lib/rpm prefix that has not been marked with a tombstone is valid.
+		if header.Typeflag == tar.TypeDir {
+			err := os.MkdirAll(filepath.Join(basepath, dirname, basename), header.FileInfo().Mode())
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		f, err := os.OpenFile(filepath.Join(basepath, dirname, basename), os.O_RDWR|os.O_CREATE|os.O_TRUNC, header.FileInfo().Mode())
+		if err != nil {
+			return nil, err
+		}
+		err = func() error {
+			// closure here allows us to defer f.Close() in this iteration instead of
+			// waiting for the parent function to complete.
+			defer f.Close()
+			_, err := io.Copy(f, tarReader)
+			return err
+		}()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return GetPackageList(ctx, basepath)
+}
+
+// PackageLayers returns the digest of the layer that installed each package in the
+// rpm database of the last layer that has one, keyed by the package's NVRA. A
+// package belongs to the layer that added it to the database, so a package that was
+// removed and installed again belongs to the layer that reinstalled it.
+func PackageLayers(ctx context.Context, layers []cranev1.Layer) (map[string]string, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	installedBy := map[string]string{}
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve digest for layer: %w", err)
+		}
+
+		pkgList, err := LayerPackageList(ctx, layer)
+		if errors.Is(err, os.ErrNotExist) {
+			// The layer did not change the rpm database.
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read the rpm database in layer %s: %w", digest, err)
+		}
+		logger.V(log.TRC).Info("found an rpm database", "layer", digest.String(), "packages", len(pkgList))
+
+		inLayer := make(map[string]string, len(pkgList))
+		for _, pkg := range pkgList {
+			nvra := NVRA(pkg)
+			if previous, ok := installedBy[nvra]; ok {
+				inLayer[nvra] = previous
+				continue
+			}
+			inLayer[nvra] = digest.String()
+		}
+		installedBy = inLayer
+	}
+
+	return installedBy, nil
+}
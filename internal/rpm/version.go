@@ -0,0 +1,168 @@
+package rpm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EVR is the epoch, version and release of a package.
+type EVR struct {
+	Epoch   int
+	Version string
+	Release string
+}
+
+// ParseEVR parses an [epoch:]version[-release] string, as found in advisories.
+func ParseEVR(s string) (EVR, error) {
+	var evr EVR
+	if epoch, rest, ok := strings.Cut(s, ":"); ok {
+		n, err := strconv.Atoi(epoch)
+		if err != nil {
+			return evr, fmt.Errorf("invalid epoch in %q: %w", s, err)
+		}
+		evr.Epoch, s = n, rest
+	}
+	if i := strings.LastIndex(s, "-"); i >= 0 {
+		evr.Version, evr.Release = s[:i], s[i+1:]
+	} else {
+		evr.Version = s
+	}
+	if evr.Version == "" {
+		return evr, fmt.Errorf("missing version in %q", s)
+	}
+	return evr, nil
+}
+
+// String returns evr as epoch:version-release.
+func (evr EVR) String() string {
+	if evr.Release == "" {
+		return fmt.Sprintf("%d:%s", evr.Epoch, evr.Version)
+	}
+	return fmt.Sprintf("%d:%s-%s", evr.Epoch, evr.Version, evr.Release)
+}
+
+// CompareEVR returns -1, 0 or 1 if a is older than, the same as, or newer than b.
+// Releases are only compared if both a and b have one, as rpm does.
+func CompareEVR(a, b EVR) int {
+	switch {
+	case a.Epoch < b.Epoch:
+		return -1
+	case a.Epoch > b.Epoch:
+		return 1
+	}
+	if c := Vercmp(a.Version, b.Version); c != 0 {
+		return c
+	}
+	if a.Release == "" || b.Release == "" {
+		return 0
+	}
+	return Vercmp(a.Release, b.Release)
+}
+
+// Vercmp compares two version or release strings the way rpmvercmp does, returning
+// -1, 0 or 1 if a is older than, the same as, or newer than b. A "~" sorts before
+// anything, including the end of the string, and a "^" sorts after the end of the
+// string but before anything else.
+func Vercmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	for {
+		a = strings.TrimLeftFunc(a, isSeparator)
+		b = strings.TrimLeftFunc(b, isSeparator)
+
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			switch {
+			case a == "":
+				return -1
+			case b == "":
+				return 1
+			case a[0] != '^':
+				return 1
+			case b[0] != '^':
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if a == "" || b == "" {
+			break
+		}
+
+		// Compare the next segment of digits or letters.
+		numeric := isDigit(rune(a[0]))
+		span := isLetter
+		if numeric {
+			span = isDigit
+		}
+		segA, segB := leading(a, span), leading(b, span)
+		a, b = a[len(segA):], b[len(segB):]
+
+		if segB == "" {
+			// Segments of different types: numbers are newer than letters.
+			if numeric {
+				return 1
+			}
+			return -1
+		}
+
+		if numeric {
+			segA = strings.TrimLeft(segA, "0")
+			segB = strings.TrimLeft(segB, "0")
+			switch {
+			case len(segA) > len(segB):
+				return 1
+			case len(segA) < len(segB):
+				return -1
+			}
+		}
+		if c := strings.Compare(segA, segB); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	default:
+		return 1
+	}
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// isSeparator reports whether r separates the segments of a version.
+func isSeparator(r rune) bool {
+	return !isDigit(r) && !isLetter(r) && r != '~' && r != '^'
+}
+
+// leading returns the prefix of s made of the runes that match fn.
+func leading(s string, fn func(rune) bool) string {
+	i := strings.IndexFunc(s, func(r rune) bool { return !fn(r) })
+	if i < 0 {
+		return s
+	}
+	return s[:i]
+}
@@ -0,0 +1,44 @@
+package rpm
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Version comparison", func() {
+	DescribeTable("Vercmp should order versions as rpm does",
+		func(a, b string, expected int) {
+			Expect(Vercmp(a, b)).To(Equal(expected))
+			Expect(Vercmp(b, a)).To(Equal(-expected))
+		},
+		Entry("equal", "1.0", "1.0", 0),
+		Entry("numeric segments", "1.10", "1.9", 1),
+		Entry("leading zeros", "1.010", "1.10", 0),
+		Entry("numbers are newer than letters", "1.0", "1.a", 1),
+		Entry("letters compare as strings", "1.b", "1.a", 1),
+		Entry("a longer version is newer", "1.0.1", "1.0", 1),
+		Entry("separators are ignored", "1_0", "1.0", 0),
+		Entry("tilde sorts before the end", "1.0~rc1", "1.0", -1),
+		Entry("caret sorts after the end", "1.0^git1", "1.0", 1),
+		Entry("caret sorts before a new segment", "1.0^git1", "1.0.1", -1),
+		Entry("el9 release updates", "6.el9_1", "6.el9", 1),
+	)
+
+	It("should compare the epoch first", func() {
+		older, err := ParseEVR("0:5.1.8-6.el9_1")
+		Expect(err).ToNot(HaveOccurred())
+		newer, err := ParseEVR("1:1.0-1.el9")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(CompareEVR(older, newer)).To(Equal(-1))
+		Expect(newer.String()).To(Equal("1:1.0-1.el9"))
+	})
+
+	It("should ignore the release when one side does not have one", func() {
+		Expect(CompareEVR(EVR{Version: "2.0", Release: "3"}, EVR{Version: "2.0"})).To(Equal(0))
+	})
+
+	It("should reject an invalid epoch", func() {
+		_, err := ParseEVR("x:1.0-1")
+		Expect(err).To(HaveOccurred())
+	})
+})
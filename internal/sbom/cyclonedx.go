@@ -0,0 +1,126 @@
+package sbom
+
+import (
+	"encoding/json"
+)
+
+const cycloneDXSpecVersion = "1.5"
+
+// Names of the properties that carry what CycloneDX has no field for.
+const (
+	propertySourceRPM = toolName + ":rpm:sourcerpm"
+	propertyGPGKeyID  = toolName + ":rpm:gpgkeyid"
+	propertyLayer     = toolName + ":layer"
+)
+
+type cycloneDXBOM struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     cycloneDXTools     `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTools struct {
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXComponent struct {
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Type       string              `json:"type"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	Supplier   *cycloneDXSupplier  `json:"supplier,omitempty"`
+	Licenses   []cycloneDXLicense  `json:"licenses,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXSupplier struct {
+	Name string `json:"name"`
+}
+
+// cycloneDXLicense is a license choice. rpm licenses are not necessarily SPDX
+// license expressions, so they are given as license names.
+type cycloneDXLicense struct {
+	License cycloneDXNamedLicense `json:"license"`
+}
+
+type cycloneDXNamedLicense struct {
+	Name string `json:"name"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// CycloneDX returns s as a CycloneDX 1.5 JSON document.
+func (s SBOM) CycloneDX() ([]byte, error) {
+	image := cycloneDXComponent{
+		BOMRef:  s.Image,
+		Type:    "container",
+		Name:    s.Image,
+		Version: s.Digest,
+	}
+
+	bom := cycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXSpecVersion,
+		SerialNumber: "urn:uuid:" + s.uuid(),
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: s.timestamp(),
+			Tools: cycloneDXTools{
+				Components: []cycloneDXComponent{{Type: "application", Name: toolName}},
+			},
+			Component: image,
+		},
+		Components: make([]cycloneDXComponent, 0, len(s.Packages)),
+	}
+
+	refs := make([]string, 0, len(s.Packages))
+	for _, pkg := range s.Packages {
+		component := cycloneDXComponent{
+			BOMRef:  pkg.PURL,
+			Type:    "library",
+			Name:    pkg.Name,
+			Version: pkg.version(),
+			PURL:    pkg.PURL,
+		}
+		if pkg.Vendor != "" {
+			component.Supplier = &cycloneDXSupplier{Name: pkg.Vendor}
+		}
+		if pkg.License != "" {
+			component.Licenses = []cycloneDXLicense{{License: cycloneDXNamedLicense{Name: pkg.License}}}
+		}
+		for _, p := range []cycloneDXProperty{
+			{Name: propertySourceRPM, Value: pkg.SourceRPM},
+			{Name: propertyGPGKeyID, Value: pkg.GPGKeyID},
+			{Name: propertyLayer, Value: pkg.Layer},
+		} {
+			if p.Value != "" {
+				component.Properties = append(component.Properties, p)
+			}
+		}
+
+		bom.Components = append(bom.Components, component)
+		refs = append(refs, component.BOMRef)
+	}
+	bom.Dependencies = []cycloneDXDependency{{Ref: image.BOMRef, DependsOn: refs}}
+
+	return json.MarshalIndent(bom, "", "    ")
+}
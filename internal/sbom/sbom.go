@@ -0,0 +1,200 @@
+// Package sbom describes the rpm packages installed in an image as SPDX and
+// CycloneDX software bills of materials.
+package sbom
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	rpmdb "github.com/knqyf263/go-rpmdb/pkg"
+
+	"github.com/opdev/container-certification/internal/rpm"
+)
+
+// toolName identifies this project as the creator of an SBOM.
+const toolName = "container-certification"
+
+// redHatVendor is the vendor of packages built by Red Hat.
+const redHatVendor = "Red Hat, Inc."
+
+// SBOM is what is known about the packages of an image.
+type SBOM struct {
+	// Image is the reference of the image, e.g. quay.io/example/app:1.0.
+	Image string
+	// Digest is the digest of the image manifest.
+	Digest string
+	// Created is when the SBOM was generated.
+	Created  time.Time
+	Packages []Package
+}
+
+// Package is an rpm package installed in an image.
+type Package struct {
+	Name    string
+	Epoch   int
+	Version string
+	Release string
+	Arch    string
+	// SourceRPM is the file name of the source rpm the package was built from.
+	SourceRPM string
+	// License is the license from the rpm header, which is not necessarily an
+	// SPDX license expression.
+	License string
+	Vendor  string
+	// GPGKeyID is the ID of the key the package was signed with, if any.
+	GPGKeyID string
+	// Layer is the digest of the layer that installed the package, if known.
+	Layer string
+	PURL  string
+}
+
+// NVRA returns the name-version-release.arch of p.
+func (p Package) NVRA() string {
+	return fmt.Sprintf("%s-%s-%s.%s", p.Name, p.Version, p.Release, p.Arch)
+}
+
+// version returns the [epoch:]version-release of p.
+func (p Package) version() string {
+	if p.Epoch > 0 {
+		return fmt.Sprintf("%d:%s-%s", p.Epoch, p.Version, p.Release)
+	}
+	return fmt.Sprintf("%s-%s", p.Version, p.Release)
+}
+
+// Distro identifies the operating system of an image, as in its os-release file.
+type Distro struct {
+	ID        string
+	VersionID string
+}
+
+// ReadDistro reads the os-release file of the filesystem at root. An empty
+// Distro is returned if the image does not have one.
+func ReadDistro(root string) (Distro, error) {
+	var d Distro
+	var f *os.File
+	var err error
+	for _, name := range []string{"etc/os-release", "usr/lib/os-release"} {
+		f, err = os.Open(filepath.Join(root, name))
+		if !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		return d, nil
+	}
+	if err != nil {
+		return d, fmt.Errorf("could not open os-release: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "ID":
+			d.ID = value
+		case "VERSION_ID":
+			d.VersionID = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return d, fmt.Errorf("could not read os-release: %w", err)
+	}
+
+	return d, nil
+}
+
+// Packages converts pkgList to Packages, sorted by NVRA. layers maps the NVRA of
+// each package to the layer that installed it, as returned by rpm.PackageLayers.
+func Packages(pkgList []*rpmdb.PackageInfo, layers map[string]string, distro Distro) []Package {
+	pkgs := make([]Package, 0, len(pkgList))
+	for _, info := range pkgList {
+		pkg := Package{
+			Name:      info.Name,
+			Epoch:     info.EpochNum(),
+			Version:   info.Version,
+			Release:   info.Release,
+			Arch:      info.Arch,
+			SourceRPM: info.SourceRpm,
+			License:   info.License,
+			Vendor:    info.Vendor,
+			GPGKeyID:  rpm.PGPKeyID(info.PGP),
+			Layer:     layers[rpm.NVRA(info)],
+		}
+		pkg.PURL = purl(pkg, distro)
+		pkgs = append(pkgs, pkg)
+	}
+
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].NVRA() < pkgs[j].NVRA()
+	})
+
+	return pkgs
+}
+
+// purl returns the package URL of pkg, e.g.
+// pkg:rpm/redhat/bash@5.1.8-6.el9?arch=x86_64&distro=rhel-9.2&upstream=bash-5.1.8-6.el9.src.rpm.
+func purl(pkg Package, distro Distro) string {
+	namespace := distro.ID
+	if pkg.Vendor == redHatVendor {
+		namespace = "redhat"
+	}
+
+	var b strings.Builder
+	b.WriteString("pkg:rpm/")
+	if namespace != "" {
+		b.WriteString(url.PathEscape(strings.ToLower(namespace)))
+		b.WriteString("/")
+	}
+	b.WriteString(url.PathEscape(pkg.Name))
+	b.WriteString("@")
+	b.WriteString(url.PathEscape(pkg.Version + "-" + pkg.Release))
+
+	// Qualifiers are sorted by key.
+	var qualifiers []string
+	if pkg.Arch != "" {
+		qualifiers = append(qualifiers, "arch="+url.QueryEscape(pkg.Arch))
+	}
+	if distro.ID != "" && distro.VersionID != "" {
+		qualifiers = append(qualifiers, "distro="+url.QueryEscape(distro.ID+"-"+distro.VersionID))
+	}
+	if pkg.Epoch > 0 {
+		qualifiers = append(qualifiers, fmt.Sprintf("epoch=%d", pkg.Epoch))
+	}
+	if pkg.SourceRPM != "" {
+		qualifiers = append(qualifiers, "upstream="+url.QueryEscape(pkg.SourceRPM))
+	}
+	if len(qualifiers) > 0 {
+		b.WriteString("?")
+		b.WriteString(strings.Join(qualifiers, "&"))
+	}
+
+	return b.String()
+}
+
+// uuid returns a name-based (version 5 style) UUID derived from the image digest
+// and creation time of s, so that the same SBOM always gets the same identifier.
+func (s SBOM) uuid() string {
+	sum := sha1.Sum([]byte(s.Digest + "@" + s.Created.UTC().Format(time.RFC3339Nano)))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// timestamp returns the creation time of s in the format both SBOM formats use.
+func (s SBOM) timestamp() string {
+	return s.Created.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,138 @@
+package sbom
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	rpmdb "github.com/knqyf263/go-rpmdb/pkg"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SBOM", func() {
+	const layer = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+	var s SBOM
+
+	BeforeEach(func() {
+		pkgList := []*rpmdb.PackageInfo{
+			{
+				Name:      "zlib",
+				Version:   "1.2.11",
+				Release:   "40.el9",
+				Arch:      "x86_64",
+				SourceRpm: "zlib-1.2.11-40.el9.src.rpm",
+				License:   "zlib and Boost",
+				Vendor:    "Red Hat, Inc.",
+				PGP:       "RSA/SHA256, Tue Jun 20 12:00:00 2023, Key ID 199e2f91fd431d51",
+			},
+			{
+				Name:    "app",
+				Version: "1.0",
+				Release: "1",
+				Arch:    "noarch",
+				License: "MIT",
+			},
+		}
+		s = SBOM{
+			Image:    "quay.io/example/app:1.0",
+			Digest:   "sha256:abcdef",
+			Created:  time.Date(2023, 6, 20, 12, 0, 0, 0, time.UTC),
+			Packages: Packages(pkgList, map[string]string{"zlib-1.2.11-40.el9.x86_64": layer}, Distro{ID: "rhel", VersionID: "9.2"}),
+		}
+	})
+
+	Context("When converting rpm packages", func() {
+		It("should sort them and record where they came from", func() {
+			Expect(s.Packages).To(HaveLen(2))
+			Expect(s.Packages[0].Name).To(Equal("app"))
+
+			zlib := s.Packages[1]
+			Expect(zlib.GPGKeyID).To(Equal("199e2f91fd431d51"))
+			Expect(zlib.Layer).To(Equal(layer))
+			Expect(zlib.PURL).To(Equal("pkg:rpm/redhat/zlib@1.2.11-40.el9?arch=x86_64&distro=rhel-9.2&upstream=zlib-1.2.11-40.el9.src.rpm"))
+		})
+		It("should use the distro as the namespace of packages not built by Red Hat", func() {
+			Expect(s.Packages[0].PURL).To(Equal("pkg:rpm/rhel/app@1.0-1?arch=noarch&distro=rhel-9.2"))
+		})
+		It("should include a non-zero epoch", func() {
+			pkg := Package{Name: "shadow-utils", Epoch: 2, Version: "4.9", Release: "6.el9", Arch: "x86_64"}
+			Expect(purl(pkg, Distro{})).To(Equal("pkg:rpm/shadow-utils@4.9-6.el9?arch=x86_64&epoch=2"))
+			Expect(pkg.version()).To(Equal("2:4.9-6.el9"))
+		})
+	})
+
+	Context("When writing SPDX", func() {
+		var doc spdxDocument
+
+		BeforeEach(func() {
+			data, err := s.SPDX()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(json.Unmarshal(data, &doc)).To(Succeed())
+		})
+		It("should describe the image and contain each package", func() {
+			Expect(doc.SPDXVersion).To(Equal("SPDX-2.3"))
+			Expect(doc.Packages).To(HaveLen(3))
+			Expect(doc.Relationships).To(ContainElement(spdxRelationship{
+				SPDXElementID:      spdxImageID,
+				RelationshipType:   "CONTAINS",
+				RelatedSPDXElement: "SPDXRef-Package-rpm-zlib-1.2.11-40.el9.x86-64",
+			}))
+		})
+		It("should record rpm licenses as license references", func() {
+			zlib := doc.Packages[2]
+			Expect(zlib.LicenseDeclared).To(Equal("LicenseRef-rpm-2"))
+			Expect(doc.ExtractedLicenses).To(ContainElement(spdxExtractedLicense{
+				LicenseID:     "LicenseRef-rpm-2",
+				ExtractedText: "zlib and Boost",
+				Name:          "zlib and Boost",
+			}))
+			Expect(zlib.SourceInfo).To(ContainSubstring("zlib-1.2.11-40.el9.src.rpm"))
+			Expect(zlib.Comment).To(Equal("installed in layer " + layer + "; signed with GPG key ID 199e2f91fd431d51"))
+			Expect(zlib.ExternalRefs[0].ReferenceLocator).To(HavePrefix("pkg:rpm/redhat/zlib@"))
+		})
+	})
+
+	Context("When writing CycloneDX", func() {
+		It("should contain each package with its properties", func() {
+			data, err := s.CycloneDX()
+			Expect(err).ToNot(HaveOccurred())
+
+			var bom cycloneDXBOM
+			Expect(json.Unmarshal(data, &bom)).To(Succeed())
+			Expect(bom.SpecVersion).To(Equal("1.5"))
+			Expect(bom.SerialNumber).To(MatchRegexp(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+			Expect(bom.Metadata.Component.Type).To(Equal("container"))
+			Expect(bom.Components).To(HaveLen(2))
+
+			zlib := bom.Components[1]
+			Expect(zlib.PURL).To(Equal(s.Packages[1].PURL))
+			Expect(zlib.Licenses).To(Equal([]cycloneDXLicense{{License: cycloneDXNamedLicense{Name: "zlib and Boost"}}}))
+			Expect(zlib.Properties).To(ConsistOf(
+				cycloneDXProperty{Name: propertySourceRPM, Value: "zlib-1.2.11-40.el9.src.rpm"},
+				cycloneDXProperty{Name: propertyGPGKeyID, Value: "199e2f91fd431d51"},
+				cycloneDXProperty{Name: propertyLayer, Value: layer},
+			))
+			Expect(bom.Dependencies[0].DependsOn).To(HaveLen(2))
+		})
+	})
+
+	Context("When reading the distro", func() {
+		It("should read os-release", func() {
+			root := GinkgoT().TempDir()
+			Expect(os.MkdirAll(filepath.Join(root, "usr", "lib"), 0o755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, "usr", "lib", "os-release"), []byte("NAME=\"Red Hat Enterprise Linux\"\nID=\"rhel\"\nVERSION_ID=\"9.2\"\n"), 0o644)).To(Succeed())
+
+			d, err := ReadDistro(root)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d).To(Equal(Distro{ID: "rhel", VersionID: "9.2"}))
+		})
+		It("should return nothing for an image without os-release", func() {
+			d, err := ReadDistro(GinkgoT().TempDir())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(d).To(BeZero())
+		})
+	})
+})
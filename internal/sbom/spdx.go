@@ -0,0 +1,167 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	spdxVersion     = "SPDX-2.3"
+	spdxNoAssertion = "NOASSERTION"
+	spdxImageID     = "SPDXRef-Image"
+)
+
+// spdxIDUnsafe matches the characters SPDX identifiers may not contain.
+var spdxIDUnsafe = regexp.MustCompile(`[^A-Za-z0-9.-]`)
+
+type spdxDocument struct {
+	SPDXVersion       string                 `json:"spdxVersion"`
+	DataLicense       string                 `json:"dataLicense"`
+	SPDXID            string                 `json:"SPDXID"`
+	Name              string                 `json:"name"`
+	DocumentNamespace string                 `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo       `json:"creationInfo"`
+	Packages          []spdxPackage          `json:"packages"`
+	Relationships     []spdxRelationship     `json:"relationships"`
+	ExtractedLicenses []spdxExtractedLicense `json:"hasExtractedLicensingInfos,omitempty"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	Supplier         string            `json:"supplier,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	CopyrightText    string            `json:"copyrightText"`
+	SourceInfo       string            `json:"sourceInfo,omitempty"`
+	Comment          string            `json:"comment,omitempty"`
+	PrimaryPurpose   string            `json:"primaryPackagePurpose,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxExtractedLicense records an rpm license string that is referred to as a
+// LicenseRef, since rpm licenses are not necessarily SPDX license expressions.
+type spdxExtractedLicense struct {
+	LicenseID     string `json:"licenseId"`
+	ExtractedText string `json:"extractedText"`
+	Name          string `json:"name"`
+}
+
+// SPDX returns s as an SPDX 2.3 JSON document.
+func (s SBOM) SPDX() ([]byte, error) {
+	doc := spdxDocument{
+		SPDXVersion:       spdxVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              s.Image,
+		DocumentNamespace: fmt.Sprintf("https://github.com/opdev/container-certification/spdx/%s-%s", spdxIDUnsafe.ReplaceAllString(s.Image, "-"), s.uuid()),
+		CreationInfo: spdxCreationInfo{
+			Created:  s.timestamp(),
+			Creators: []string{"Tool: " + toolName},
+		},
+		Packages: []spdxPackage{{
+			SPDXID:           spdxImageID,
+			Name:             s.Image,
+			VersionInfo:      s.Digest,
+			DownloadLocation: spdxNoAssertion,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  spdxNoAssertion,
+			CopyrightText:    spdxNoAssertion,
+			PrimaryPurpose:   "CONTAINER",
+		}},
+		Relationships: []spdxRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: spdxImageID,
+		}},
+	}
+
+	licenseRefs := map[string]string{}
+	for _, pkg := range s.Packages {
+		license := spdxNoAssertion
+		if pkg.License != "" {
+			ref, ok := licenseRefs[pkg.License]
+			if !ok {
+				ref = fmt.Sprintf("LicenseRef-rpm-%d", len(licenseRefs)+1)
+				licenseRefs[pkg.License] = ref
+				doc.ExtractedLicenses = append(doc.ExtractedLicenses, spdxExtractedLicense{
+					LicenseID:     ref,
+					ExtractedText: pkg.License,
+					Name:          pkg.License,
+				})
+			}
+			license = ref
+		}
+
+		supplier := spdxNoAssertion
+		if pkg.Vendor != "" {
+			supplier = "Organization: " + pkg.Vendor
+		}
+
+		var sourceInfo string
+		if pkg.SourceRPM != "" {
+			sourceInfo = "built from source rpm " + pkg.SourceRPM
+		}
+
+		id := "SPDXRef-Package-rpm-" + spdxIDUnsafe.ReplaceAllString(pkg.NVRA(), "-")
+		doc.Packages = append(doc.Packages, spdxPackage{
+			SPDXID:           id,
+			Name:             pkg.Name,
+			VersionInfo:      pkg.version(),
+			Supplier:         supplier,
+			DownloadLocation: spdxNoAssertion,
+			LicenseConcluded: spdxNoAssertion,
+			LicenseDeclared:  license,
+			CopyrightText:    spdxNoAssertion,
+			SourceInfo:       sourceInfo,
+			Comment:          spdxComment(pkg),
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  pkg.PURL,
+			}},
+		})
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      spdxImageID,
+			RelationshipType:   "CONTAINS",
+			RelatedSPDXElement: id,
+		})
+	}
+
+	return json.MarshalIndent(doc, "", "    ")
+}
+
+// spdxComment records what SPDX has no field for: the layer that installed pkg, and
+// the key it was signed with.
+func spdxComment(pkg Package) string {
+	var parts []string
+	if pkg.Layer != "" {
+		parts = append(parts, "installed in layer "+pkg.Layer)
+	}
+	if pkg.GPGKeyID != "" {
+		parts = append(parts, "signed with GPG key ID "+pkg.GPGKeyID)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -2,17 +2,20 @@ package submit
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/opdev/knex/log"
 	"github.com/redhat-openshift-ecosystem/openshift-preflight/artifacts"
 
+	"github.com/opdev/container-certification/internal/crane"
 	"github.com/opdev/container-certification/internal/defaults"
 	"github.com/opdev/container-certification/internal/policy"
 	"github.com/opdev/container-certification/internal/pyxis"
@@ -30,20 +33,23 @@ type PyxisClient interface {
 	SubmitResults(context.Context, *pyxis.CertificationInput) (*pyxis.CertificationResults, error)
 }
 
-// NewPyxisClient initializes a pyxisClient with relevant information from cfg.
+// NewPyxisClient initializes a pyxisClient with relevant information from cfg, which
+// retries failed requests according to retry.
 // If the the CertificationProjectID, PyxisAPIToken, or PyxisHost are empty, then nil is returned.
 // Callers should treat a nil pyxis client as an indicator that pyxis calls should not be made.
-func NewPyxisClient(_ context.Context, projectID, token, host string) PyxisClient {
+func NewPyxisClient(_ context.Context, projectID, token, host string, retry pyxis.RetryPolicy) PyxisClient {
 	if projectID == "" || token == "" || host == "" {
 		return nil
 	}
 
-	return pyxis.NewPyxisClient(
+	client := pyxis.NewPyxisClient(
 		host,
 		token,
 		projectID,
 		&http.Client{Timeout: 60 * time.Second},
 	)
+	client.Retry = retry
+	return client
 }
 
 // ContainerCertificationSubmitter submits container results to Pyxis, and implements
@@ -55,6 +61,15 @@ type ContainerCertificationSubmitter struct {
 	PreflightLogFile       string
 	// Note(Jose): Added PyxisEnv here so that URL building functiosn can be switched to methods on this type.
 	PyxisEnv string
+	// SubmitSBOM attaches the SBOMs written by the engine to the submission as artifacts.
+	SubmitSBOM bool
+	// DryRun writes the requests that would submit the results to the submission
+	// directory of the artifacts, instead of sending them.
+	DryRun bool
+	// Platforms are the platforms of a multi-architecture image that were certified.
+	// The artifacts of each platform are submitted as a separate image. When empty,
+	// the artifacts of a single image are submitted.
+	Platforms []string
 }
 
 func (s *ContainerCertificationSubmitter) Submit(ctx context.Context) error {
@@ -116,20 +131,51 @@ func (s *ContainerCertificationSubmitter) Submit(ctx context.Context) error {
 		return errors.New("the artifact writer was either missing or was not supported, so results cannot be submitted")
 	}
 
-	certImage, err := os.Open(path.Join(artifactWriter.Path(), defaults.DefaultCertImageFilename))
+	if s.DryRun {
+		// Remove the requests of a previous dry run, which may have had more artifacts
+		// or platforms.
+		if err := os.RemoveAll(filepath.Join(artifactWriter.Path(), defaults.DefaultSubmissionDirName)); err != nil {
+			return fmt.Errorf("could not remove previous dry run: %w", err)
+		}
+	}
+
+	platforms := s.Platforms
+	if len(platforms) == 0 {
+		platforms = []string{""}
+	}
+	for _, platform := range platforms {
+		if err := s.submitPlatform(ctx, certProject, artifactWriter.Path(), platform); err != nil {
+			if platform != "" {
+				return fmt.Errorf("could not submit linux/%s: %w", platform, err)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// submitPlatform submits the artifacts of platform in artifactsDir as an image of
+// certProject. An empty platform submits the artifacts of a single image.
+func (s *ContainerCertificationSubmitter) submitPlatform(ctx context.Context, certProject *pyxis.CertProject, artifactsDir string, platform string) error {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	certImageFilename := crane.PlatformFilename(defaults.DefaultCertImageFilename, platform)
+	certImage, err := os.Open(path.Join(artifactsDir, certImageFilename))
 	if err != nil {
 		return fmt.Errorf("could not open file for submission: %s: %w",
-			defaults.DefaultCertImageFilename,
+			certImageFilename,
 			err,
 		)
 	}
 	defer certImage.Close()
 
-	preflightResults, err := os.Open(path.Join(artifactWriter.Path(), defaults.DefaultTestResultsFilename))
+	testResultsFilename := crane.PlatformFilename(defaults.DefaultTestResultsFilename, platform)
+	preflightResults, err := os.Open(path.Join(artifactsDir, testResultsFilename))
 	if err != nil {
 		return fmt.Errorf(
 			"could not open file for submission: %s: %w",
-			defaults.DefaultTestResultsFilename,
+			testResultsFilename,
 			err,
 		)
 	}
@@ -160,17 +206,40 @@ func (s *ContainerCertificationSubmitter) Submit(ctx context.Context) error {
 	// only read the rpm manifest file off of disk if the policy executed is not scratch
 	// scratch images do not have rpm manifests, the rpm-manifest.json file is not written to disk by the engine during execution
 	if pol != policy.PolicyScratch {
-		rpmManifest, err := os.Open(path.Join(artifactWriter.Path(), defaults.DefaultRPMManifestFilename))
+		rpmManifestFilename := crane.PlatformFilename(defaults.DefaultRPMManifestFilename, platform)
+		rpmManifest, err := os.Open(path.Join(artifactsDir, rpmManifestFilename))
 		if err != nil {
 			return fmt.Errorf(
 				"could not open file for submission: %s: %w",
-				defaults.DefaultRPMManifestFilename,
+				rpmManifestFilename,
 				err,
 			)
 		}
 		defer rpmManifest.Close()
 
 		options = append(options, pyxis.WithRPMManifest(rpmManifest))
+
+		if s.SubmitSBOM {
+			for _, filename := range []string{defaults.DefaultSPDXFilename, defaults.DefaultCycloneDXFilename} {
+				filename = crane.PlatformFilename(filename, platform)
+				sbom, err := os.Open(path.Join(artifactsDir, filename))
+				if err != nil {
+					return fmt.Errorf("could not open file for submission: %s: %w", filename, err)
+				}
+				defer sbom.Close()
+
+				options = append(options, pyxis.WithArtifact(sbom, filename))
+			}
+		}
+	}
+
+	// A dry run sends nothing, so there is no progress to record.
+	if !s.DryRun {
+		journal, err := pyxis.OpenJournal(path.Join(artifactsDir, crane.PlatformFilename(defaults.DefaultSubmissionJournal, platform)))
+		if err != nil {
+			return err
+		}
+		options = append(options, pyxis.WithJournal(journal))
 	}
 
 	submission, err := pyxis.NewCertificationInput(ctx, certProject, options...)
@@ -178,12 +247,17 @@ func (s *ContainerCertificationSubmitter) Submit(ctx context.Context) error {
 		return fmt.Errorf("unable to finalize data that would be sent to pyxis: %w", err)
 	}
 
+	if s.DryRun {
+		dir := filepath.Join(artifactsDir, defaults.DefaultSubmissionDirName, platform)
+		return s.writeDryRun(ctx, dir, submission)
+	}
+
 	certResults, err := s.Pyxis.SubmitResults(ctx, submission)
 	if err != nil {
 		return fmt.Errorf("could not submit to pyxis: %w", err)
 	}
 
-	logger.Info("Test results have been submitted to Red Hat.")
+	logger.Info("Test results have been submitted to Red Hat.", "platform", platform)
 	logger.Info("These results will be reviewed by Red Hat for final certification.")
 	logger.Info(fmt.Sprintf("The container's image id is: %s.", certResults.CertImage.ID))
 	logger.Info(fmt.Sprintf("Please check %s to view scan results.", s.BuildScanResultsURL(s.CertificationProjectID, certResults.CertImage.ID)))
@@ -192,6 +266,58 @@ func (s *ContainerCertificationSubmitter) Submit(ctx context.Context) error {
 	return nil
 }
 
+// writeDryRun writes the body of each request that would submit submission to dir,
+// in the order they would be sent, with the docker config redacted.
+func (s *ContainerCertificationSubmitter) writeDryRun(ctx context.Context, dir string, submission *pyxis.CertificationInput) error {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	requests, err := pyxis.PlanSubmission(s.CertificationProjectID, submission)
+	if err != nil {
+		return fmt.Errorf("could not plan the submission: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("could not create submission directory: %w", err)
+	}
+
+	for i, req := range requests {
+		name := strings.ReplaceAll(req.Operation, " ", "-")
+		if artifact, ok := req.Body.(*pyxis.Artifact); ok {
+			name += "-" + filepath.Base(artifact.Filename)
+		}
+		filename := filepath.Join(dir, fmt.Sprintf("%02d-%s.json", i+1, name))
+
+		b, err := json.MarshalIndent(redactDockerConfig(req.Body), "", "    ")
+		if err != nil {
+			return fmt.Errorf("could not marshal request to %s: %w", req.Operation, err)
+		}
+		if err := os.WriteFile(filename, b, 0o644); err != nil { //nolint:gosec // the artifacts are not secret
+			return fmt.Errorf("could not write request to %s: %w", req.Operation, err)
+		}
+
+		logger.Info("request not sent in dry run", "method", req.Method, "path", req.Path, "body", filename)
+	}
+
+	logger.Info(fmt.Sprintf("Dry run: nothing was submitted. The requests that would be sent are in %s.", dir))
+	logger.Info(fmt.Sprintf("Requests that refer to the image use %q for the ID that Pyxis would assign.", pyxis.DryRunImageID))
+
+	return nil
+}
+
+// redactedDockerConfig replaces the docker config of a project in a dry run.
+const redactedDockerConfig = "REDACTED"
+
+// redactDockerConfig returns body with the docker config of a project redacted.
+func redactDockerConfig(body any) any {
+	project, ok := body.(*pyxis.CertProject)
+	if !ok || project.Container.DockerConfigJSON == "" {
+		return body
+	}
+	redacted := *project
+	redacted.Container.DockerConfigJSON = redactedDockerConfig
+	return &redacted
+}
+
 func (s *ContainerCertificationSubmitter) BuildConnectURL(projectID string) string {
 	connectURL := fmt.Sprintf("https://connect.redhat.com/projects/%s", projectID)
 
@@ -0,0 +1,131 @@
+package submit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redhat-openshift-ecosystem/openshift-preflight/artifacts"
+
+	"github.com/opdev/container-certification/internal/crane"
+	"github.com/opdev/container-certification/internal/defaults"
+	"github.com/opdev/container-certification/internal/pyxis"
+)
+
+// fakePyxisClient returns a project, and records the submissions it is asked to send.
+type fakePyxisClient struct {
+	submitted int
+}
+
+func (f *fakePyxisClient) FindImagesByDigest(context.Context, []string) ([]pyxis.CertImage, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakePyxisClient) GetProject(context.Context) (*pyxis.CertProject, error) {
+	return &pyxis.CertProject{
+		ID:                  "my-project",
+		CertificationStatus: "Started",
+		Container:           pyxis.Container{Type: "container"},
+	}, nil
+}
+
+func (f *fakePyxisClient) SubmitResults(context.Context, *pyxis.CertificationInput) (*pyxis.CertificationResults, error) {
+	f.submitted++
+	return nil, errors.New("a dry run must not submit")
+}
+
+var _ = Describe("Submitting results in a dry run", func() {
+	const dockerConfig = `{"auths": {"quay.io": {"auth": "bXk6c2VjcmV0"}}}`
+
+	var (
+		artifactsDir string
+		ctx          context.Context
+		client       *fakePyxisClient
+		submitter    *ContainerCertificationSubmitter
+	)
+
+	writeFile := func(name, content string) string {
+		path := filepath.Join(artifactsDir, name)
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+		return path
+	}
+
+	writeArtifacts := func(platform string) {
+		writeFile(crane.PlatformFilename(defaults.DefaultCertImageFilename, platform), `{"docker_image_digest": "sha256:1234", "architecture": "amd64", "repositories": [{"registry": "quay.io", "repository": "my/image"}]}`)
+		writeFile(crane.PlatformFilename(defaults.DefaultTestResultsFilename, platform), `{"image": "quay.io/my/image", "passed": true}`)
+		writeFile(crane.PlatformFilename(defaults.DefaultRPMManifestFilename, platform), `{"rpms": [{"name": "bash"}]}`)
+	}
+
+	BeforeEach(func() {
+		artifactsDir = GinkgoT().TempDir()
+		writer, err := artifacts.NewFilesystemWriter(artifacts.WithDirectory(artifactsDir))
+		Expect(err).ToNot(HaveOccurred())
+		ctx = artifacts.ContextWithWriter(context.Background(), writer)
+
+		client = &fakePyxisClient{}
+		submitter = &ContainerCertificationSubmitter{
+			CertificationProjectID: "my-project",
+			Pyxis:                  client,
+			DockerConfig:           writeFile("config.json", dockerConfig),
+			PreflightLogFile:       writeFile("preflight.log", "log"),
+			DryRun:                 true,
+		}
+	})
+
+	It("should write each request with the docker config redacted, and send none", func() {
+		writeArtifacts("")
+		Expect(submitter.Submit(ctx)).To(Succeed())
+		Expect(client.submitted).To(BeZero())
+
+		dir := filepath.Join(artifactsDir, defaults.DefaultSubmissionDirName)
+		entries, err := os.ReadDir(dir)
+		Expect(err).ToNot(HaveOccurred())
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.Name())
+			b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(b)).ToNot(ContainSubstring("bXk6c2VjcmV0"))
+		}
+		Expect(names).To(Equal([]string{
+			"01-update-project.json",
+			"02-create-image.json",
+			"03-create-rpm-manifest.json",
+			"04-create-artifact-preflight.log.json",
+			"05-create-test-results.json",
+		}))
+
+		project, err := os.ReadFile(filepath.Join(dir, "01-update-project.json"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(project)).To(ContainSubstring(redactedDockerConfig))
+
+		// A dry run records no progress.
+		Expect(filepath.Join(artifactsDir, defaults.DefaultSubmissionJournal)).ToNot(BeAnExistingFile())
+	})
+
+	It("should write the requests of each platform to its own directory", func() {
+		submitter.Platforms = []string{"amd64", "arm/v7"}
+		for _, platform := range submitter.Platforms {
+			writeArtifacts(platform)
+		}
+		Expect(submitter.Submit(ctx)).To(Succeed())
+		Expect(client.submitted).To(BeZero())
+
+		for _, platform := range submitter.Platforms {
+			Expect(filepath.Join(artifactsDir, defaults.DefaultSubmissionDirName, platform, "02-create-image.json")).To(BeAnExistingFile())
+		}
+	})
+
+	It("should remove the requests of a previous dry run", func() {
+		stale := filepath.Join(artifactsDir, defaults.DefaultSubmissionDirName, "06-create-artifact-old.json")
+		Expect(os.MkdirAll(filepath.Dir(stale), 0o755)).To(Succeed())
+		writeFile(filepath.Join(defaults.DefaultSubmissionDirName, filepath.Base(stale)), "{}")
+
+		writeArtifacts("")
+		Expect(submitter.Submit(ctx)).To(Succeed())
+		Expect(stale).ToNot(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,79 @@
+// Package transport configures how image registries are reached, for registries
+// that use certificates the system does not trust.
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+var errNoCertificates = errors.New("no PEM certificates found")
+
+// Options configures the connection to image registries. The zero value uses the
+// default transport, which trusts the system CAs only.
+type Options struct {
+	// Insecure skips TLS verification and allows registries served over plain
+	// HTTP. It takes precedence over CAFile.
+	Insecure bool
+	// CAFile is a PEM bundle of CAs trusted in addition to the system CAs.
+	CAFile string
+}
+
+// CraneOptions returns the crane options that apply o. It returns no options for
+// the zero value.
+func (o Options) CraneOptions() ([]crane.Option, error) {
+	if !o.Insecure && o.CAFile == "" {
+		return nil, nil
+	}
+
+	// Adding WithTransport opt is a workaround to allow for access to HTTPS
+	// container registries with self-signed or non-trusted certificates.
+	//
+	// See https://github.com/google/go-containerregistry/issues/1553 for more context. If this issue
+	// is resolved, then this workaround can likely be removed or adjusted to use new features in the
+	// go-containerregistry project.
+	rt := remote.DefaultTransport.(*http.Transport).Clone()
+
+	if o.Insecure {
+		rt.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true, //nolint: gosec
+		}
+		return []crane.Option{crane.Insecure, crane.WithTransport(rt)}, nil
+	}
+
+	pool, err := certPool(o.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	rt.TLSClientConfig = &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	return []crane.Option{crane.WithTransport(rt)}, nil
+}
+
+// certPool returns the system CAs, along with those in the PEM bundle caFile.
+func certPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not read registry CA file: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("could not load registry CA file %s: %w", caFile, errNoCertificates)
+	}
+
+	return pool, nil
+}
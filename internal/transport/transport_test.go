@@ -0,0 +1,110 @@
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/registry"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// writeCA writes a self-signed CA certificate to a PEM file and returns its path.
+func writeCA() string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test registry CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	Expect(err).ToNot(HaveOccurred())
+	return writePEM(der)
+}
+
+// writePEM writes a DER certificate to a PEM file and returns its path.
+func writePEM(der []byte) string {
+	file := filepath.Join(GinkgoT().TempDir(), "ca.pem")
+	Expect(os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)).To(Succeed())
+	return file
+}
+
+var _ = Describe("Registry transport", func() {
+	var (
+		server *httptest.Server
+		host   string
+	)
+
+	BeforeEach(func() {
+		// Serve a registry over TLS with a certificate the system does not trust.
+		registryLogger := log.New(io.Discard, "", log.Ldate)
+		server = httptest.NewTLSServer(registry.New(registry.Logger(registryLogger)))
+		DeferCleanup(server.Close)
+		u, err := url.Parse(server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		host = u.Host
+	})
+
+	It("should not change the default transport for the zero value", func() {
+		opts, err := Options{}.CraneOptions()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(opts).To(BeEmpty())
+
+		_, err = crane.Catalog(host, opts...)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should skip verification when insecure", func() {
+		opts, err := Options{Insecure: true, CAFile: "ignored.pem"}.CraneOptions()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = crane.Catalog(host, opts...)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should trust the CAs in the CA file", func() {
+		opts, err := Options{CAFile: writePEM(server.Certificate().Raw)}.CraneOptions()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = crane.Catalog(host, opts...)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should keep verifying certificates not signed by the CA file", func() {
+		opts, err := Options{CAFile: writeCA()}.CraneOptions()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = crane.Catalog(host, opts...)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject a CA file without certificates", func() {
+		file := filepath.Join(GinkgoT().TempDir(), "empty.pem")
+		Expect(os.WriteFile(file, []byte("not a certificate"), 0o644)).To(Succeed())
+		_, err := Options{CAFile: file}.CraneOptions()
+		Expect(errors.Is(err, errNoCertificates)).To(BeTrue())
+	})
+
+	It("should report a missing CA file", func() {
+		_, err := Options{CAFile: filepath.Join(GinkgoT().TempDir(), "missing.pem")}.CraneOptions()
+		Expect(errors.Is(err, os.ErrNotExist)).To(BeTrue())
+	})
+})
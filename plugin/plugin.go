@@ -85,6 +85,7 @@ func (p *plug) Init(ctx context.Context, cfg *viper.Viper, args []string) error
 			cfg.GetString(flags.KeyCertProjectID),
 			&http.Client{Timeout: 60 * time.Second},
 		)
+		pyxisClient.Retry = flags.PyxisRetryPolicy(cfg)
 
 		override, err := exceptions.GetContainerPolicyExceptions(ctx, pyxisClient)
 		if err != nil {
@@ -101,19 +102,36 @@ func (p *plug) Init(ctx context.Context, cfg *viper.Viper, args []string) error
 		PyxisAPIToken:          cfg.GetString(flags.KeyPyxisAPIToken),
 		CertificationProjectID: cfg.GetString(flags.KeyCertProjectID),
 		PyxisHost:              p.pyxisHost,
+		PolicyFiles:            cfg.GetStringSlice(flags.KeyPolicyFiles),
+		Insecure:               cfg.GetBool(flags.KeyInsecure),
+		RegistryCAFile:         cfg.GetString(flags.KeyRegistryCAFile),
+		AdvisoryFiles:          cfg.GetStringSlice(flags.KeyAdvisoryFiles),
+		PyxisRetry:             flags.PyxisRetryPolicy(cfg),
 	})
 	if err != nil {
 		return err
 	}
 
+	checkTimeouts, err := crane.ParseCheckTimeouts(cfg.GetStringSlice(flags.KeyCheckTimeoutOverrides))
+	if err != nil {
+		return err
+	}
+
 	p.image = args[0]
 	p.engine = &crane.CraneEngine{
-		DockerConfig: cfg.GetString(flags.KeyDockerConfig),
-		Image:        p.image,
-		Checks:       renderedChecks,
-		Platform:     cfg.GetString(flags.KeyPlatform),
-		IsScratch:    pol == policy.PolicyScratch,
-		Insecure:     false, // TODO(Jose): This isn't wired because this probably needs to come from the preflight tool? Maybe not.
+		DockerConfig:       cfg.GetString(flags.KeyDockerConfig),
+		Image:              p.image,
+		Checks:             renderedChecks,
+		Platform:           cfg.GetString(flags.KeyPlatform),
+		Platforms:          cfg.GetStringSlice(flags.KeyPlatforms),
+		IsScratch:          pol == policy.PolicyScratch,
+		Insecure:           cfg.GetBool(flags.KeyInsecure),
+		RegistryCAFile:     cfg.GetString(flags.KeyRegistryCAFile),
+		Parallelism:        cfg.GetInt(flags.KeyCheckParallelism),
+		CheckTimeout:       cfg.GetDuration(flags.KeyCheckTimeout),
+		CheckTimeouts:      checkTimeouts,
+		LayerCacheDir:      cfg.GetString(flags.KeyLayerCacheDir),
+		LayerCacheMaxBytes: cfg.GetInt64(flags.KeyLayerCacheMaxMB) * 1024 * 1024,
 	}
 
 	// Note(Jose) store the config so that Submit can use values from it.
@@ -129,8 +147,17 @@ func (p *plug) Flags() *pflag.FlagSet {
 	flags.BindFlagPyxisAPIToken(f)
 	flags.BindFlagPyxisEnv(f)
 	flags.BindFlagPyxisHost(f)
+	flags.BindFlagsPyxisRetry(f)
 	flags.BindFlagCertificationProjectID(f)
 	flags.BindFlagsImagePlatform(f)
+	flags.BindFlagCheckParallelism(f)
+	flags.BindFlagCheckTimeouts(f)
+	flags.BindFlagsLayerCache(f)
+	flags.BindFlagPolicyFiles(f)
+	flags.BindFlagsRegistryTLS(f)
+	flags.BindFlagAdvisoryFiles(f)
+	flags.BindFlagSubmitSBOM(f)
+	flags.BindFlagSubmitDryRun(f)
 	return f
 }
 
@@ -162,10 +189,16 @@ func (p *plug) Submit(ctx context.Context) error {
 			p.config.GetString(flags.KeyCertProjectID),
 			p.config.GetString(flags.KeyPyxisAPIToken),
 			p.pyxisHost,
+			flags.PyxisRetryPolicy(p.config),
 		),
 		DockerConfig:     p.config.GetString(flags.KeyDockerConfig),
 		PreflightLogFile: "preflight.log", // TODO: This is probably coming from knex so we need to map this somehow.
 		PyxisEnv:         p.config.GetString(flags.KeyPyxisEnv),
+		SubmitSBOM:       p.config.GetBool(flags.KeySubmitSBOM),
+		DryRun:           p.config.GetBool(flags.KeySubmitDryRun),
+	}
+	for _, pr := range p.engine.PlatformResults() {
+		container.Platforms = append(container.Platforms, pr.Platform)
 	}
 
 	return container.Submit(ctx)
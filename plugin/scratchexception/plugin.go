@@ -59,19 +59,36 @@ func (p *plug) Init(ctx context.Context, cfg *viper.Viper, args []string) error
 		PyxisAPIToken:          cfg.GetString(flags.KeyPyxisAPIToken),
 		CertificationProjectID: cfg.GetString(flags.KeyCertProjectID),
 		PyxisHost:              cfg.GetString(flags.KeyPyxisHost),
+		PolicyFiles:            cfg.GetStringSlice(flags.KeyPolicyFiles),
+		Insecure:               cfg.GetBool(flags.KeyInsecure),
+		RegistryCAFile:         cfg.GetString(flags.KeyRegistryCAFile),
+		AdvisoryFiles:          cfg.GetStringSlice(flags.KeyAdvisoryFiles),
+		PyxisRetry:             flags.PyxisRetryPolicy(cfg),
 	})
 	if err != nil {
 		return err
 	}
 
+	checkTimeouts, err := crane.ParseCheckTimeouts(cfg.GetStringSlice(flags.KeyCheckTimeoutOverrides))
+	if err != nil {
+		return err
+	}
+
 	p.image = args[0]
 	p.engine = &crane.CraneEngine{
-		DockerConfig: cfg.GetString(flags.KeyDockerConfig),
-		Image:        p.image,
-		Checks:       renderedChecks,
-		Platform:     cfg.GetString(flags.KeyPlatform),
-		IsScratch:    pol == policy.PolicyScratch,
-		Insecure:     false, // TODO(Jose): This isn't wired because this probably needs to come from the preflight tool? Maybe not.
+		DockerConfig:       cfg.GetString(flags.KeyDockerConfig),
+		Image:              p.image,
+		Checks:             renderedChecks,
+		Platform:           cfg.GetString(flags.KeyPlatform),
+		Platforms:          cfg.GetStringSlice(flags.KeyPlatforms),
+		IsScratch:          pol == policy.PolicyScratch,
+		Insecure:           cfg.GetBool(flags.KeyInsecure),
+		RegistryCAFile:     cfg.GetString(flags.KeyRegistryCAFile),
+		Parallelism:        cfg.GetInt(flags.KeyCheckParallelism),
+		CheckTimeout:       cfg.GetDuration(flags.KeyCheckTimeout),
+		CheckTimeouts:      checkTimeouts,
+		LayerCacheDir:      cfg.GetString(flags.KeyLayerCacheDir),
+		LayerCacheMaxBytes: cfg.GetInt64(flags.KeyLayerCacheMaxMB) * 1024 * 1024,
 	}
 	return nil
 }
@@ -82,7 +99,14 @@ func (p *plug) Flags() *pflag.FlagSet {
 	flags.BindFlagPyxisAPIToken(f)
 	flags.BindFlagPyxisEnv(f)
 	flags.BindFlagPyxisHost(f)
+	flags.BindFlagsPyxisRetry(f)
 	flags.BindFlagsImagePlatform(f)
+	flags.BindFlagCheckParallelism(f)
+	flags.BindFlagCheckTimeouts(f)
+	flags.BindFlagsLayerCache(f)
+	flags.BindFlagPolicyFiles(f)
+	flags.BindFlagsRegistryTLS(f)
+	flags.BindFlagAdvisoryFiles(f)
 	return f
 }
 